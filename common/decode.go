@@ -60,7 +60,9 @@ func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
 
 	if block.Type == "CERTIFICATE" {
 		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
-			return cert.PublicKey.(*rsa.PublicKey), nil
+			if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+				return pub, nil
+			}
 		}
 	} else if block.Type == "PUBLIC KEY" {
 		if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
@@ -81,11 +83,11 @@ func SignRSAData(data []byte, priKey *rsa.PrivateKey) ([]byte, error) {
 	return rsa.SignPKCS1v15(rand.Reader, priKey, hash, hashed)
 }
 
-// VerifySignRSA : Verify map data with SHA256
-func VerifySignRSA(data []byte, pubKey *rsa.PublicKey) error {
+// VerifySignRSA : Verify signature of data with SHA256
+func VerifySignRSA(data, sign []byte, pubKey *rsa.PublicKey) error {
 	hash := crypto.SHA256
 	h := hash.New()
 	h.Write(data)
 	hashed := h.Sum(nil)
-	return rsa.VerifyPKCS1v15(pubKey, hash, hashed, data)
+	return rsa.VerifyPKCS1v15(pubKey, hash, hashed, sign)
 }
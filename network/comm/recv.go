@@ -7,26 +7,30 @@ import (
 // When FuncMsg.FuncID < 100; is Built-in api
 
 type CommReq struct {
-	Data interface{} `json:"data"`
-	Num  int         `json:"num"`
-	Func int         `json:"fid"`
+	Data []byte `json:"data"`
+	Num  int    `json:"num"`
+	Func int    `json:"fid"`
 }
 
 const (
 	// 内置接收接口
 	BUILT_IN_MAX  = 30
 	WATCH_IN_MAX  = 100
+	FUNC_ID_MAX   = 65535 // function id is two bytes of request
 	BUILT_IN_NAME = "builtin"
 
 	// didn't return data
 	PingNetwork  = 1
 	DialRegister = 2
+	TcpFrameMode = 3 // negotiate tcp frame format
+	CancelCall   = 4 // cancel running server api by event id
 
 	// make builtin retrun
 	UpFuncMapList = 11
 	UpNodeConnMsg = 12
 	UpServerState = 13
 	UpWatcherList = 14
+	UpAuthPolicy  = 15 // authorization policy of api was changed
 )
 
 func SplitServName(name string) string {
@@ -24,6 +24,10 @@ const (
 	GetWatcher = 84
 	GetApiConn = 85
 	NewServers = 86
+	ElectVote  = 87
+	ElectSync  = 88
+	DeleteApi  = 89
+	Deregister = 90
 )
 
 var WatchFmsg = &WatchFmsgData{Name: "WatchApi", Fmsg: map[uint32]*pb.FuncMsg{
@@ -34,6 +38,10 @@ var WatchFmsg = &WatchFmsgData{Name: "WatchApi", Fmsg: map[uint32]*pb.FuncMsg{
 	GetWatcher: &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "GetWatcher"},
 	GetApiConn: &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "GetApiConn"},
 	NewServers: &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "NewServers"},
+	ElectVote:  &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "ElectVote"},
+	ElectSync:  &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "ElectSync"},
+	DeleteApi:  &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "DeleteApi"},
+	Deregister: &pb.FuncMsg{ApiType: pb.ApiType_Call, FuncName: "Deregister"},
 }}
 
 func init() {
@@ -68,3 +76,15 @@ func (w *WatchFmsgData) GetSerConn() *pb.FuncMsg { return w.Fmsg[GetApiConn] }
 
 // 获取发现节点列表
 func (w *WatchFmsgData) GetWatcher() *pb.FuncMsg { return w.Fmsg[GetWatcher] }
+
+// 发现节点请求投票选举主节点
+func (w *WatchFmsgData) ElectVote() *pb.FuncMsg { return w.Fmsg[ElectVote] }
+
+// 主发现节点心跳与数据同步
+func (w *WatchFmsgData) ElectSync() *pb.FuncMsg { return w.Fmsg[ElectSync] }
+
+// 删除接口，回收接口编号
+func (w *WatchFmsgData) DeleteApi() *pb.FuncMsg { return w.Fmsg[DeleteApi] }
+
+// 节点关闭，注销服务
+func (w *WatchFmsgData) Deregister() *pb.FuncMsg { return w.Fmsg[Deregister] }
@@ -2,6 +2,8 @@ package network
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"time"
 
 	"micro/network/pb"
@@ -34,12 +36,114 @@ type NodeConfig struct {
 	UdpPort uint64
 	// default not use udp listen
 	UdpListenOn bool
+	// send udp request with ack and retransmission when remote node support it,
+	// default send udp packet without ack
+	UdpReliable bool
 
 	// localhost tcp listen port
 	// default switch on to use, free port
 	HttpPort uint64
 	// default use http listen
 	HttpListenOff bool
+
+	// load balance weight of this node, default: 100
+	Weight uint32
+	// slow start after this node joined or recovered, caller weight increase
+	// linearly from 10% to full by the time and request success rate, default 0 not use
+	WarmUp time.Duration
+	// load balance strategy to call remote api
+	// eg: [RoundRobin, WeightRandom, LeastActive, SystemLoad]
+	// default: RoundRobin
+	Balance string
+	// load balance strategy of api name, cover Balance
+	// eg: {"Billing.Charge": "LeastActive"}
+	ApiBalance map[string]string
+
+	// default negotiate varint length-prefixed tcp frame when connect,
+	// switch on to keep the fixed-size frame
+	TcpFrameLegacy bool
+
+	// retry policy to call remote api, default only try next node when send failed
+	Retry *RetryPolicy
+	// retry policy of api name, cover Retry
+	ApiRetry map[string]*RetryPolicy
+
+	// circuit breaker of every remote node, default use
+	Breaker *BreakerConfig
+	// switch on to not use circuit breaker
+	BreakerOff bool
+
+	// tls of tcp and http to listen and connect other nodes, default nil not use,
+	// udp cannot be used with tls
+	TLS *TLSConfig
+
+	// pem file of rsa private key to sign register message, default not sign
+	SignKey string
+	// pem files of rsa public key or certificate allowed to dial register this node,
+	// map to node names signed by the key, default nil not check
+	// eg: {"billing.pub": ["Billing"], "watcher.pub": ["WATCHER"]}
+	SignKeys map[string][]string
+
+	// compressor name of request and response body, eg: gzip, default empty not compress,
+	// only compressed when remote node registered the compressor, see rpc.RegisterCompressor
+	Compress string
+	// body smaller than it was not compressed, default: 1024
+	CompressMin int
+	// compressor name of api, cover Compress, empty name not compress
+	// eg: {"Report.Upload": "gzip"}
+	ApiCompress map[string]string
+}
+
+// certificate of the node, verify remote node by CA, mutual tls when ClientAuth switch on
+type TLSConfig struct {
+	// pem file of certificate and private key
+	CertFile string
+	KeyFile  string
+	// loaded certificate, cover CertFile and KeyFile
+	Certificate *tls.Certificate
+	// pem file of CA to verify remote node
+	CAFile string
+	// loaded CA pool, cover CAFile, default system CA
+	CAPool *x509.CertPool
+	// server name to verify certificate of remote node, default host of the node
+	ServerName string
+	// server require and verify certificate of caller node
+	ClientAuth bool
+}
+
+// circuit breaker open by consecutive failed request, skip the node and ping it
+// from high to low frequency, allow one request to test after ping success
+type BreakerConfig struct {
+	// consecutive failed request to open, default: 5
+	Failures int
+	// request slower than it was failed, default 0 not check
+	SlowCall time.Duration
+	// first ping after open, double when failed again, default: 1s
+	OpenTimeout time.Duration
+	// max ping interval, default: 1m
+	MaxOpenTimeout time.Duration
+}
+
+// retry failed request by other node of the api
+type RetryPolicy struct {
+	// max request times include the first, default 1 not retry
+	MaxAttempts int
+	// wait before retry, double every time, default: 50ms
+	Backoff time.Duration
+	// max wait before retry, default: 1s
+	MaxBackoff time.Duration
+	// random rate of backoff [0, 1], default: 0.2
+	Jitter float64
+	// timeout of every request, default use the whole context
+	PerTryTimeout time.Duration
+	// status code name to retry, default: ["Unavailable"]
+	// eg: [Unavailable, DeadlineExceeded, ResourceExhausted, Unknown]
+	RetryOn []string
+	// api can be called again, retry all RetryOn code,
+	// else only Unavailable that server api was not called
+	Idempotent bool
+	// send api was not retried unless allowed
+	RetrySend bool
 }
 
 type WatcherConfig struct {
@@ -53,6 +157,34 @@ type WatcherConfig struct {
 	HttpPort uint64
 	// config file path
 	ConfigPath string
+
+	// other watcher nodes of cluster, elect one master
+	Peers []*WatcherConfig
+	// master watcher heartbeat timeout to elect, default: 1.5s
+	ElectTimeout time.Duration
+
+	// api without provider node will be deleted after the time, default: 10m
+	ApiDeleteDelay time.Duration
+	// deleted function id can be reused after the time, default: 24h
+	ApiReuseDelay time.Duration
+
+	// tls of the watcher node to listen and connect peers, default nil not use
+	TLS *TLSConfig
+
+	// pem file of rsa private key to sign register message to peers
+	SignKey string
+	// pem files of rsa public key or certificate allowed to register,
+	// map to node names signed by the key, default nil not check
+	SignKeys map[string][]string
+
+	// json file of api authorization policy, reload when modified, default not check
+	// eg: {"Rules": [{"Caller": "Gateway", "Apis": ["Billing.*"]}]}
+	// api matched by rules can only be called by verified callers (certificate common name
+	// or name of node registered with signature) of these rules
+	AuthPolicy string
+	// verified callers (certificate common name or name of node registered with signature)
+	// allowed to call admin api eg: DeleteApi, default nil refuse all
+	Admins []string
 }
 
 type Node interface {
@@ -90,6 +222,31 @@ type NodeApi interface {
 	SendAutoAll(name string, req interface{}, rsp *pb.SendAllRsp) error
 	SendAutoContext(ctx context.Context, name string, req interface{}) error
 	SendAutoTimeout(duration time.Duration, name string, req interface{}) error
+
+	// open stream to server api, cancel by ctx or ClientStream.Close
+	CallStream(ctx context.Context, name string, req interface{}) (ClientStream, error)
+
+	// deregister from watcher, reject new request, wait running request until ctx done,
+	// then close listeners and timer
+	Shutdown(ctx context.Context) error
+	// shutdown by SIGINT or SIGTERM in timeout, then exit process
+	SignalExit(timeout time.Duration)
+	// report health state to watcher, node not SERVING was removed from api node list
+	SetServingState(state pb.ServingState)
+	// health state callback run by heartbeat, used when SetServingState was SERVING
+	SetHealthCheck(function func() pb.ServingState)
+}
+
+// client side of stream api, not safe to call Next concurrently
+type ClientStream interface {
+	// receive next response message, io.EOF when server finished
+	Next(rsp interface{}) error
+	// send request message to server, bidirectional stream
+	Send(req interface{}) error
+	// close send direction, server Recv return io.EOF
+	CloseSend() error
+	// cancel the stream
+	Close()
 }
 
 type CallResp interface {
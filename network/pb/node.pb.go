@@ -72,9 +72,10 @@ func (Compiler) EnumDescriptor() ([]byte, []int) {
 type ApiType int32
 
 const (
-	ApiType_Send  ApiType = 0 // 单传入参数
-	ApiType_Call  ApiType = 1 // 单传入参数，单传出参数解析
-	ApiType_Multi ApiType = 2 // 多参数传入，单参数传出解析
+	ApiType_Send   ApiType = 0 // 单传入参数
+	ApiType_Call   ApiType = 1 // 单传入参数，单传出参数解析
+	ApiType_Multi  ApiType = 2 // 多参数传入，单参数传出解析
+	ApiType_Stream ApiType = 3 // 单传入参数，流式传出，可双向流式传入
 )
 
 // Enum value maps for ApiType.
@@ -83,11 +84,13 @@ var (
 		0: "Send",
 		1: "Call",
 		2: "Multi",
+		3: "Stream",
 	}
 	ApiType_value = map[string]int32{
-		"Send":  0,
-		"Call":  1,
-		"Multi": 2,
+		"Send":   0,
+		"Call":   1,
+		"Multi":  2,
+		"Stream": 3,
 	}
 )
 
@@ -143,6 +146,22 @@ type NodeInfo struct {
 	Hport uint64 `protobuf:"varint,9,opt,name=Hport,proto3" json:"Hport,omitempty"`
 	// local api types: send or call, protocal
 	Funcs []*FuncApi `protobuf:"bytes,10,rep,name=Funcs,proto3" json:"Funcs,omitempty"`
+	// load balance weight, default 100
+	Weight uint32 `protobuf:"varint,11,opt,name=Weight,proto3" json:"Weight,omitempty"`
+	// slow start milliseconds after join or recover, weight increase from low to full
+	WarmUp uint64 `protobuf:"varint,12,opt,name=WarmUp,proto3" json:"WarmUp,omitempty"`
+	// rsa signature of node message without this field, checked when register
+	Sign []byte `protobuf:"bytes,13,opt,name=Sign,proto3" json:"Sign,omitempty"`
+	// compressor names of body that node can decompress
+	Compress []string `protobuf:"bytes,14,rep,name=Compress,proto3" json:"Compress,omitempty"`
+	// udp listen can receive reliable packet with ack and retransmission
+	UdpReliable bool `protobuf:"varint,15,opt,name=UdpReliable,proto3" json:"UdpReliable,omitempty"`
+	// millisecond time of signature, old message was rejected as replay
+	SignTime int64 `protobuf:"varint,16,opt,name=SignTime,proto3" json:"SignTime,omitempty"`
+	// random bytes of signature, repeated message was rejected as replay
+	Nonce []byte `protobuf:"bytes,17,opt,name=Nonce,proto3" json:"Nonce,omitempty"`
+	// node can parse request metadata and response status of fixed-size frame
+	Metadata bool `protobuf:"varint,18,opt,name=Metadata,proto3" json:"Metadata,omitempty"`
 }
 
 func (x *NodeInfo) Reset() {
@@ -247,6 +266,62 @@ func (x *NodeInfo) GetFuncs() []*FuncApi {
 	return nil
 }
 
+func (x *NodeInfo) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetWarmUp() uint64 {
+	if x != nil {
+		return x.WarmUp
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetSign() []byte {
+	if x != nil {
+		return x.Sign
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetCompress() []string {
+	if x != nil {
+		return x.Compress
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetUdpReliable() bool {
+	if x != nil {
+		return x.UdpReliable
+	}
+	return false
+}
+
+func (x *NodeInfo) GetSignTime() int64 {
+	if x != nil {
+		return x.SignTime
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetMetadata() bool {
+	if x != nil {
+		return x.Metadata
+	}
+	return false
+}
+
 type FuncApi struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -510,7 +585,7 @@ func (x *MultiBody) GetData() [][]byte {
 var File_node_proto protoreflect.FileDescriptor
 
 var file_node_proto_rawDesc = []byte{
-	0x0a, 0x0a, 0x6e, 0x6f, 0x64, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xe0, 0x01, 0x0a,
+	0x0a, 0x0a, 0x6e, 0x6f, 0x64, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xb0, 0x03, 0x0a,
 	0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x10, 0x0a, 0x03, 0x50, 0x69, 0x64,
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x50, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x56,
 	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x56, 0x65, 0x72, 0x12, 0x12, 0x0a,
@@ -524,7 +599,20 @@ var file_node_proto_rawDesc = []byte{
 	0x28, 0x04, 0x52, 0x05, 0x55, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x48, 0x70, 0x6f,
 	0x72, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x48, 0x70, 0x6f, 0x72, 0x74, 0x12,
 	0x1e, 0x0a, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08,
-	0x2e, 0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x22,
+	0x2e, 0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x06, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x57, 0x61, 0x72, 0x6d, 0x55,
+	0x70, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x57, 0x61, 0x72, 0x6d, 0x55, 0x70, 0x12,
+	0x12, 0x0a, 0x04, 0x53, 0x69, 0x67, 0x6e, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x53,
+	0x69, 0x67, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x18,
+	0x0e, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x12,
+	0x20, 0x0a, 0x0b, 0x55, 0x64, 0x70, 0x52, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x55, 0x64, 0x70, 0x52, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x53, 0x69, 0x67, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x10, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x08, 0x53, 0x69, 0x67, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x4e, 0x6f,
+	0x6e, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18,
+	0x12, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x22,
 	0x6a, 0x0a, 0x07, 0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44,
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61,
 	0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c,
@@ -552,11 +640,12 @@ var file_node_proto_rawDesc = []byte{
 	0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x04, 0x44, 0x61, 0x74, 0x61, 0x2a,
 	0x2b, 0x0a, 0x08, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x72, 0x12, 0x09, 0x0a, 0x05, 0x50,
 	0x52, 0x4f, 0x54, 0x4f, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x4a, 0x53, 0x4f, 0x4e, 0x10, 0x01,
-	0x12, 0x0a, 0x0a, 0x06, 0x4a, 0x53, 0x4f, 0x4e, 0x50, 0x42, 0x10, 0x02, 0x2a, 0x28, 0x0a, 0x07,
+	0x12, 0x0a, 0x0a, 0x06, 0x4a, 0x53, 0x4f, 0x4e, 0x50, 0x42, 0x10, 0x02, 0x2a, 0x34, 0x0a, 0x07,
 	0x41, 0x70, 0x69, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x65, 0x6e, 0x64, 0x10,
 	0x00, 0x12, 0x08, 0x0a, 0x04, 0x43, 0x61, 0x6c, 0x6c, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x4d,
-	0x75, 0x6c, 0x74, 0x69, 0x10, 0x02, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2f, 0x3b, 0x70, 0x62, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x75, 0x6c, 0x74, 0x69, 0x10, 0x02, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x10, 0x03, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2f, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -20,16 +20,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// node health state, only SERVING node was used to call
+type ServingState int32
+
+const (
+	ServingState_SERVING     ServingState = 0
+	ServingState_NOT_SERVING ServingState = 1
+	ServingState_DRAINING    ServingState = 2 // finish running request, not accept new request
+	ServingState_DEGRADED    ServingState = 3
+)
+
+// Enum value maps for ServingState.
+var (
+	ServingState_name = map[int32]string{
+		0: "SERVING",
+		1: "NOT_SERVING",
+		2: "DRAINING",
+		3: "DEGRADED",
+	}
+	ServingState_value = map[string]int32{
+		"SERVING":     0,
+		"NOT_SERVING": 1,
+		"DRAINING":    2,
+		"DEGRADED":    3,
+	}
+)
+
+func (x ServingState) Enum() *ServingState {
+	p := new(ServingState)
+	*p = x
+	return p
+}
+
+func (x ServingState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ServingState) Descriptor() protoreflect.EnumDescriptor {
+	return file_watch_proto_enumTypes[0].Descriptor()
+}
+
+func (ServingState) Type() protoreflect.EnumType {
+	return &file_watch_proto_enumTypes[0]
+}
+
+func (x ServingState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ServingState.Descriptor instead.
+func (ServingState) EnumDescriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{0}
+}
+
 type SystemStatus struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Uuid    string `protobuf:"bytes,1,opt,name=Uuid,proto3" json:"Uuid,omitempty"`
-	CpuRate uint64 `protobuf:"varint,2,opt,name=CpuRate,proto3" json:"CpuRate,omitempty"`
-	MemFree uint64 `protobuf:"varint,3,opt,name=MemFree,proto3" json:"MemFree,omitempty"`
-	MemUsed uint64 `protobuf:"varint,4,opt,name=MemUsed,proto3" json:"MemUsed,omitempty"`
-	ConnNum uint64 `protobuf:"varint,5,opt,name=ConnNum,proto3" json:"ConnNum,omitempty"`
+	Uuid        string       `protobuf:"bytes,1,opt,name=Uuid,proto3" json:"Uuid,omitempty"`
+	CpuRate     uint64       `protobuf:"varint,2,opt,name=CpuRate,proto3" json:"CpuRate,omitempty"`
+	MemFree     uint64       `protobuf:"varint,3,opt,name=MemFree,proto3" json:"MemFree,omitempty"`
+	MemUsed     uint64       `protobuf:"varint,4,opt,name=MemUsed,proto3" json:"MemUsed,omitempty"`
+	ConnNum     uint64       `protobuf:"varint,5,opt,name=ConnNum,proto3" json:"ConnNum,omitempty"`
+	State       ServingState `protobuf:"varint,6,opt,name=State,proto3,enum=ServingState" json:"State,omitempty"` // node health state
+	AuthVersion uint64       `protobuf:"varint,7,opt,name=AuthVersion,proto3" json:"AuthVersion,omitempty"`       // version of authorization policy used by node
 }
 
 func (x *SystemStatus) Reset() {
@@ -99,6 +154,132 @@ func (x *SystemStatus) GetConnNum() uint64 {
 	return 0
 }
 
+func (x *SystemStatus) GetState() ServingState {
+	if x != nil {
+		return x.State
+	}
+	return ServingState_SERVING
+}
+
+func (x *SystemStatus) GetAuthVersion() uint64 {
+	if x != nil {
+		return x.AuthVersion
+	}
+	return 0
+}
+
+// authorization policy of api, api matched by rules can only be called by callers of these rules,
+// api matched by no rule can be called by all
+type AuthPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version uint64      `protobuf:"varint,1,opt,name=Version,proto3" json:"Version,omitempty"` // hash of rules, 0 is no policy
+	Rules   []*AuthRule `protobuf:"bytes,2,rep,name=Rules,proto3" json:"Rules,omitempty"`
+}
+
+func (x *AuthPolicy) Reset() {
+	*x = AuthPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AuthPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthPolicy) ProtoMessage() {}
+
+func (x *AuthPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthPolicy.ProtoReflect.Descriptor instead.
+func (*AuthPolicy) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AuthPolicy) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *AuthPolicy) GetRules() []*AuthRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type AuthRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Caller string   `protobuf:"bytes,1,opt,name=Caller,proto3" json:"Caller,omitempty"` // caller node name or certificate common name, * is all
+	Apis   []string `protobuf:"bytes,2,rep,name=Apis,proto3" json:"Apis,omitempty"`     // api name pattern, eg: Billing.*
+}
+
+func (x *AuthRule) Reset() {
+	*x = AuthRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AuthRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthRule) ProtoMessage() {}
+
+func (x *AuthRule) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthRule.ProtoReflect.Descriptor instead.
+func (*AuthRule) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AuthRule) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *AuthRule) GetApis() []string {
+	if x != nil {
+		return x.Apis
+	}
+	return nil
+}
+
 type SendAllRsp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -111,7 +292,7 @@ type SendAllRsp struct {
 func (x *SendAllRsp) Reset() {
 	*x = SendAllRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[1]
+		mi := &file_watch_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -124,7 +305,7 @@ func (x *SendAllRsp) String() string {
 func (*SendAllRsp) ProtoMessage() {}
 
 func (x *SendAllRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[1]
+	mi := &file_watch_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -137,7 +318,7 @@ func (x *SendAllRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SendAllRsp.ProtoReflect.Descriptor instead.
 func (*SendAllRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{1}
+	return file_watch_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *SendAllRsp) GetResult() []*SendRsp {
@@ -168,7 +349,7 @@ type SendRsp struct {
 func (x *SendRsp) Reset() {
 	*x = SendRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[2]
+		mi := &file_watch_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -181,7 +362,7 @@ func (x *SendRsp) String() string {
 func (*SendRsp) ProtoMessage() {}
 
 func (x *SendRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[2]
+	mi := &file_watch_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -194,7 +375,7 @@ func (x *SendRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SendRsp.ProtoReflect.Descriptor instead.
 func (*SendRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{2}
+	return file_watch_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *SendRsp) GetUuid() string {
@@ -232,12 +413,14 @@ type RegisteredRsp struct {
 
 	Watch []*NodeInfo `protobuf:"bytes,1,rep,name=Watch,proto3" json:"Watch,omitempty"`
 	Funcs []*FuncApi  `protobuf:"bytes,2,rep,name=Funcs,proto3" json:"Funcs,omitempty"`
+	Term  uint64      `protobuf:"varint,3,opt,name=Term,proto3" json:"Term,omitempty"` // watcher elect term
+	Auth  *AuthPolicy `protobuf:"bytes,4,opt,name=Auth,proto3" json:"Auth,omitempty"`  // authorization policy of api
 }
 
 func (x *RegisteredRsp) Reset() {
 	*x = RegisteredRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[3]
+		mi := &file_watch_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -250,7 +433,7 @@ func (x *RegisteredRsp) String() string {
 func (*RegisteredRsp) ProtoMessage() {}
 
 func (x *RegisteredRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[3]
+	mi := &file_watch_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -263,7 +446,7 @@ func (x *RegisteredRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisteredRsp.ProtoReflect.Descriptor instead.
 func (*RegisteredRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{3}
+	return file_watch_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *RegisteredRsp) GetWatch() []*NodeInfo {
@@ -280,6 +463,20 @@ func (x *RegisteredRsp) GetFuncs() []*FuncApi {
 	return nil
 }
 
+func (x *RegisteredRsp) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RegisteredRsp) GetAuth() *AuthPolicy {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
 // Query function message
 type GetFuncMsgReq struct {
 	state         protoimpl.MessageState
@@ -293,7 +490,7 @@ type GetFuncMsgReq struct {
 func (x *GetFuncMsgReq) Reset() {
 	*x = GetFuncMsgReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[4]
+		mi := &file_watch_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -306,7 +503,7 @@ func (x *GetFuncMsgReq) String() string {
 func (*GetFuncMsgReq) ProtoMessage() {}
 
 func (x *GetFuncMsgReq) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[4]
+	mi := &file_watch_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -319,7 +516,7 @@ func (x *GetFuncMsgReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetFuncMsgReq.ProtoReflect.Descriptor instead.
 func (*GetFuncMsgReq) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{4}
+	return file_watch_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetFuncMsgReq) GetFuncID() uint32 {
@@ -348,7 +545,7 @@ type GetFuncMsgRsp struct {
 func (x *GetFuncMsgRsp) Reset() {
 	*x = GetFuncMsgRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[5]
+		mi := &file_watch_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -361,7 +558,7 @@ func (x *GetFuncMsgRsp) String() string {
 func (*GetFuncMsgRsp) ProtoMessage() {}
 
 func (x *GetFuncMsgRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[5]
+	mi := &file_watch_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -374,7 +571,7 @@ func (x *GetFuncMsgRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetFuncMsgRsp.ProtoReflect.Descriptor instead.
 func (*GetFuncMsgRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{5}
+	return file_watch_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetFuncMsgRsp) GetFunc() *FuncApi {
@@ -404,7 +601,7 @@ type GetNodeMsgReq struct {
 func (x *GetNodeMsgReq) Reset() {
 	*x = GetNodeMsgReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[6]
+		mi := &file_watch_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -417,7 +614,7 @@ func (x *GetNodeMsgReq) String() string {
 func (*GetNodeMsgReq) ProtoMessage() {}
 
 func (x *GetNodeMsgReq) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[6]
+	mi := &file_watch_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -430,7 +627,7 @@ func (x *GetNodeMsgReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetNodeMsgReq.ProtoReflect.Descriptor instead.
 func (*GetNodeMsgReq) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{6}
+	return file_watch_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetNodeMsgReq) GetUuid() string {
@@ -459,7 +656,7 @@ type GetNodeMsgRsp struct {
 func (x *GetNodeMsgRsp) Reset() {
 	*x = GetNodeMsgRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[7]
+		mi := &file_watch_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -472,7 +669,7 @@ func (x *GetNodeMsgRsp) String() string {
 func (*GetNodeMsgRsp) ProtoMessage() {}
 
 func (x *GetNodeMsgRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[7]
+	mi := &file_watch_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -485,7 +682,7 @@ func (x *GetNodeMsgRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetNodeMsgRsp.ProtoReflect.Descriptor instead.
 func (*GetNodeMsgRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{7}
+	return file_watch_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *GetNodeMsgRsp) GetData() *NodeInfo {
@@ -510,12 +707,14 @@ type GetApiConnReq struct {
 
 	FuncID  uint32 `protobuf:"varint,1,opt,name=FuncID,proto3" json:"FuncID,omitempty"`  // function id
 	ApiName string `protobuf:"bytes,2,opt,name=ApiName,proto3" json:"ApiName,omitempty"` // server struct name
+	Uuid    string `protobuf:"bytes,3,opt,name=Uuid,proto3" json:"Uuid,omitempty"`       // request node uuid, subscribe api node change
+	All     bool   `protobuf:"varint,4,opt,name=All,proto3" json:"All,omitempty"`        // include node not SERVING
 }
 
 func (x *GetApiConnReq) Reset() {
 	*x = GetApiConnReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[8]
+		mi := &file_watch_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -528,7 +727,7 @@ func (x *GetApiConnReq) String() string {
 func (*GetApiConnReq) ProtoMessage() {}
 
 func (x *GetApiConnReq) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[8]
+	mi := &file_watch_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -541,7 +740,7 @@ func (x *GetApiConnReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetApiConnReq.ProtoReflect.Descriptor instead.
 func (*GetApiConnReq) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{8}
+	return file_watch_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GetApiConnReq) GetFuncID() uint32 {
@@ -558,19 +757,35 @@ func (x *GetApiConnReq) GetApiName() string {
 	return ""
 }
 
+func (x *GetApiConnReq) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *GetApiConnReq) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
 type GetApiConnRsp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Func *FuncMsg    `protobuf:"bytes,1,opt,name=Func,proto3" json:"Func,omitempty"` // function message
-	List []*NodeInfo `protobuf:"bytes,2,rep,name=List,proto3" json:"List,omitempty"` // node list
+	Func    *FuncMsg        `protobuf:"bytes,1,opt,name=Func,proto3" json:"Func,omitempty"`        // function message
+	List    []*NodeInfo     `protobuf:"bytes,2,rep,name=List,proto3" json:"List,omitempty"`        // node list
+	Stat    []*SystemStatus `protobuf:"bytes,3,rep,name=Stat,proto3" json:"Stat,omitempty"`        // node system status
+	Deleted bool            `protobuf:"varint,4,opt,name=Deleted,proto3" json:"Deleted,omitempty"` // function id deleted by watcher
 }
 
 func (x *GetApiConnRsp) Reset() {
 	*x = GetApiConnRsp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_watch_proto_msgTypes[9]
+		mi := &file_watch_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -583,7 +798,7 @@ func (x *GetApiConnRsp) String() string {
 func (*GetApiConnRsp) ProtoMessage() {}
 
 func (x *GetApiConnRsp) ProtoReflect() protoreflect.Message {
-	mi := &file_watch_proto_msgTypes[9]
+	mi := &file_watch_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -596,7 +811,7 @@ func (x *GetApiConnRsp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetApiConnRsp.ProtoReflect.Descriptor instead.
 func (*GetApiConnRsp) Descriptor() ([]byte, []int) {
-	return file_watch_proto_rawDescGZIP(), []int{9}
+	return file_watch_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GetApiConnRsp) GetFunc() *FuncMsg {
@@ -613,144 +828,921 @@ func (x *GetApiConnRsp) GetList() []*NodeInfo {
 	return nil
 }
 
-var File_watch_proto protoreflect.FileDescriptor
+func (x *GetApiConnRsp) GetStat() []*SystemStatus {
+	if x != nil {
+		return x.Stat
+	}
+	return nil
+}
 
-var file_watch_proto_rawDesc = []byte{
-	0x0a, 0x0b, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0a, 0x6e,
-	0x6f, 0x64, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x8a, 0x01, 0x0a, 0x0c, 0x53, 0x79,
-	0x73, 0x74, 0x65, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x55, 0x75,
-	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64, 0x12, 0x18,
-	0x0a, 0x07, 0x43, 0x70, 0x75, 0x52, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
-	0x07, 0x43, 0x70, 0x75, 0x52, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x6d, 0x46,
-	0x72, 0x65, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x4d, 0x65, 0x6d, 0x46, 0x72,
-	0x65, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x6d, 0x55, 0x73, 0x65, 0x64, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x04, 0x52, 0x07, 0x4d, 0x65, 0x6d, 0x55, 0x73, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07,
-	0x43, 0x6f, 0x6e, 0x6e, 0x4e, 0x75, 0x6d, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x43,
-	0x6f, 0x6e, 0x6e, 0x4e, 0x75, 0x6d, 0x22, 0x44, 0x0a, 0x0a, 0x53, 0x65, 0x6e, 0x64, 0x41, 0x6c,
-	0x6c, 0x52, 0x73, 0x70, 0x12, 0x20, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x53, 0x65, 0x6e, 0x64, 0x52, 0x73, 0x70, 0x52, 0x06,
-	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x6b, 0x0a, 0x07,
-	0x53, 0x65, 0x6e, 0x64, 0x52, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x55, 0x75, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x4e,
-	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4e, 0x65,
-	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12,
-	0x18, 0x0a, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x50, 0x0a, 0x0d, 0x52, 0x65, 0x67,
-	0x69, 0x73, 0x74, 0x65, 0x72, 0x65, 0x64, 0x52, 0x73, 0x70, 0x12, 0x1f, 0x0a, 0x05, 0x57, 0x61,
-	0x74, 0x63, 0x68, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65,
-	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1e, 0x0a, 0x05, 0x46,
-	0x75, 0x6e, 0x63, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e,
-	0x63, 0x41, 0x70, 0x69, 0x52, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x22, 0x41, 0x0a, 0x0d, 0x47,
-	0x65, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06,
-	0x46, 0x75, 0x6e, 0x63, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x46, 0x75,
-	0x6e, 0x63, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x4b,
-	0x0a, 0x0d, 0x47, 0x65, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x4d, 0x73, 0x67, 0x52, 0x73, 0x70, 0x12,
-	0x1c, 0x0a, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e,
-	0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x12, 0x1c, 0x0a,
-	0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75,
-	0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x37, 0x0a, 0x0d, 0x47,
-	0x65, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04,
-	0x55, 0x75, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64,
-	0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x4e, 0x61, 0x6d, 0x65, 0x22, 0x4d, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x4d,
-	0x73, 0x67, 0x52, 0x73, 0x70, 0x12, 0x1d, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04,
-	0x44, 0x61, 0x74, 0x61, 0x12, 0x1d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x02, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x4c,
-	0x69, 0x73, 0x74, 0x22, 0x41, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x41, 0x70, 0x69, 0x43, 0x6f, 0x6e,
-	0x6e, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x46, 0x75, 0x6e, 0x63, 0x49, 0x44, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x46, 0x75, 0x6e, 0x63, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07,
-	0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x41,
-	0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x4c, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x41, 0x70, 0x69,
-	0x43, 0x6f, 0x6e, 0x6e, 0x52, 0x73, 0x70, 0x12, 0x1c, 0x0a, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x4d, 0x73, 0x67, 0x52,
-	0x04, 0x46, 0x75, 0x6e, 0x63, 0x12, 0x1d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x02, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04,
-	0x4c, 0x69, 0x73, 0x74, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2f, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *GetApiConnRsp) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
 }
 
-var (
-	file_watch_proto_rawDescOnce sync.Once
-	file_watch_proto_rawDescData = file_watch_proto_rawDesc
-)
+// Watcher cluster node list
+type WatcherList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-func file_watch_proto_rawDescGZIP() []byte {
-	file_watch_proto_rawDescOnce.Do(func() {
-		file_watch_proto_rawDescData = protoimpl.X.CompressGZIP(file_watch_proto_rawDescData)
-	})
-	return file_watch_proto_rawDescData
+	List []*NodeInfo `protobuf:"bytes,1,rep,name=List,proto3" json:"List,omitempty"`  // watcher node list, Main is master
+	Term uint64      `protobuf:"varint,2,opt,name=Term,proto3" json:"Term,omitempty"` // elect term
+	Base *NodeInfo   `protobuf:"bytes,3,opt,name=Base,proto3" json:"Base,omitempty"`  // response watcher node
 }
 
-var file_watch_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
-var file_watch_proto_goTypes = []interface{}{
-	(*SystemStatus)(nil),  // 0: SystemStatus
-	(*SendAllRsp)(nil),    // 1: SendAllRsp
-	(*SendRsp)(nil),       // 2: SendRsp
-	(*RegisteredRsp)(nil), // 3: RegisteredRsp
-	(*GetFuncMsgReq)(nil), // 4: GetFuncMsgReq
-	(*GetFuncMsgRsp)(nil), // 5: GetFuncMsgRsp
-	(*GetNodeMsgReq)(nil), // 6: GetNodeMsgReq
-	(*GetNodeMsgRsp)(nil), // 7: GetNodeMsgRsp
-	(*GetApiConnReq)(nil), // 8: GetApiConnReq
-	(*GetApiConnRsp)(nil), // 9: GetApiConnRsp
-	(*NodeInfo)(nil),      // 10: NodeInfo
-	(*FuncApi)(nil),       // 11: FuncApi
-	(*FuncMsg)(nil),       // 12: FuncMsg
+func (x *WatcherList) Reset() {
+	*x = WatcherList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
-var file_watch_proto_depIdxs = []int32{
-	2,  // 0: SendAllRsp.Result:type_name -> SendRsp
-	10, // 1: RegisteredRsp.Watch:type_name -> NodeInfo
-	11, // 2: RegisteredRsp.Funcs:type_name -> FuncApi
-	11, // 3: GetFuncMsgRsp.Func:type_name -> FuncApi
-	11, // 4: GetFuncMsgRsp.List:type_name -> FuncApi
-	10, // 5: GetNodeMsgRsp.Data:type_name -> NodeInfo
-	10, // 6: GetNodeMsgRsp.List:type_name -> NodeInfo
-	12, // 7: GetApiConnRsp.Func:type_name -> FuncMsg
-	10, // 8: GetApiConnRsp.List:type_name -> NodeInfo
-	9,  // [9:9] is the sub-list for method output_type
-	9,  // [9:9] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+
+func (x *WatcherList) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_watch_proto_init() }
-func file_watch_proto_init() {
-	if File_watch_proto != nil {
-		return
-	}
-	file_node_proto_init()
-	if !protoimpl.UnsafeEnabled {
-		file_watch_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SystemStatus); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+func (*WatcherList) ProtoMessage() {}
+
+func (x *WatcherList) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_watch_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SendAllRsp); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatcherList.ProtoReflect.Descriptor instead.
+func (*WatcherList) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatcherList) GetList() []*NodeInfo {
+	if x != nil {
+		return x.List
+	}
+	return nil
+}
+
+func (x *WatcherList) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *WatcherList) GetBase() *NodeInfo {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+// Request vote to be master watcher
+type ElectVoteReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Term  uint64    `protobuf:"varint,1,opt,name=Term,proto3" json:"Term,omitempty"`   // candidate term
+	Index uint64    `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"` // candidate state index
+	Base  *NodeInfo `protobuf:"bytes,3,opt,name=Base,proto3" json:"Base,omitempty"`    // candidate watcher node
+}
+
+func (x *ElectVoteReq) Reset() {
+	*x = ElectVoteReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ElectVoteReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElectVoteReq) ProtoMessage() {}
+
+func (x *ElectVoteReq) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElectVoteReq.ProtoReflect.Descriptor instead.
+func (*ElectVoteReq) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ElectVoteReq) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ElectVoteReq) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ElectVoteReq) GetBase() *NodeInfo {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+type ElectVoteRsp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Term  uint64 `protobuf:"varint,1,opt,name=Term,proto3" json:"Term,omitempty"`
+	Grant bool   `protobuf:"varint,2,opt,name=Grant,proto3" json:"Grant,omitempty"`
+}
+
+func (x *ElectVoteRsp) Reset() {
+	*x = ElectVoteRsp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ElectVoteRsp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElectVoteRsp) ProtoMessage() {}
+
+func (x *ElectVoteRsp) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElectVoteRsp.ProtoReflect.Descriptor instead.
+func (*ElectVoteRsp) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ElectVoteRsp) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ElectVoteRsp) GetGrant() bool {
+	if x != nil {
+		return x.Grant
+	}
+	return false
+}
+
+// Master watcher heartbeat and state replication
+type ElectSyncReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Term  uint64      `protobuf:"varint,1,opt,name=Term,proto3" json:"Term,omitempty"`   // master term
+	Index uint64      `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"` // master state index
+	Base  *NodeInfo   `protobuf:"bytes,3,opt,name=Base,proto3" json:"Base,omitempty"`    // master watcher node
+	State *WatchState `protobuf:"bytes,4,opt,name=State,proto3" json:"State,omitempty"`  // null when follower state index is same
+}
+
+func (x *ElectSyncReq) Reset() {
+	*x = ElectSyncReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ElectSyncReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElectSyncReq) ProtoMessage() {}
+
+func (x *ElectSyncReq) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElectSyncReq.ProtoReflect.Descriptor instead.
+func (*ElectSyncReq) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ElectSyncReq) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ElectSyncReq) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ElectSyncReq) GetBase() *NodeInfo {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *ElectSyncReq) GetState() *WatchState {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+type ElectSyncRsp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Term    uint64 `protobuf:"varint,1,opt,name=Term,proto3" json:"Term,omitempty"`
+	Index   uint64 `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"` // follower state index
+	Success bool   `protobuf:"varint,3,opt,name=Success,proto3" json:"Success,omitempty"`
+}
+
+func (x *ElectSyncRsp) Reset() {
+	*x = ElectSyncRsp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ElectSyncRsp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElectSyncRsp) ProtoMessage() {}
+
+func (x *ElectSyncRsp) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElectSyncRsp.ProtoReflect.Descriptor instead.
+func (*ElectSyncRsp) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ElectSyncRsp) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ElectSyncRsp) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ElectSyncRsp) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// Watcher state to replicate
+type WatchState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Funcs []*FuncNode     `protobuf:"bytes,1,rep,name=Funcs,proto3" json:"Funcs,omitempty"`
+	Nodes []*NodeInfo     `protobuf:"bytes,2,rep,name=Nodes,proto3" json:"Nodes,omitempty"`
+	Stat  []*SystemStatus `protobuf:"bytes,3,rep,name=Stat,proto3" json:"Stat,omitempty"`
+	Tomb  []*FuncRecord   `protobuf:"bytes,4,rep,name=Tomb,proto3" json:"Tomb,omitempty"` // deleted function id
+}
+
+func (x *WatchState) Reset() {
+	*x = WatchState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchState) ProtoMessage() {}
+
+func (x *WatchState) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchState.ProtoReflect.Descriptor instead.
+func (*WatchState) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchState) GetFuncs() []*FuncNode {
+	if x != nil {
+		return x.Funcs
+	}
+	return nil
+}
+
+func (x *WatchState) GetNodes() []*NodeInfo {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *WatchState) GetStat() []*SystemStatus {
+	if x != nil {
+		return x.Stat
+	}
+	return nil
+}
+
+func (x *WatchState) GetTomb() []*FuncRecord {
+	if x != nil {
+		return x.Tomb
+	}
+	return nil
+}
+
+type FuncNode struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Func *FuncApi `protobuf:"bytes,1,opt,name=Func,proto3" json:"Func,omitempty"`
+	Node []string `protobuf:"bytes,2,rep,name=Node,proto3" json:"Node,omitempty"` // server node uuid
+	Subs []string `protobuf:"bytes,3,rep,name=Subs,proto3" json:"Subs,omitempty"` // node uuid request api connection
+}
+
+func (x *FuncNode) Reset() {
+	*x = FuncNode{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FuncNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FuncNode) ProtoMessage() {}
+
+func (x *FuncNode) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FuncNode.ProtoReflect.Descriptor instead.
+func (*FuncNode) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *FuncNode) GetFunc() *FuncApi {
+	if x != nil {
+		return x.Func
+	}
+	return nil
+}
+
+func (x *FuncNode) GetNode() []string {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *FuncNode) GetSubs() []string {
+	if x != nil {
+		return x.Subs
+	}
+	return nil
+}
+
+// Function id registry record, same field number with FuncApi
+type FuncRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ID      uint32   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name    string   `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Type    ApiType  `protobuf:"varint,5,opt,name=Type,proto3,enum=ApiType" json:"Type,omitempty"`
+	Kind    Compiler `protobuf:"varint,6,opt,name=Kind,proto3,enum=Compiler" json:"Kind,omitempty"`
+	Deleted int64    `protobuf:"varint,7,opt,name=Deleted,proto3" json:"Deleted,omitempty"` // tombstone timestamp (millisecond), 0 is valid
+}
+
+func (x *FuncRecord) Reset() {
+	*x = FuncRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FuncRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FuncRecord) ProtoMessage() {}
+
+func (x *FuncRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FuncRecord.ProtoReflect.Descriptor instead.
+func (*FuncRecord) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *FuncRecord) GetID() uint32 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
+func (x *FuncRecord) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FuncRecord) GetType() ApiType {
+	if x != nil {
+		return x.Type
+	}
+	return ApiType_Send
+}
+
+func (x *FuncRecord) GetKind() Compiler {
+	if x != nil {
+		return x.Kind
+	}
+	return Compiler_PROTO
+}
+
+func (x *FuncRecord) GetDeleted() int64 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+// Admin request to delete function id
+type DeleteApiReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FuncID  uint32 `protobuf:"varint,1,opt,name=FuncID,proto3" json:"FuncID,omitempty"`
+	ApiName string `protobuf:"bytes,2,opt,name=ApiName,proto3" json:"ApiName,omitempty"`
+	Force   bool   `protobuf:"varint,3,opt,name=Force,proto3" json:"Force,omitempty"` // delete when api has provider nodes
+}
+
+func (x *DeleteApiReq) Reset() {
+	*x = DeleteApiReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteApiReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteApiReq) ProtoMessage() {}
+
+func (x *DeleteApiReq) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteApiReq.ProtoReflect.Descriptor instead.
+func (*DeleteApiReq) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *DeleteApiReq) GetFuncID() uint32 {
+	if x != nil {
+		return x.FuncID
+	}
+	return 0
+}
+
+func (x *DeleteApiReq) GetApiName() string {
+	if x != nil {
+		return x.ApiName
+	}
+	return ""
+}
+
+func (x *DeleteApiReq) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type DeleteApiRsp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Func *FuncApi `protobuf:"bytes,1,opt,name=Func,proto3" json:"Func,omitempty"`
+}
+
+func (x *DeleteApiRsp) Reset() {
+	*x = DeleteApiRsp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_watch_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteApiRsp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteApiRsp) ProtoMessage() {}
+
+func (x *DeleteApiRsp) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteApiRsp.ProtoReflect.Descriptor instead.
+func (*DeleteApiRsp) Descriptor() ([]byte, []int) {
+	return file_watch_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeleteApiRsp) GetFunc() *FuncApi {
+	if x != nil {
+		return x.Func
+	}
+	return nil
+}
+
+var File_watch_proto protoreflect.FileDescriptor
+
+var file_watch_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0a, 0x6e,
+	0x6f, 0x64, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd1, 0x01, 0x0a, 0x0c, 0x53, 0x79,
+	0x73, 0x74, 0x65, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x55, 0x75,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64, 0x12, 0x18,
+	0x0a, 0x07, 0x43, 0x70, 0x75, 0x52, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x07, 0x43, 0x70, 0x75, 0x52, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x6d, 0x46,
+	0x72, 0x65, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x4d, 0x65, 0x6d, 0x46, 0x72,
+	0x65, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x6d, 0x55, 0x73, 0x65, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x4d, 0x65, 0x6d, 0x55, 0x73, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07,
+	0x43, 0x6f, 0x6e, 0x6e, 0x4e, 0x75, 0x6d, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x43,
+	0x6f, 0x6e, 0x6e, 0x4e, 0x75, 0x6d, 0x12, 0x23, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x6e, 0x67, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x41,
+	0x75, 0x74, 0x68, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0b, 0x41, 0x75, 0x74, 0x68, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x47, 0x0a,
+	0x0a, 0x41, 0x75, 0x74, 0x68, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x05, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x52, 0x75, 0x6c, 0x65, 0x52,
+	0x05, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x22, 0x36, 0x0a, 0x08, 0x41, 0x75, 0x74, 0x68, 0x52, 0x75,
+	0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x43, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x41, 0x70,
+	0x69, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x41, 0x70, 0x69, 0x73, 0x22, 0x44,
+	0x0a, 0x0a, 0x53, 0x65, 0x6e, 0x64, 0x41, 0x6c, 0x6c, 0x52, 0x73, 0x70, 0x12, 0x20, 0x0a, 0x06,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x53,
+	0x65, 0x6e, 0x64, 0x52, 0x73, 0x70, 0x52, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x22, 0x6b, 0x0a, 0x07, 0x53, 0x65, 0x6e, 0x64, 0x52, 0x73, 0x70, 0x12,
+	0x12, 0x0a, 0x04, 0x55, 0x75, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55,
+	0x75, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x18, 0x0a,
+	0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x22, 0x85, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x65, 0x64,
+	0x52, 0x73, 0x70, 0x12, 0x1f, 0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x12, 0x1e, 0x0a, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x05, 0x46,
+	0x75, 0x6e, 0x63, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x12, 0x1f, 0x0a, 0x04, 0x41, 0x75, 0x74, 0x68,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x79, 0x52, 0x04, 0x41, 0x75, 0x74, 0x68, 0x22, 0x41, 0x0a, 0x0d, 0x47, 0x65, 0x74,
+	0x46, 0x75, 0x6e, 0x63, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x46, 0x75,
+	0x6e, 0x63, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x46, 0x75, 0x6e, 0x63,
+	0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x4b, 0x0a, 0x0d,
+	0x47, 0x65, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x4d, 0x73, 0x67, 0x52, 0x73, 0x70, 0x12, 0x1c, 0x0a,
+	0x04, 0x46, 0x75, 0x6e, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75,
+	0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x12, 0x1c, 0x0a, 0x04, 0x4c,
+	0x69, 0x73, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e, 0x63,
+	0x41, 0x70, 0x69, 0x52, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x37, 0x0a, 0x0d, 0x47, 0x65, 0x74,
+	0x4e, 0x6f, 0x64, 0x65, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x55, 0x75,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61,
+	0x6d, 0x65, 0x22, 0x4d, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x4d, 0x73, 0x67,
+	0x52, 0x73, 0x70, 0x12, 0x1d, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x44, 0x61,
+	0x74, 0x61, 0x12, 0x1d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x4c, 0x69, 0x73,
+	0x74, 0x22, 0x67, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x41, 0x70, 0x69, 0x43, 0x6f, 0x6e, 0x6e, 0x52,
+	0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x46, 0x75, 0x6e, 0x63, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x06, 0x46, 0x75, 0x6e, 0x63, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x41, 0x70,
+	0x69, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x41, 0x70, 0x69,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x55, 0x75, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x55, 0x75, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x41, 0x6c, 0x6c, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x41, 0x6c, 0x6c, 0x22, 0x89, 0x01, 0x0a, 0x0d, 0x47,
+	0x65, 0x74, 0x41, 0x70, 0x69, 0x43, 0x6f, 0x6e, 0x6e, 0x52, 0x73, 0x70, 0x12, 0x1c, 0x0a, 0x04,
+	0x46, 0x75, 0x6e, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e,
+	0x63, 0x4d, 0x73, 0x67, 0x52, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x12, 0x1d, 0x0a, 0x04, 0x4c, 0x69,
+	0x73, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49,
+	0x6e, 0x66, 0x6f, 0x52, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x04, 0x53, 0x74, 0x61,
+	0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x53, 0x79, 0x73, 0x74, 0x65, 0x6d,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x18, 0x0a, 0x07,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x44,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0x5f, 0x0a, 0x0b, 0x57, 0x61, 0x74, 0x63, 0x68, 0x65,
+	0x72, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04,
+	0x4c, 0x69, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x12, 0x1d, 0x0a, 0x04, 0x42, 0x61, 0x73, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x04, 0x42, 0x61, 0x73, 0x65, 0x22, 0x57, 0x0a, 0x0c, 0x45, 0x6c, 0x65, 0x63, 0x74,
+	0x56, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x1d, 0x0a, 0x04, 0x42, 0x61, 0x73, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x42, 0x61, 0x73, 0x65,
+	0x22, 0x38, 0x0a, 0x0c, 0x45, 0x6c, 0x65, 0x63, 0x74, 0x56, 0x6f, 0x74, 0x65, 0x52, 0x73, 0x70,
+	0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04,
+	0x54, 0x65, 0x72, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x22, 0x7a, 0x0a, 0x0c, 0x45, 0x6c,
+	0x65, 0x63, 0x74, 0x53, 0x79, 0x6e, 0x63, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65,
+	0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x12, 0x14,
+	0x0a, 0x05, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x1d, 0x0a, 0x04, 0x42, 0x61, 0x73, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x42,
+	0x61, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x22, 0x52, 0x0a, 0x0c, 0x45, 0x6c, 0x65, 0x63, 0x74, 0x53,
+	0x79, 0x6e, 0x63, 0x52, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x54, 0x65, 0x72, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x49, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x49, 0x6e, 0x64, 0x65, 0x78,
+	0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x92, 0x01, 0x0a, 0x0a, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1f, 0x0a, 0x05, 0x46, 0x75, 0x6e,
+	0x63, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x4e,
+	0x6f, 0x64, 0x65, 0x52, 0x05, 0x46, 0x75, 0x6e, 0x63, 0x73, 0x12, 0x1f, 0x0a, 0x05, 0x4e, 0x6f,
+	0x64, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65,
+	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x04, 0x53,
+	0x74, 0x61, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x53, 0x79, 0x73, 0x74,
+	0x65, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1f,
+	0x0a, 0x04, 0x54, 0x6f, 0x6d, 0x62, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x46,
+	0x75, 0x6e, 0x63, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x04, 0x54, 0x6f, 0x6d, 0x62, 0x22,
+	0x50, 0x0a, 0x08, 0x46, 0x75, 0x6e, 0x63, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x1c, 0x0a, 0x04, 0x46,
+	0x75, 0x6e, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e, 0x63,
+	0x41, 0x70, 0x69, 0x52, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x6f, 0x64,
+	0x65, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x53, 0x75, 0x62, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x53, 0x75, 0x62,
+	0x73, 0x22, 0x87, 0x01, 0x0a, 0x0a, 0x46, 0x75, 0x6e, 0x63, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64,
+	0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x49, 0x44,
+	0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x08, 0x2e, 0x41, 0x70, 0x69, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x1d, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x09, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x72, 0x52, 0x04, 0x4b, 0x69, 0x6e,
+	0x64, 0x12, 0x18, 0x0a, 0x07, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0x56, 0x0a, 0x0c, 0x44,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x70, 0x69, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x46,
+	0x75, 0x6e, 0x63, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x46, 0x75, 0x6e,
+	0x63, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x41, 0x70, 0x69, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x46, 0x6f,
+	0x72, 0x63, 0x65, 0x22, 0x2c, 0x0a, 0x0c, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x70, 0x69,
+	0x52, 0x73, 0x70, 0x12, 0x1c, 0x0a, 0x04, 0x46, 0x75, 0x6e, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x08, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x41, 0x70, 0x69, 0x52, 0x04, 0x46, 0x75, 0x6e,
+	0x63, 0x2a, 0x48, 0x0a, 0x0c, 0x53, 0x65, 0x72, 0x76, 0x69, 0x6e, 0x67, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x45, 0x52, 0x56, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0f,
+	0x0a, 0x0b, 0x4e, 0x4f, 0x54, 0x5f, 0x53, 0x45, 0x52, 0x56, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12,
+	0x0c, 0x0a, 0x08, 0x44, 0x52, 0x41, 0x49, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x0c, 0x0a,
+	0x08, 0x44, 0x45, 0x47, 0x52, 0x41, 0x44, 0x45, 0x44, 0x10, 0x03, 0x42, 0x07, 0x5a, 0x05, 0x2e,
+	0x2f, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_watch_proto_rawDescOnce sync.Once
+	file_watch_proto_rawDescData = file_watch_proto_rawDesc
+)
+
+func file_watch_proto_rawDescGZIP() []byte {
+	file_watch_proto_rawDescOnce.Do(func() {
+		file_watch_proto_rawDescData = protoimpl.X.CompressGZIP(file_watch_proto_rawDescData)
+	})
+	return file_watch_proto_rawDescData
+}
+
+var file_watch_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_watch_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_watch_proto_goTypes = []interface{}{
+	(ServingState)(0),     // 0: ServingState
+	(*SystemStatus)(nil),  // 1: SystemStatus
+	(*AuthPolicy)(nil),    // 2: AuthPolicy
+	(*AuthRule)(nil),      // 3: AuthRule
+	(*SendAllRsp)(nil),    // 4: SendAllRsp
+	(*SendRsp)(nil),       // 5: SendRsp
+	(*RegisteredRsp)(nil), // 6: RegisteredRsp
+	(*GetFuncMsgReq)(nil), // 7: GetFuncMsgReq
+	(*GetFuncMsgRsp)(nil), // 8: GetFuncMsgRsp
+	(*GetNodeMsgReq)(nil), // 9: GetNodeMsgReq
+	(*GetNodeMsgRsp)(nil), // 10: GetNodeMsgRsp
+	(*GetApiConnReq)(nil), // 11: GetApiConnReq
+	(*GetApiConnRsp)(nil), // 12: GetApiConnRsp
+	(*WatcherList)(nil),   // 13: WatcherList
+	(*ElectVoteReq)(nil),  // 14: ElectVoteReq
+	(*ElectVoteRsp)(nil),  // 15: ElectVoteRsp
+	(*ElectSyncReq)(nil),  // 16: ElectSyncReq
+	(*ElectSyncRsp)(nil),  // 17: ElectSyncRsp
+	(*WatchState)(nil),    // 18: WatchState
+	(*FuncNode)(nil),      // 19: FuncNode
+	(*FuncRecord)(nil),    // 20: FuncRecord
+	(*DeleteApiReq)(nil),  // 21: DeleteApiReq
+	(*DeleteApiRsp)(nil),  // 22: DeleteApiRsp
+	(*NodeInfo)(nil),      // 23: NodeInfo
+	(*FuncApi)(nil),       // 24: FuncApi
+	(*FuncMsg)(nil),       // 25: FuncMsg
+	(ApiType)(0),          // 26: ApiType
+	(Compiler)(0),         // 27: Compiler
+}
+var file_watch_proto_depIdxs = []int32{
+	0,  // 0: SystemStatus.State:type_name -> ServingState
+	3,  // 1: AuthPolicy.Rules:type_name -> AuthRule
+	5,  // 2: SendAllRsp.Result:type_name -> SendRsp
+	23, // 3: RegisteredRsp.Watch:type_name -> NodeInfo
+	24, // 4: RegisteredRsp.Funcs:type_name -> FuncApi
+	2,  // 5: RegisteredRsp.Auth:type_name -> AuthPolicy
+	24, // 6: GetFuncMsgRsp.Func:type_name -> FuncApi
+	24, // 7: GetFuncMsgRsp.List:type_name -> FuncApi
+	23, // 8: GetNodeMsgRsp.Data:type_name -> NodeInfo
+	23, // 9: GetNodeMsgRsp.List:type_name -> NodeInfo
+	25, // 10: GetApiConnRsp.Func:type_name -> FuncMsg
+	23, // 11: GetApiConnRsp.List:type_name -> NodeInfo
+	1,  // 12: GetApiConnRsp.Stat:type_name -> SystemStatus
+	23, // 13: WatcherList.List:type_name -> NodeInfo
+	23, // 14: WatcherList.Base:type_name -> NodeInfo
+	23, // 15: ElectVoteReq.Base:type_name -> NodeInfo
+	23, // 16: ElectSyncReq.Base:type_name -> NodeInfo
+	18, // 17: ElectSyncReq.State:type_name -> WatchState
+	19, // 18: WatchState.Funcs:type_name -> FuncNode
+	23, // 19: WatchState.Nodes:type_name -> NodeInfo
+	1,  // 20: WatchState.Stat:type_name -> SystemStatus
+	20, // 21: WatchState.Tomb:type_name -> FuncRecord
+	24, // 22: FuncNode.Func:type_name -> FuncApi
+	26, // 23: FuncRecord.Type:type_name -> ApiType
+	27, // 24: FuncRecord.Kind:type_name -> Compiler
+	24, // 25: DeleteApiRsp.Func:type_name -> FuncApi
+	26, // [26:26] is the sub-list for method output_type
+	26, // [26:26] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
+}
+
+func init() { file_watch_proto_init() }
+func file_watch_proto_init() {
+	if File_watch_proto != nil {
+		return
+	}
+	file_node_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_watch_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SystemStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AuthPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
 			}
 		}
 		file_watch_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SendRsp); i {
+			switch v := v.(*AuthRule); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -762,7 +1754,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RegisteredRsp); i {
+			switch v := v.(*SendAllRsp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -774,7 +1766,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetFuncMsgReq); i {
+			switch v := v.(*SendRsp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -786,7 +1778,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetFuncMsgRsp); i {
+			switch v := v.(*RegisteredRsp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -798,7 +1790,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetNodeMsgReq); i {
+			switch v := v.(*GetFuncMsgReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -810,7 +1802,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetNodeMsgRsp); i {
+			switch v := v.(*GetFuncMsgRsp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -822,7 +1814,7 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetApiConnReq); i {
+			switch v := v.(*GetNodeMsgReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -834,6 +1826,30 @@ func file_watch_proto_init() {
 			}
 		}
 		file_watch_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetNodeMsgRsp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetApiConnReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetApiConnRsp); i {
 			case 0:
 				return &v.state
@@ -845,19 +1861,140 @@ func file_watch_proto_init() {
 				return nil
 			}
 		}
+		file_watch_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatcherList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ElectVoteReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ElectVoteRsp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ElectSyncReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ElectSyncRsp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FuncNode); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FuncRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteApiReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_watch_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteApiRsp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_watch_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   10,
+			NumEnums:      1,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_watch_proto_goTypes,
 		DependencyIndexes: file_watch_proto_depIdxs,
+		EnumInfos:         file_watch_proto_enumTypes,
 		MessageInfos:      file_watch_proto_msgTypes,
 	}.Build()
 	File_watch_proto = out.File
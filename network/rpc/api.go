@@ -1,14 +1,9 @@
 package rpc
 
 import (
-	"bytes"
 	"context"
-	"errors"
-	"fmt"
-	"io/ioutil"
 	"log"
 	"micro/timer"
-	"net/http"
 	"time"
 
 	"micro/network"
@@ -102,7 +97,7 @@ func (n *NodeDetail) CallMultiByUuid(uuid, name string, args ...interface{}) net
 func (n *NodeDetail) CallRemoteByte(duration time.Duration, uuid, name string, req []byte) network.CallByte {
 	_, apiname := SplitApiName(name)
 	if apiname == "" {
-		return &CallResp{msg: &pb.NodeInfo{}, err: errors.New("name cannot be null")}
+		return &CallResp{msg: &pb.NodeInfo{}, err: NewStatus(CodeInvalidArgument, "name cannot be null")}
 	}
 
 	ctx, cancel := context.WithTimeout(context.TODO(), duration)
@@ -111,7 +106,7 @@ func (n *NodeDetail) CallRemoteByte(duration time.Duration, uuid, name string, r
 	tmp := n.fmsg.Query(0, apiname)
 	if tmp == nil {
 		return &CallResp{msg: &pb.NodeInfo{}, con: "Remote",
-			err: errors.New("not found function to request: " + apiname)}
+			err: NewStatus(CodeNotFound, "not found function to request: "+apiname)}
 	}
 	fmsg := tmp.GetMsg()
 	conns := n.fmsg.GetFuncConn(0, apiname)
@@ -138,79 +133,32 @@ TryRemote:
 				}
 			}
 			n.fmsg.UpFuncNode(fmsg.FuncID, ids)
+			upConnStatus(conns, rsp.Stat)
 		}
 		if len(conns) == 0 {
 			return &CallResp{msg: &pb.NodeInfo{}, con: "Remote",
-				err: errors.New("not found node to request: " + fmsg.ApiName)}
+				err: NewStatus(CodeUnavailable, "not found node to request: "+fmsg.ApiName)}
 		}
 		remote = true
 	}
 
-	var num = n.serial(fmsg.FuncID)
-	var tcpRow, udpRow [][]byte
-	for _, conn := range conns {
-		if conn.Uuid == n.Uuid || conn.wrong {
-			continue
-		}
-		switch conn.types {
-		case ConnWithTCP:
-			if len(tcpRow) == 0 {
-				tcpRow = tcpsplit.MakeReqBody(req, num, int(fmsg.FuncID))
-			}
-			for _, row := range tcpRow {
-				if _, err := conn.tconn.Write(row); err != nil {
-					goto NextConn
-				}
-			}
-			body, err := conn.WaitRspByte(ctx, num, fmsg)
-			return &CallResp{msg: &conn.NodeInfo, con: "TCP", rsp: body, err: err}
-
-		case ConnWithUDP:
-			if len(udpRow) == 0 {
-				udpRow = udpsplit.MakeReqBody(req, num, int(fmsg.FuncID))
-			}
-			for _, row := range udpRow {
-				if _, err := conn.uconn.Write(row); err != nil {
-					goto NextConn
-				}
-			}
-			body, err := conn.WaitRspByte(ctx, num, fmsg)
-			return &CallResp{msg: &conn.NodeInfo, con: "UDP", rsp: body, err: err}
-
-		case ConnWithHTTP:
-			var addr = fmt.Sprintf("http://%s:%d/%s", conn.Host, conn.Hport, fmsg.ApiName)
-			if resp, err := http.Post(addr, "application/octet-stream",
-				bytes.NewBuffer(req)); err == nil {
-				defer resp.Body.Close()
-				code := resp.Header.Get("code")
-
-				if code == HttpReqSuccessBody {
-					body, err := ioutil.ReadAll(resp.Body)
-					return &CallResp{msg: &conn.NodeInfo, con: "UDP", rsp: body, err: err}
-				} else if code == HttpReqSuccessNull {
-					return &CallResp{msg: &conn.NodeInfo, con: "UDP"}
-				} else if code == HttpReqFailMessage {
-					body, _ := ioutil.ReadAll(resp.Body)
-					return &CallResp{msg: &conn.NodeInfo, con: "UDP", err: errors.New(string(body))}
-				}
-			}
-		}
-	NextConn:
+	if body, conn, err := n.retryCall(ctx, conns, fmsg, req); conn != nil {
+		return &CallResp{msg: &conn.NodeInfo, con: conn.network(), rsp: body, err: err}
 	}
 
 	if !remote {
 		conns = nil
 		goto TryRemote
 	}
-	return &CallResp{err: errors.New("call all server node with api, but all wrong"),
+	return &CallResp{err: NewStatus(CodeUnavailable, "call all server node with api, but all wrong"),
 		msg: &pb.NodeInfo{}, con: "Remote"}
 }
 
-func (n *NodeConn) WaitRspByte(ctx context.Context, num int, fmsg *pb.FuncMsg) ([]byte, error) {
-	var c = n.NewChan(num)
-	defer n.DelChan(num)
+func (n *NodeConn) WaitRspByte(ctx context.Context, c *RecvChan) ([]byte, error) {
+	defer n.DelChan(c.num)
 	select {
 	case <-ctx.Done():
+		n.sendCancel(c.num)
 		return nil, ctx.Err()
 	case buff := <-c.body:
 		return buff, nil
@@ -228,20 +176,20 @@ func (n *NodeDetail) CallMultiByByte(duration time.Duration, uuid, name string,
 		if bts, err := MarshalInterface(pb.Compiler_PROTO, req); err == nil {
 			return n.CallRemoteByte(duration, uuid, name, bts)
 		} else {
-			return &CallResp{err: errors.New("combined request body error: " + err.Error()),
+			return &CallResp{err: NewStatus(CodeInternal, "combined request body error: "+err.Error()),
 				msg: &pb.NodeInfo{}, con: "Local"}
 		}
 	} else {
-		return &CallResp{err: errors.New("call args cannot null"), msg: &pb.NodeInfo{}, con: "Local"}
+		return &CallResp{err: NewStatus(CodeInvalidArgument, "call args cannot null"), msg: &pb.NodeInfo{}, con: "Local"}
 	}
 }
 
 func (n *NodeDetail) WatchSend(dur time.Duration, uuid string, fid uint32, req []byte) error {
 	if uuid == "" || fid == 0 {
-		return errors.New("uuid and function id cannot be null")
+		return NewStatus(CodeInvalidArgument, "uuid and function id cannot be null")
 	}
 	if conn := n.fmsg.GetNodeConn(uuid); conn == nil {
-		return errors.New("not found this uuid")
+		return NewStatus(CodeNotFound, "not found this uuid")
 	} else {
 		ctx, cancel := context.WithTimeout(context.TODO(), dur)
 		defer cancel()
@@ -253,10 +201,10 @@ func (n *NodeDetail) WatchSend(dur time.Duration, uuid string, fid uint32, req [
 
 func (n *NodeDetail) WatchCall(dur time.Duration, uuid string, fid uint32, req []byte, rsp interface{}) error {
 	if uuid == "" || fid == 0 {
-		return errors.New("uuid and function id cannot be null")
+		return NewStatus(CodeInvalidArgument, "uuid and function id cannot be null")
 	}
 	if conn := n.fmsg.GetNodeConn(uuid); conn == nil {
-		return errors.New("not found this uuid")
+		return NewStatus(CodeNotFound, "not found this uuid")
 	} else {
 		ctx, cancel := context.WithTimeout(context.TODO(), dur)
 		defer cancel()
@@ -265,3 +213,11 @@ func (n *NodeDetail) WatchCall(dur time.Duration, uuid string, fid uint32, req [
 		return resp.Err()
 	}
 }
+
+// request api by the connection, fmsg need api name when connection is http
+func (n *NodeDetail) ConnCall(ctx context.Context, conn *NodeConn, fmsg *pb.FuncMsg, req []byte, rsp interface{}) error {
+	if conn == nil || fmsg == nil {
+		return NewStatus(CodeInvalidArgument, "connection and function message cannot be null")
+	}
+	return n.connsCall(ctx, []*NodeConn{conn}, fmsg, req, rsp).Err()
+}
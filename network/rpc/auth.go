@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"crypto/x509"
+	"path"
+	"sync/atomic"
+
+	"micro/network/pb"
+)
+
+// AuthCallerAll : rule caller matched all nodes
+const AuthCallerAll = "*"
+
+// authorization policy from watcher, nil when not use
+func (n *NodeDetail) authPolicy() *pb.AuthPolicy {
+	policy, _ := n.auth.Load().(*pb.AuthPolicy)
+	return policy
+}
+
+// set policy from watcher, policy without rule from unverified source cannot replace the last good policy
+func (n *NodeDetail) setAuthPolicy(policy *pb.AuthPolicy, verified bool) error {
+	if policy == nil {
+		policy = &pb.AuthPolicy{}
+	}
+	if old := n.authPolicy(); !verified && len(policy.Rules) == 0 && len(old.GetRules()) > 0 {
+		return NewStatus(CodePermissionDenied, "authorization policy without rule was not verified")
+	}
+	n.auth.Store(policy)
+	atomic.StoreUint64(&n.wser.authver, policy.Version)
+	return nil
+}
+
+// api matched by rules can only be called by callers of these rules,
+// caller is verified identity: certificate common name or name of signed register node,
+// caller cannot be verified ("") only matched by all caller rule,
+// node of watcher rejects all request before policy received
+func (n *NodeDetail) authorize(api string, caller string) error {
+	policy := n.authPolicy()
+	if policy == nil && n.wser != nil && len(n.wser.config) > 0 {
+		return NewStatus(CodeUnavailable, "authorization policy was not received from watcher")
+	} else if len(policy.GetRules()) == 0 {
+		return nil
+	}
+	var matched bool
+	for _, rule := range policy.Rules {
+		if !matchApi(rule.Apis, api) {
+			continue
+		}
+		matched = true
+		if rule.Caller == AuthCallerAll || (caller != "" && rule.Caller == caller) {
+			return nil
+		}
+	}
+	if !matched {
+		return nil
+	} else if caller == "" {
+		caller = "unverified"
+	}
+	return Errorf(CodePermissionDenied, "caller %s was not allowed to call %s", caller, api)
+}
+
+type identityKey struct{}
+type peerKey struct{}
+
+// CallerIdentity : verified caller of the server request, certificate common name
+// or name of the node registered with signature, "" when not verified
+func CallerIdentity(ctx context.Context) string {
+	name, _ := ctx.Value(identityKey{}).(string)
+	return name
+}
+
+func withIdentity(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, identityKey{}, name)
+}
+
+// RegisteredPeer : node registered by the accepted tcp connection of the server request,
+// it was verified only when node signature was checked, nil when not registered
+func RegisteredPeer(ctx context.Context) *pb.NodeInfo {
+	node, _ := ctx.Value(peerKey{}).(*pb.NodeInfo)
+	return node
+}
+
+func withPeer(ctx context.Context, node *pb.NodeInfo) context.Context {
+	if node == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, peerKey{}, node)
+}
+
+// common name of verified certificate, "" when not use
+func certName(cert *x509.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+	return cert.Subject.CommonName
+}
+
+func matchApi(patterns []string, api string) bool {
+	for _, row := range patterns {
+		if ok, _ := path.Match(row, api); ok {
+			return true
+		}
+	}
+	return false
+}
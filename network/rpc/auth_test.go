@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"micro/network/comm"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type TstAuthReq struct{}
+type TstAuthRsp struct {
+	Caller string `json:"caller"`
+}
+type TstAuth struct{}
+
+func (s *TstAuth) Compiler_JSON() {}
+
+func (s *TstAuth) Call(req *TstAuthReq, rsp *TstAuthRsp) error { return nil }
+
+func (s *TstAuth) Who(ctx context.Context, req *TstAuthReq, rsp *TstAuthRsp) error {
+	rsp.Caller = CallerIdentity(ctx)
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+	client, server := testNodePair(t, &TstAuth{})
+	var call = func() error {
+		return client.CallAuto("TstAuth.Call", &TstAuthReq{}, &TstAuthRsp{}).Err()
+	}
+	if err := call(); err != nil {
+		t.Fatal("api must be allowed without policy: ", err)
+	}
+
+	server.setAuthPolicy(&pb.AuthPolicy{Version: 1, Rules: []*pb.AuthRule{
+		{Caller: "Gateway", Apis: []string{"TstAuth.*"}},
+		{Caller: AuthCallerAll, Apis: []string{"Other.*"}},
+	}}, true)
+	if err := call(); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("caller not in rule must be denied: ", err)
+	}
+	// node name of metadata was not verified
+	client.Name = "Gateway"
+	if err := call(); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("spoofed node name must be denied: ", err)
+	}
+	var accepted = func(function func(nc *NodeConn)) {
+		server.lis.conns.Range(func(key, value interface{}) bool {
+			function(key.(*NodeConn))
+			return true
+		})
+	}
+	accepted(func(nc *NodeConn) { nc.setPeer(&pb.NodeInfo{Uuid: "C", Name: "Gateway"}, false) })
+	if err := call(); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("register node without signature must be denied: ", err)
+	}
+
+	// register message was verified by signature
+	var dir = t.TempDir()
+	prifile, pubfile := testSignKey(t, dir, "gateway")
+	node, err := newSigner(prifile, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if server.sign, err = newSigner("", map[string][]string{pubfile: {"Gateway"}}); err != nil {
+		t.Fatal(err)
+	}
+	bts, err := node.marshal(&pb.NodeInfo{Uuid: "C", Name: "Gateway", Host: "127.0.0.1", Tport: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted(func(nc *NodeConn) {
+		if _, err := server.builtin(comm.DialRegister, nc, bts); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := call(); err != nil {
+		t.Fatal("caller in rule must be allowed: ", err)
+	}
+	var who = &TstAuthRsp{}
+	if err := client.CallAuto("TstAuth.Who", &TstAuthReq{}, who).Err(); err != nil || who.Caller != "Gateway" {
+		t.Fatal("verified caller must be in context: ", err, who.Caller)
+	}
+	var cert = &x509.Certificate{Subject: pkix.Name{CommonName: "Gateway"}}
+	if server.authorize("TstAuth.Call", certName(cert)) != nil {
+		t.Fatal("caller certificate in rule must be allowed")
+	} else if server.authorize("TstAuth.Call", "") == nil {
+		t.Fatal("caller not verified must be denied")
+	} else if server.authorize("Billing.Charge", "") != nil {
+		t.Fatal("api matched by no rule must be allowed")
+	} else if server.authorize("Other.Call", "") != nil {
+		t.Fatal("all caller rule must be allowed")
+	}
+
+	// http
+	var svc = server.funcs["TstAuth"]
+	server.httpCall("TstAuth.Call", svc, svc.funcs["Call"])
+	var mux = http.NewServeMux()
+	for key, function := range server.hlist {
+		mux.HandleFunc("/"+key, function)
+	}
+	var hs = httptest.NewServer(mux)
+	defer hs.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(hs.URL, "http://"))
+	hport, _ := strconv.ParseUint(port, 10, 64)
+	var nc = &NodeConn{NodeInfo: pb.NodeInfo{Host: host, Hport: hport}}
+	var fmsg = &pb.FuncMsg{ApiName: "TstAuth.Call", Protocal: pb.Compiler_JSON}
+	if _, err, _ := postHttpByte(context.TODO(), nc, fmsg, []byte(`{}`)); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("http caller must be denied: ", err)
+	}
+	ctx := AppendOutgoingContext(context.TODO(), MetaNodeUuid, "G", MetaNodeName, "Gateway")
+	if _, err, _ := postHttpByte(ctx, nc, fmsg, []byte(`{}`)); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("spoofed http header must be denied: ", err)
+	}
+
+	// policy only set by watcher
+	bts, _ = proto.Marshal(&pb.AuthPolicy{Version: 2})
+	if _, err := server.builtin(comm.UpAuthPolicy, &NodeConn{}, bts); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("policy from other node must be denied: ", err)
+	}
+	var watcher = &NodeConn{peer: &pb.NodeInfo{Name: comm.WatchNodeName}}
+	if _, err := server.builtin(comm.UpAuthPolicy, watcher, bts); ErrorCode(err) != CodePermissionDenied {
+		t.Fatal("policy from unsigned watcher must be denied: ", err)
+	} else if err = server.setAuthPolicy(&pb.AuthPolicy{Version: 2}, false); err == nil || server.authPolicy().Version != 1 {
+		t.Fatal("empty policy not verified must not replace the last policy: ", err)
+	}
+	watcher.signed = true
+	if _, err := server.builtin(comm.UpAuthPolicy, watcher, bts); err != nil {
+		t.Fatal(err)
+	} else if server.wser.SystemState().AuthVersion != 2 {
+		t.Fatal("policy version must be reported by heartbeat")
+	}
+	// watcher list and api nodes only pushed by watcher
+	var unsigned = &NodeConn{peer: &pb.NodeInfo{Name: comm.WatchNodeName}}
+	var signed = &NodeDetail{sign: server.sign, fmsg: &funcmap{}, wser: &WatchNode{}}
+	var plain = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}}
+	for _, fid := range []int{comm.UpWatcherList, comm.UpNodeConnMsg} {
+		if _, err := signed.builtin(fid, unsigned, nil); ErrorCode(err) != CodePermissionDenied {
+			t.Fatal("push from unsigned watcher must be denied: ", err)
+		} else if _, err = signed.builtin(fid, watcher, nil); err != nil {
+			t.Fatal(err)
+		} else if _, err = plain.builtin(fid, unsigned, nil); err != nil {
+			t.Fatal("push from registered watcher must be allowed when node not verify: ", err)
+		} else if _, err = plain.builtin(fid, &NodeConn{}, nil); ErrorCode(err) != CodePermissionDenied {
+			t.Fatal("push from other connection must be denied: ", err)
+		} else if _, err = plain.builtin(fid, &NodeConn{types: ConnWithUDP}, nil); err != nil {
+			t.Fatal("udp push must be allowed when node not verify: ", err)
+		} else if _, err = signed.builtin(fid, &NodeConn{types: ConnWithUDP}, nil); ErrorCode(err) != CodePermissionDenied {
+			t.Fatal("udp push must be denied when node verify caller: ", err)
+		}
+		// http built in request has no registered connection
+		body, _ := json.Marshal(&comm.CommReq{Func: fid})
+		var rec = httptest.NewRecorder()
+		plain.httpBuiltIn(rec, httptest.NewRequest(http.MethodPost, "/"+comm.BUILT_IN_NAME, bytes.NewReader(body)))
+		if st := statusFromHeader(rec.Header(), rec.Body.String()); st.Code != CodePermissionDenied {
+			t.Fatal("http push must be denied: ", st)
+		}
+	}
+	client.Name = ""
+	if err := call(); err != nil {
+		t.Fatal("api must be allowed by empty policy: ", err)
+	}
+
+	// node of watcher wait policy
+	var wait = &NodeDetail{wser: &WatchNode{config: []*pb.NodeInfo{{Host: "127.0.0.1"}}}}
+	if err := wait.authorize("TstAuth.Call", ""); ErrorCode(err) != CodeUnavailable {
+		t.Fatal("request must be rejected before policy received: ", err)
+	}
+}
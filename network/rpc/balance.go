@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"micro/network/pb"
+)
+
+const (
+	BalanceRoundRobin   = "RoundRobin"   // 轮询
+	BalanceWeightRandom = "WeightRandom" // 按权重随机
+	BalanceLeastActive  = "LeastActive"  // 最少并发请求
+	BalanceSystemLoad   = "SystemLoad"   // 按节点系统负载(CPU, 内存)加权随机
+
+	// default node weight to load balance
+	DefaultWeight = 100
+)
+
+// Balancer : sort remote connections of api to request,
+// the first one will be called first, the others used when it was wrong
+type Balancer interface {
+	Select(fmsg *pb.FuncMsg, conns []*NodeConn) []*NodeConn
+}
+
+var balancers = struct {
+	sync.RWMutex
+	list map[string]func() Balancer
+}{list: map[string]func() Balancer{
+	BalanceRoundRobin:   func() Balancer { return &roundRobin{} },
+	BalanceWeightRandom: func() Balancer { return &weightRandom{weight: connWeight} },
+	BalanceLeastActive:  func() Balancer { return &leastActive{} },
+	BalanceSystemLoad:   func() Balancer { return &weightRandom{weight: systemWeight} },
+}}
+
+// RegisterBalancer : add custom balancer, can be used by name in NodeConfig
+func RegisterBalancer(name string, function func() Balancer) error {
+	if name == "" || function == nil {
+		return errors.New("balancer name and function cannot be null")
+	}
+	balancers.Lock()
+	balancers.list[name] = function
+	balancers.Unlock()
+	return nil
+}
+
+// NewBalancer : make balancer by name, default: RoundRobin
+func NewBalancer(name string) (Balancer, error) {
+	if name == "" {
+		name = BalanceRoundRobin
+	}
+	balancers.RLock()
+	function, ok := balancers.list[name]
+	balancers.RUnlock()
+	if !ok {
+		return nil, errors.New("not found load balance strategy: " + name)
+	}
+	return function(), nil
+}
+
+// SetBalancer : set load balance strategy of api name, empty name is default
+func (n *NodeDetail) SetBalancer(apiname string, b Balancer) {
+	if b == nil {
+		return
+	}
+	if apiname == "" {
+		n.balance.Store(balanceValue{b})
+	} else {
+		n.apibal.Store(apiname, b)
+	}
+}
+
+// sort connections by api load balance strategy
+func (n *NodeDetail) selectConn(fmsg *pb.FuncMsg, conns []*NodeConn) []*NodeConn {
+	if len(conns) <= 1 || fmsg == nil {
+		return conns
+	}
+	if v, ok := n.apibal.Load(fmsg.ApiName); ok && v != nil {
+		return v.(Balancer).Select(fmsg, conns)
+	}
+	if v, ok := n.balance.Load().(balanceValue); ok {
+		return v.Select(fmsg, conns)
+	}
+	return conns
+}
+
+// default balancer stored in atomic.Value, concrete type must be same
+type balanceValue struct{ Balancer }
+
+// Active : number of request waiting response
+func (n *NodeConn) Active() int {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	return len(n.rc)
+}
+
+// LoadWeight : node weight to load balance, reduced by WarmUpRate in slow start
+func (n *NodeConn) LoadWeight() uint32 {
+	var weight = n.Weight
+	if weight == 0 {
+		weight = DefaultWeight
+	}
+	if rate := n.WarmUpRate(); rate < 1 {
+		weight = uint32(math.Ceil(float64(weight) * rate))
+	}
+	return weight
+}
+
+// SystemStatus : last system status of node, report by watcher
+func (n *NodeConn) SystemStatus() *pb.SystemStatus {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	return n.stat
+}
+
+func (n *NodeConn) setSystemStatus(stat *pb.SystemStatus) {
+	n.mut.Lock()
+	n.stat = stat
+	n.mut.Unlock()
+}
+
+// round robin by api name
+type roundRobin struct {
+	next sync.Map // map[api-name]*uint32
+}
+
+func (r *roundRobin) Select(fmsg *pb.FuncMsg, conns []*NodeConn) []*NodeConn {
+	v, _ := r.next.LoadOrStore(fmsg.ApiName, new(uint32))
+	start := int(atomic.AddUint32(v.(*uint32), 1) % uint32(len(conns)))
+
+	var result = make([]*NodeConn, 0, len(conns))
+	result = append(result, conns[start:]...)
+	result = append(result, conns[:start]...)
+	// node in slow start was skipped by the weight rate
+	if rate := result[0].WarmUpRate(); rate < 1 && rand.Float64() >= rate {
+		result = append(result[1:], result[0])
+	}
+	return result
+}
+
+// weight random, sort by weighted random sampling
+type weightRandom struct {
+	weight func(*NodeConn) float64
+}
+
+func (w *weightRandom) Select(fmsg *pb.FuncMsg, conns []*NodeConn) []*NodeConn {
+	type sortKey struct {
+		key  float64
+		conn *NodeConn
+	}
+	var keys = make([]sortKey, len(conns))
+	for i, conn := range conns {
+		keys[i].conn = conn
+		if weight := w.weight(conn); weight > 0 {
+			keys[i].key = math.Pow(rand.Float64(), 1/weight)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+
+	var result = make([]*NodeConn, len(keys))
+	for i := range keys {
+		result[i] = keys[i].conn
+	}
+	return result
+}
+
+// least active request, same active use bigger weight
+type leastActive struct{}
+
+func (l *leastActive) Select(fmsg *pb.FuncMsg, conns []*NodeConn) []*NodeConn {
+	var result = make([]*NodeConn, len(conns))
+	var active = make(map[*NodeConn]int, len(conns))
+	for i, j := range rand.Perm(len(conns)) {
+		result[i] = conns[j]
+		active[conns[j]] = conns[j].Active()
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if active[result[i]] != active[result[j]] {
+			return active[result[i]] < active[result[j]]
+		}
+		return result[i].LoadWeight() > result[j].LoadWeight()
+	})
+	return result
+}
+
+func connWeight(n *NodeConn) float64 { return float64(n.LoadWeight()) }
+
+// weight of cpu idle rate and memory free rate
+// CpuRate: per mille, MemFree & MemUsed: same unit
+func systemWeight(n *NodeConn) float64 {
+	var weight = float64(n.LoadWeight())
+	stat := n.SystemStatus()
+	if stat == nil {
+		return weight
+	}
+	if stat.CpuRate < 1000 {
+		weight *= float64(1000-stat.CpuRate) / 1000
+	} else {
+		weight *= 0.01
+	}
+	if total := stat.MemFree + stat.MemUsed; total > 0 {
+		weight *= 0.2 + 0.8*float64(stat.MemFree)/float64(total)
+	}
+	return weight
+}
@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"micro/network/pb"
+)
+
+func testBalanceConns(weights ...uint32) []*NodeConn {
+	var conns []*NodeConn
+	for i, weight := range weights {
+		conn := &NodeConn{rc: make(map[int]*RecvChan)}
+		conn.Uuid = string(rune('A' + i))
+		conn.Weight = weight
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+func TestBalanceRoundRobin(t *testing.T) {
+	b, err := NewBalancer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fmsg = &pb.FuncMsg{ApiName: "Tsv.GetName"}
+	var conns = testBalanceConns(100, 100, 100)
+	var count = make(map[string]int)
+	for i := 0; i < 300; i++ {
+		rows := b.Select(fmsg, conns)
+		if len(rows) != len(conns) {
+			t.Fatal("balance select lost connection")
+		}
+		count[rows[0].Uuid]++
+	}
+	for _, conn := range conns {
+		if count[conn.Uuid] != 100 {
+			t.Errorf("round robin node %s called %d times", conn.Uuid, count[conn.Uuid])
+		}
+	}
+}
+
+func TestBalanceWeightRandom(t *testing.T) {
+	b, _ := NewBalancer(BalanceWeightRandom)
+	var fmsg = &pb.FuncMsg{ApiName: "Tsv.GetName"}
+	var conns = testBalanceConns(800, 100, 100)
+	var count = make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		count[b.Select(fmsg, conns)[0].Uuid]++
+	}
+	if count["A"] < 7500 || count["A"] > 8500 {
+		t.Errorf("weight random result wrong: %v", count)
+	}
+}
+
+func TestBalanceLeastActive(t *testing.T) {
+	b, _ := NewBalancer(BalanceLeastActive)
+	var conns = testBalanceConns(100, 100, 100)
+	conns[0].rc[1], conns[0].rc[2] = &RecvChan{}, &RecvChan{}
+	conns[2].rc[1] = &RecvChan{}
+	rows := b.Select(&pb.FuncMsg{}, conns)
+	if rows[0].Uuid != "B" || rows[1].Uuid != "C" || rows[2].Uuid != "A" {
+		t.Errorf("least active sort wrong: %s %s %s", rows[0].Uuid, rows[1].Uuid, rows[2].Uuid)
+	}
+}
+
+func TestBalanceSystemLoad(t *testing.T) {
+	b, _ := NewBalancer(BalanceSystemLoad)
+	var fmsg = &pb.FuncMsg{ApiName: "Tsv.GetName"}
+	var conns = testBalanceConns(100, 100)
+	conns[0].setSystemStatus(&pb.SystemStatus{CpuRate: 950, MemFree: 10, MemUsed: 90})
+	conns[1].setSystemStatus(&pb.SystemStatus{CpuRate: 50, MemFree: 90, MemUsed: 10})
+	var count = make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		count[b.Select(fmsg, conns)[0].Uuid]++
+	}
+	if count["B"] < count["A"]*5 {
+		t.Errorf("system load balance result wrong: %v", count)
+	}
+}
+
+func TestBalanceWarmUp(t *testing.T) {
+	var conns = testBalanceConns(100, 100)
+	conns[0].WarmUp = 10000
+	conns[0].startWarmUp()
+	if rate := conns[0].WarmUpRate(); rate != WarmUpMinRate || conns[0].LoadWeight() != 10 {
+		t.Fatalf("slow start must begin with min rate: %v", rate)
+	}
+
+	// half of the time, half weight
+	var half = time.Now().Add(-time.Second * 5).UnixMilli()
+	conns[0].warm.start = half
+	if weight := conns[0].LoadWeight(); weight < 50 || weight > 51 {
+		t.Fatalf("slow start weight wrong: %d", weight)
+	}
+	// round robin keep half turns of node A, weight random by weight 50:100
+	for name, expect := range map[string]int{BalanceRoundRobin: 2500, BalanceWeightRandom: 3333} {
+		b, _ := NewBalancer(name)
+		var count = make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			count[b.Select(&pb.FuncMsg{ApiName: "Tsv.GetName"}, conns)[0].Uuid]++
+		}
+		if count["A"] < expect-500 || count["A"] > expect+500 {
+			t.Errorf("%s slow start balance wrong: %v", name, count)
+		}
+	}
+
+	// failed request reduce weight
+	for i := 0; i < warmUpMinCalls; i++ {
+		conns[0].warmReport(i%2 == 0)
+	}
+	conns[0].warm.start = half
+	if weight := conns[0].LoadWeight(); weight < 25 || weight > 26 {
+		t.Fatalf("slow start weight by success rate wrong: %d", weight)
+	}
+
+	conns[0].warm.start = time.Now().Add(-time.Second * 10).UnixMilli()
+	if conns[0].LoadWeight() != 100 || conns[0].warm.start != 0 {
+		t.Fatal("slow start must be finished")
+	}
+	conns[0].warmReport(true)
+	if conns[0].WarmUpRate() != 1 {
+		t.Fatal("request after slow start was not counted")
+	}
+}
+
+func TestBalanceUnknown(t *testing.T) {
+	if _, err := NewBalancer("NotExist"); err == nil {
+		t.Error("unknown balancer should return error")
+	}
+}
+
+func TestBalanceSetDefault(t *testing.T) {
+	var node = &NodeDetail{}
+	var fmsg = &pb.FuncMsg{ApiName: "Tsv.GetName"}
+	var conns = testBalanceConns(100, 100)
+	var done = make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if len(node.selectConn(fmsg, conns)) != len(conns) {
+				t.Error("balance select lost connection")
+			}
+		}
+	}()
+	// change default balancer of other type while selecting
+	for _, name := range []string{BalanceRoundRobin, BalanceLeastActive, BalanceWeightRandom} {
+		b, err := NewBalancer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.SetBalancer("", b)
+	}
+	<-done
+}
@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"micro/network"
+	"micro/network/comm"
+)
+
+const (
+	BreakerClosed   = int32(0) // 正常请求
+	BreakerOpen     = int32(1) // 熔断, 定时 ping 节点
+	BreakerHalfOpen = int32(2) // ping 成功, 允许一个请求测试
+
+	DefaultBreakerFailures = 5
+	DefaultBreakerOpen     = time.Second
+	DefaultBreakerMaxOpen  = time.Minute
+	BreakerProbeTimeout    = time.Second * 3
+)
+
+// circuit breaker of the node connection
+type breaker struct {
+	mut   sync.Mutex
+	state int32
+	fails int           // consecutive failed request
+	wait  time.Duration // ping interval when open
+	trial bool          // request of half-open was running
+}
+
+func newBreakerConfig(config *network.BreakerConfig) *network.BreakerConfig {
+	var result = network.BreakerConfig{}
+	if config != nil {
+		result = *config
+	}
+	if result.Failures <= 0 {
+		result.Failures = DefaultBreakerFailures
+	}
+	if result.OpenTimeout <= 0 {
+		result.OpenTimeout = DefaultBreakerOpen
+	}
+	if result.MaxOpenTimeout < result.OpenTimeout {
+		result.MaxOpenTimeout = DefaultBreakerMaxOpen
+		if result.MaxOpenTimeout < result.OpenTimeout {
+			result.MaxOpenTimeout = result.OpenTimeout
+		}
+	}
+	return &result
+}
+
+// BreakerState : circuit breaker state of the node, [BreakerClosed, BreakerOpen, BreakerHalfOpen]
+func (n *NodeConn) BreakerState() int32 {
+	n.brk.mut.Lock()
+	defer n.brk.mut.Unlock()
+	return n.brk.state
+}
+
+// node can be requested, only one request when half-open
+func (n *NodeConn) allow() bool {
+	n.brk.mut.Lock()
+	defer n.brk.mut.Unlock()
+	switch n.brk.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if !n.brk.trial {
+			n.brk.trial = true
+			return true
+		}
+	}
+	return false
+}
+
+// node was wrong by the request error, server api error was not
+func breakerFailed(err error) bool {
+	if err == nil {
+		return false
+	} else if isConnError(err) {
+		return true
+	}
+	switch ErrorCode(err) {
+	case CodeUnavailable, CodeDeadlineExceeded, CodeResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// record request result of the node, count success rate of slow start,
+// open breaker by consecutive failed or slow request
+func (n *NodeDetail) report(conn *NodeConn, err error, cost time.Duration) {
+	var canceled = ErrorCode(err) == CodeCanceled // cancelled by caller, not result of the node
+	if !canceled {
+		conn.warmReport(breakerFailed(err))
+	}
+	var config = n.breaker
+	if config == nil {
+		return
+	}
+	var failed = breakerFailed(err) || (config.SlowCall > 0 && cost > config.SlowCall)
+	var b = &conn.brk
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.trial = false
+		if failed {
+			n.openBreaker(conn)
+		} else if !canceled {
+			b.state, b.fails, b.wait = BreakerClosed, 0, 0
+			conn.startWarmUp() // recovered node
+		}
+	case BreakerClosed:
+		if failed {
+			if b.fails++; b.fails >= config.Failures {
+				n.openBreaker(conn)
+			}
+		} else if !canceled {
+			b.fails = 0
+		}
+	}
+}
+
+// open breaker and ping the node later, interval was doubled every time, need lock
+func (n *NodeDetail) openBreaker(conn *NodeConn) {
+	var b = &conn.brk
+	if b.wait == 0 {
+		b.wait = n.breaker.OpenTimeout
+	} else if b.wait *= 2; b.wait > n.breaker.MaxOpenTimeout {
+		b.wait = n.breaker.MaxOpenTimeout
+	}
+	b.state, b.fails, b.trial = BreakerOpen, 0, false
+	time.AfterFunc(b.wait, func() { n.probeBreaker(conn) })
+}
+
+// ping the node of open breaker, half-open when success, reconnect when connection was closed,
+// stop when the connection was removed or node was shutdown
+func (n *NodeDetail) probeBreaker(conn *NodeConn) {
+	if !n.probing(conn) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), BreakerProbeTimeout)
+	defer cancel()
+	err := conn.ping(ctx)
+	if err != nil && !n.probing(conn) {
+		return
+	} else if err != nil && conn.isClosed() && n.RefreshConn(conn) == nil {
+		err = conn.ping(ctx)
+	}
+
+	conn.brk.mut.Lock()
+	defer conn.brk.mut.Unlock()
+	if err != nil {
+		n.openBreaker(conn)
+	} else {
+		conn.brk.state, conn.brk.trial = BreakerHalfOpen, false
+	}
+}
+
+// connection was still used by the node
+func (n *NodeDetail) probing(conn *NodeConn) bool {
+	return !n.isShutdown() && n.fmsg.GetNodeConn(conn.Uuid) == conn
+}
+
+// ping by PingNetwork and wait response
+func (n *NodeConn) ping(ctx context.Context) error {
+	switch n.types {
+	case ConnWithTCP, ConnWithUDP:
+		c, err := n.NewCall()
+		if err != nil {
+			return err
+		}
+		if n.types == ConnWithTCP {
+			err = n.WriteTCP(nil, c.num, comm.PingNetwork)
+		} else {
+			err = n.WriteUDP(nil, c.num, comm.PingNetwork)
+		}
+		if err != nil {
+			n.DelChan(c.num)
+			return err
+		}
+		_, err = n.WaitRspByte(ctx, c)
+		return err
+	case ConnWithHTTP:
+		return httpPing(n.Host, n.Hport, n.tlsc)
+	}
+	return errors.New("no tcp or udp connection")
+}
+
+func (n *NodeConn) isClosed() bool {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	return n.closed
+}
@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"micro/network"
+)
+
+type TstBreakerReq struct {
+	Sleep int `json:"sleep"` // milliseconds
+}
+type TstBreakerRsp struct{}
+type TstBreaker struct {
+	runs int32
+	down int32
+}
+
+func (s *TstBreaker) Compiler_JSON() {}
+
+func (s *TstBreaker) Call(req *TstBreakerReq, rsp *TstBreakerRsp) error {
+	atomic.AddInt32(&s.runs, 1)
+	time.Sleep(time.Duration(req.Sleep) * time.Millisecond)
+	if atomic.LoadInt32(&s.down) == 1 {
+		return NewStatus(CodeUnavailable, "unavailable")
+	}
+	return nil
+}
+
+func (s *TstBreaker) take() int32 { return atomic.SwapInt32(&s.runs, 0) }
+
+func waitBreaker(t *testing.T, conn *NodeConn, state int32) {
+	for i := 0; i < 100 && conn.BreakerState() != state; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if conn.BreakerState() != state {
+		t.Fatal("breaker state wrong: ", conn.BreakerState(), state)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	var svc = &TstBreaker{down: 1}
+	client, _ := testNodePair(t, svc)
+	client.breaker = newBreakerConfig(&network.BreakerConfig{Failures: 2,
+		SlowCall: time.Millisecond * 50, OpenTimeout: time.Millisecond * 50})
+	var conn = client.fmsg.GetNodeConn("S")
+
+	// consecutive failed request open breaker
+	for i := 0; i < 2; i++ {
+		client.CallAuto("TstBreaker.Call", &TstBreakerReq{}, &TstBreakerRsp{})
+	}
+	if conn.BreakerState() != BreakerOpen || svc.take() != 2 {
+		t.Fatal("breaker must be open by failed request")
+	}
+	if err := client.CallAuto("TstBreaker.Call", &TstBreakerReq{}, &TstBreakerRsp{}).Err(); ErrorCode(err) != CodeUnavailable || svc.take() != 0 {
+		t.Fatal("node of open breaker must be skipped: ", err)
+	}
+
+	// ping success, one request to test
+	atomic.StoreInt32(&svc.down, 0)
+	waitBreaker(t, conn, BreakerHalfOpen)
+	if err := client.CallAuto("TstBreaker.Call", &TstBreakerReq{}, &TstBreakerRsp{}).Err(); err != nil {
+		t.Fatal("half-open request wrong: ", err)
+	} else if conn.BreakerState() != BreakerClosed {
+		t.Fatal("breaker must be closed by success request")
+	}
+
+	// slow request
+	for i := 0; i < 2; i++ {
+		client.CallAuto("TstBreaker.Call", &TstBreakerReq{Sleep: 60}, &TstBreakerRsp{})
+	}
+	if conn.BreakerState() != BreakerOpen {
+		t.Fatal("breaker must be open by slow request")
+	}
+
+	// ping failed, wait double time
+	waitBreaker(t, conn, BreakerHalfOpen)
+	conn.Close()
+	client.CallAuto("TstBreaker.Call", &TstBreakerReq{}, &TstBreakerRsp{})
+	waitBreaker(t, conn, BreakerOpen)
+	time.Sleep(time.Millisecond * 150)
+	conn.brk.mut.Lock()
+	wait := conn.brk.wait
+	conn.brk.mut.Unlock()
+	if wait < time.Millisecond*200 {
+		t.Fatal("ping interval must be doubled: ", wait)
+	}
+}
+
+func TestBreakerStop(t *testing.T) {
+	var svc = &TstBreaker{}
+	client, _ := testNodePair(t, svc)
+	client.breaker = newBreakerConfig(&network.BreakerConfig{OpenTimeout: time.Millisecond * 20})
+	var conn = client.fmsg.GetNodeConn("S")
+	var open = func() {
+		conn.brk.mut.Lock()
+		conn.brk.wait = 0
+		client.openBreaker(conn)
+		conn.brk.mut.Unlock()
+	}
+
+	// connection was removed, ping was stopped
+	client.fmsg.DelConn("S")
+	open()
+	time.Sleep(time.Millisecond * 100)
+	if conn.BreakerState() != BreakerOpen {
+		t.Fatal("removed connection must not be probed")
+	}
+	client.fmsg.PutConn(conn)
+	open()
+	waitBreaker(t, conn, BreakerHalfOpen)
+
+	// node was shutdown
+	atomic.StoreInt32(&client.lis.shut, 1)
+	open()
+	time.Sleep(time.Millisecond * 100)
+	if conn.BreakerState() != BreakerOpen {
+		t.Fatal("connection must not be probed after shutdown")
+	}
+}
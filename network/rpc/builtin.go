@@ -1,7 +1,9 @@
 package rpc
 
 import (
+	"encoding/binary"
 	"errors"
+	"log"
 	"strings"
 
 	"micro/network/comm"
@@ -16,11 +18,29 @@ func (n *NodeDetail) builtin(fid int, nc *NodeConn, bts []byte) ([]byte, error)
 	case comm.PingNetwork:
 		return []byte("PONG"), nil
 
+	case comm.TcpFrameMode:
+		if nc.types != ConnWithTCP || n.legacy || len(bts) != 1 || FrameType(bts[0]) != FrameLength {
+			return []byte{byte(FrameFixed)}, nil
+		}
+		nc.setFrame(FrameLength)
+		return []byte{byte(FrameLength)}, nil
+
+	case comm.CancelCall:
+		// udp server connection was shared by all caller, event id is not unique
+		if num, size := binary.Uvarint(bts); size > 0 && nc.types == ConnWithTCP {
+			nc.cancelCall(int(num))
+		}
+		return nil, nil
+
 	case comm.DialRegister:
 		var rsp = &pb.NodeInfo{}
 		if err := proto.Unmarshal(bts, rsp); err != nil {
 			return nil, err
+		} else if err = n.sign.verify(rsp); err != nil {
+			log.Println("DialRegister: ", err)
+			return nil, err
 		} else {
+			rsp.Sign = nil
 			if rsp.Host == "" || rsp.Host == "127.0.0.1" || rsp.Host == "0.0.0.0" {
 				if nc.types == ConnWithTCP {
 					rsp.Host = strings.Split(nc.tconn.RemoteAddr().String(), ":")[0]
@@ -28,6 +48,9 @@ func (n *NodeDetail) builtin(fid int, nc *NodeConn, bts []byte) ([]byte, error)
 					rsp.Host = strings.Split(nc.uconn.RemoteAddr().String(), ":")[0]
 				}
 			}
+			if nc.types == ConnWithTCP {
+				nc.setPeer(proto.Clone(rsp).(*pb.NodeInfo), n.sign.enabled())
+			}
 			n.NodeBaseToConn(rsp)
 			return nil, nil
 		}
@@ -42,10 +65,21 @@ func (n *NodeDetail) builtin(fid int, nc *NodeConn, bts []byte) ([]byte, error)
 		}
 		return nil, nil
 
+	case comm.UpAuthPolicy:
+		var req = &pb.AuthPolicy{}
+		if err := proto.Unmarshal(bts, req); err != nil {
+			return nil, err
+		} else if nc.identity() != comm.WatchNodeName {
+			return nil, NewStatus(CodePermissionDenied, "authorization policy only can be set by verified watcher")
+		}
+		return nil, n.setAuthPolicy(req, true)
+
 	case comm.UpNodeConnMsg:
 		var req = &pb.GetApiConnRsp{}
 		if err := proto.Unmarshal(bts, req); err != nil {
 			return nil, err
+		} else if !n.fromWatcher(nc) {
+			return nil, NewStatus(CodePermissionDenied, "api node list only can be pushed by watcher")
 		}
 		n.UpServNodeConn(req)
 		return nil, nil
@@ -53,25 +87,56 @@ func (n *NodeDetail) builtin(fid int, nc *NodeConn, bts []byte) ([]byte, error)
 	case comm.UpServerState:
 
 	case comm.UpWatcherList:
-		// var req = &comm.UpWatcherListReq{}
+		var req = &pb.WatcherList{}
+		if err := proto.Unmarshal(bts, req); err != nil {
+			return nil, err
+		} else if !n.fromWatcher(nc) {
+			return nil, NewStatus(CodePermissionDenied, "watcher list only can be pushed by watcher")
+		}
+		n.UpWatchList(req)
+		return nil, nil
 
 	}
 	return nil, errors.New("parse build-in request byte wrong")
 }
 
+// push of watcher only accepted from the connection registered by verified watcher,
+// registered name or udp is used when this node cannot verify caller (without sign keys and tls)
+func (n *NodeDetail) fromWatcher(nc *NodeConn) bool {
+	if nc.identity() == comm.WatchNodeName {
+		return true
+	} else if n.sign.enabled() || n.tlsc != nil {
+		return false
+	}
+	peer := nc.registered()
+	return nc.types == ConnWithUDP || (peer != nil && peer.Name == comm.WatchNodeName)
+}
+
+// update server api node connection list, push by watcher
 func (n *NodeDetail) UpServNodeConn(data *pb.GetApiConnRsp) {
+	if data.GetFunc().GetFuncID() == 0 {
+		return
+	} else if data.Deleted {
+		n.fmsg.DelMsg(data.Func.FuncID)
+		return
+	}
+	var ids = make([]string, 0, len(data.List))
 	var result = make([]*NodeConn, 0, len(data.List))
 	for _, row := range data.List {
 		if conn := n.fmsg.GetNodeConn(row.Uuid); conn != nil {
 			conn.Host, conn.Hport = row.Host, row.Hport
 			conn.Tport, conn.Uport = row.Tport, row.Uport
+			conn.Weight, conn.WarmUp = row.Weight, row.WarmUp
+			conn.Compress = row.Compress
+			ids = append(ids, row.Uuid)
 			result = append(result, conn)
 		} else if conn, err := n.NodeBaseToConn(row); err == nil {
 			n.fmsg.PutConn(conn)
+			ids = append(ids, row.Uuid)
 			result = append(result, conn)
 		}
 	}
-	if len(result) > 0 {
-		// n.StoreConnByName(data.Func.ServName, result)
-	}
+	upConnStatus(result, data.Stat)
+	n.fmsg.PutMsg(data.Func)
+	n.fmsg.UpFuncNode(data.Func.FuncID, ids)
 }
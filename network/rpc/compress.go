@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+const (
+	CompressGzip       = "gzip"
+	DefaultCompressMin = 1024 // body smaller than it was not compressed
+)
+
+// Compressor : compress request and response body, name was sent before compressed body,
+// decompressed body was read from the reader and limited by MaxFrameSize
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(r io.Reader) (io.Reader, error)
+}
+
+var compressors = struct {
+	mut  sync.RWMutex
+	list map[string]Compressor
+}{list: map[string]Compressor{CompressGzip: gzipCompressor{}}}
+
+// RegisterCompressor : add compressor to decompress body and choose by NodeConfig.Compress,
+// need register before NewClient, node advertise the names to callers
+func RegisterCompressor(c Compressor) error {
+	if c == nil || c.Name() == "" || len(c.Name()) > 255 {
+		return errors.New("compressor name was wrong")
+	}
+	compressors.mut.Lock()
+	defer compressors.mut.Unlock()
+	compressors.list[c.Name()] = c
+	return nil
+}
+
+func getCompressor(name string) Compressor {
+	compressors.mut.RLock()
+	defer compressors.mut.RUnlock()
+	return compressors.list[name]
+}
+
+func compressorNames() []string {
+	compressors.mut.RLock()
+	defer compressors.mut.RUnlock()
+	var result = make([]string, 0, len(compressors.list))
+	for name := range compressors.list {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// compressor used to send body of the node
+type compressConfig struct {
+	name string            // default compressor name, empty not compress
+	min  int               // body smaller than it was not compressed
+	apis map[string]string // compressor name of api, cover name
+}
+
+func newCompressConfig(name string, min int, apis map[string]string) (*compressConfig, error) {
+	var result = &compressConfig{name: name, min: min, apis: apis}
+	if result.min <= 0 {
+		result.min = DefaultCompressMin
+	}
+	if name != "" && getCompressor(name) == nil {
+		return nil, errors.New("not found compressor: " + name)
+	}
+	for _, row := range apis {
+		if row != "" && getCompressor(row) == nil {
+			return nil, errors.New("not found compressor: " + row)
+		}
+	}
+	return result, nil
+}
+
+// compress body of api when remote node can decompress it, return true when compressed:
+// byte(len(name)) | name | compressed body
+func (n *NodeConn) compress(api string, data []byte) ([]byte, bool) {
+	var config = n.zipc
+	if config == nil || len(data) < config.min {
+		return data, false
+	}
+	var name = config.name
+	if v, ok := config.apis[api]; ok {
+		name = v
+	}
+	if name == "" || !n.canDecompress(name) {
+		return data, false
+	}
+	c := getCompressor(name)
+	if c == nil {
+		return data, false
+	}
+	bts, err := c.Compress(data)
+	if err != nil || len(bts)+len(name)+1 >= len(data) {
+		return data, false
+	}
+	var result = make([]byte, 0, len(name)+1+len(bts))
+	result = append(append(result, byte(len(name))), name...)
+	return append(result, bts...), true
+}
+
+// compressor names of remote node, registered node of the accepted connection first
+func (n *NodeConn) canDecompress(name string) bool {
+	n.mut.RLock()
+	var names = n.Compress
+	if n.peer != nil {
+		names = n.peer.Compress
+	}
+	n.mut.RUnlock()
+	for _, row := range names {
+		if row == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decompress body by the compressor name before it, limit decompressed size as frame size
+func decompress(data []byte) ([]byte, error) {
+	if len(data) < 1 || len(data) < int(data[0])+1 {
+		return nil, errors.New("compressed body was wrong")
+	}
+	var name = string(data[1 : 1+int(data[0])])
+	c := getCompressor(name)
+	if c == nil {
+		return nil, errors.New("not found compressor: " + name)
+	}
+	r, err := c.Decompress(bytes.NewReader(data[1+int(data[0]):]))
+	if err != nil {
+		return nil, err
+	}
+	bts, err := ioutil.ReadAll(io.LimitReader(r, MaxFrameSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(bts) > MaxFrameSize {
+		return nil, errors.New("decompressed body over limit")
+	}
+	return bts, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return CompressGzip }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	} else if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"micro/network/pb"
+)
+
+type TstZipMsg struct {
+	Text string `json:"text"`
+}
+type TstZip struct{}
+
+func (s *TstZip) Compiler_JSON() {}
+
+func (s *TstZip) Echo(req *TstZipMsg, rsp *TstZipMsg) error {
+	rsp.Text = req.Text
+	return nil
+}
+
+// gzip compressor with counter
+type tstCounter struct {
+	gzipCompressor
+	zip, unzip int32
+}
+
+func (c *tstCounter) Name() string { return "count" }
+
+func (c *tstCounter) Compress(data []byte) ([]byte, error) {
+	atomic.AddInt32(&c.zip, 1)
+	return c.gzipCompressor.Compress(data)
+}
+
+func (c *tstCounter) Decompress(r io.Reader) (io.Reader, error) {
+	atomic.AddInt32(&c.unzip, 1)
+	return c.gzipCompressor.Decompress(r)
+}
+
+// compressor decompress endless body
+type tstBomb struct{ gzipCompressor }
+
+func (tstBomb) Name() string { return "bomb" }
+
+func (tstBomb) Decompress(r io.Reader) (io.Reader, error) { return zeroReader{}, nil }
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) { return len(p), nil }
+
+func TestCompressBody(t *testing.T) {
+	var data = bytes.Repeat([]byte("compress body "), 200)
+	var nc = &NodeConn{zipc: &compressConfig{name: CompressGzip, min: DefaultCompressMin,
+		apis: map[string]string{"Tst.Raw": ""}}}
+	if _, zip := nc.compress("Tst.Echo", data); zip {
+		t.Fatal("remote node without compressor must not be compressed")
+	}
+	nc.Compress = []string{CompressGzip}
+	bts, zip := nc.compress("Tst.Echo", data)
+	if !zip || len(bts) >= len(data) {
+		t.Fatal("body must be compressed")
+	} else if result, err := decompress(bts); err != nil || !bytes.Equal(result, data) {
+		t.Fatal("decompress wrong: ", err)
+	}
+	if _, zip = nc.compress("Tst.Echo", data[:100]); zip {
+		t.Fatal("small body must not be compressed")
+	} else if _, zip = nc.compress("Tst.Raw", data); zip {
+		t.Fatal("api without compressor must not be compressed")
+	} else if _, err := decompress([]byte{4, 'n', 'o', 'n', 'e', 0}); err == nil {
+		t.Fatal("unknown compressor must be wrong")
+	} else if _, err = newCompressConfig("none", 0, nil); err == nil {
+		t.Fatal("unknown compressor config must be wrong")
+	}
+
+	// decompressed size of every compressor was limited
+	if err := RegisterCompressor(tstBomb{}); err != nil {
+		t.Fatal(err)
+	} else if _, err = decompress([]byte{4, 'b', 'o', 'm', 'b', 0}); err == nil {
+		t.Fatal("decompressed body over limit must be wrong")
+	}
+}
+
+func TestCompressCall(t *testing.T) {
+	var counter = &tstCounter{}
+	if err := RegisterCompressor(counter); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	for i := 0; i < 10000; i++ {
+		text.WriteString(strconv.Itoa(i) + ",")
+	}
+
+	for _, legacy := range []bool{false, true} {
+		var server = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}, funcs: make(map[string]*Server),
+			hlist: make(map[string]func(http.ResponseWriter, *http.Request)), legacy: legacy}
+		if err := server.Register(&TstZip{}); err != nil {
+			t.Fatal(err)
+		}
+		server.Uuid = "S"
+		server.zipc, _ = newCompressConfig("count", 0, nil)
+		var client = testNodeDial(t, server)
+		var nc = client.fmsg.GetNodeConn("S")
+		nc.zipc, nc.Compress = server.zipc, compressorNames()
+
+		var call = func(req string) (int32, int32) {
+			zip, unzip := atomic.LoadInt32(&counter.zip), atomic.LoadInt32(&counter.unzip)
+			var rsp = &TstZipMsg{}
+			if err := client.CallAuto("TstZip.Echo", &TstZipMsg{Text: req}, rsp).Err(); err != nil {
+				t.Fatal(err)
+			} else if rsp.Text != req {
+				t.Fatal("response body wrong")
+			}
+			return atomic.LoadInt32(&counter.zip) - zip, atomic.LoadInt32(&counter.unzip) - unzip
+		}
+		// caller of the accepted connection was not registered
+		if zip, unzip := call(text.String()); zip != 1 || unzip != 1 {
+			t.Fatal("only request must be compressed: ", legacy, zip, unzip)
+		}
+		server.lis.conns.Range(func(key, value interface{}) bool {
+			key.(*NodeConn).setPeer(&pb.NodeInfo{Uuid: "C", Compress: compressorNames()}, false)
+			return true
+		})
+		if zip, unzip := call(text.String()); zip != 2 || unzip != 2 {
+			t.Fatal("request and response must be compressed: ", legacy, zip, unzip)
+		} else if zip, unzip = call("small"); zip != 0 || unzip != 0 {
+			t.Fatal("small body must not be compressed: ", legacy, zip, unzip)
+		}
+	}
+}
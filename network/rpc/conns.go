@@ -1,13 +1,20 @@
 package rpc
 
 import (
+	"bufio"
+	"context"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"micro/network/comm"
 	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
 )
 
 type ConnType int
@@ -18,26 +25,67 @@ var (
 	ConnWithHTTP ConnType = 3
 )
 
+const (
+	EventNumStart = 100       // 小于此编号用于不等待返回的内置请求(ping, register)
+	EventNumFixed = 1<<24 - 1 // 定长帧事件编号为三个字节
+	EventNumFrame = 1<<32 - 1 // 长度前缀帧事件编号
+)
+
 type NodeConn struct {
 	pb.NodeInfo
 
-	tconn *net.TCPConn
+	tconn net.Conn // tcp or tls connection
 	uconn *net.UDPConn
 	types ConnType
+	frame int32  // tcp frame format, FrameType
+	seq   uint32 // request event id of the connection
+	meta  bool   // node can parse metadata of fixed frame
 	wrong bool
 	stamp int64
+	// system status report by watcher
+	stat *pb.SystemStatus
+	// circuit breaker of the node
+	brk breaker
+	// slow start of the node
+	warm warmUp
 
 	// safe lock rc to read and wirte
 	mut sync.RWMutex
+	// response reader of the connection was stopped
+	closed bool
 	// struct function (api name), send boolean
 	fc map[string]bool
 	// recv response
 	rc map[int]*RecvChan
 	// recv response body
 	list map[int]*ReadLink
+	// server stream of the connection
+	strm map[int]*stream
+	// cancel running server api by event id
+	calls map[int]context.CancelFunc
+	// remote node registered by the accepted connection
+	peer *pb.NodeInfo
+	// register message of peer was verified by signature
+	signed bool
+	// caller certificate verified by mutual tls of the accepted connection
+	cert *x509.Certificate
+	// tls config to connect the node, nil when not use
+	tlsc *tlsConfig
+	// compressor config to send body
+	zipc *compressConfig
+	// reliable udp of the connection, nil when not use
+	rudp *rudp
+	// last time to drop body not all received
+	sweep int64
+	// cancelled when the accepted connection closed
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type RecvChan struct {
+	num   int      // request event id
+	strm  *stream  // client stream
+	meta  Metadata // response metadata
 	body  chan []byte
 	err   chan error
 	stamp int64
@@ -49,18 +97,210 @@ type ReadLink struct {
 	uuid   int      // event id
 	body   [][]byte // request body
 	recv   uint32   // recv count
+	stamp  int64    // first body received time
+}
+
+func (n *NodeConn) Frame() FrameType { return FrameType(atomic.LoadInt32(&n.frame)) }
+
+func (n *NodeConn) setFrame(f FrameType) { atomic.StoreInt32(&n.frame, int32(f)) }
+
+func (n *NodeConn) setPeer(node *pb.NodeInfo, signed bool) {
+	n.mut.Lock()
+	n.peer, n.signed = node, signed
+	n.mut.Unlock()
+}
+
+// node registered by the accepted connection, nil when not registered
+func (n *NodeConn) registered() *pb.NodeInfo {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	return n.peer
+}
+
+// verified caller of the accepted connection, certificate common name first,
+// else name of the node registered with signature, "" when not verified
+func (n *NodeConn) identity() string {
+	if name := certName(n.cert); name != "" {
+		return name
+	}
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	if n.peer != nil && n.signed {
+		return n.peer.Name
+	}
+	return ""
+}
+
+// remote node of the accepted connection, only host when not registered
+func (n *NodeConn) peerInfo() *pb.NodeInfo {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+	if n.peer != nil {
+		return n.peer
+	} else if n.types == ConnWithTCP && n.tconn != nil {
+		host, _, _ := net.SplitHostPort(n.tconn.RemoteAddr().String())
+		return &pb.NodeInfo{Host: host}
+	}
+	return &pb.NodeInfo{}
+}
+
+// server api context of the accepted connection
+func (n *NodeConn) context() context.Context {
+	if n.ctx == nil {
+		return context.Background()
+	}
+	return n.ctx
+}
+
+// register running server api by event id that caller can cancel it,
+// built in request without waiting is not registered
+func (n *NodeConn) startCall(num int) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(n.context())
+	if num < EventNumStart {
+		return ctx, cancel
+	}
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	if n.calls == nil {
+		n.calls = make(map[int]context.CancelFunc)
+	} else if _, ok := n.calls[num]; ok {
+		return ctx, cancel
+	}
+	n.calls[num] = cancel
+	return ctx, func() {
+		n.mut.Lock()
+		delete(n.calls, num)
+		n.mut.Unlock()
+		cancel()
+	}
+}
+
+// cancel running server api and drop request body not all received
+func (n *NodeConn) cancelCall(num int) {
+	n.mut.Lock()
+	cancel, ok := n.calls[num]
+	delete(n.list, num)
+	n.mut.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// notify server to cancel the waiting request, only tcp node can parse it
+func (n *NodeConn) sendCancel(num int) {
+	if n.types != ConnWithTCP || (n.Frame() != FrameLength && !n.meta) {
+		return
+	}
+	n.WriteTCP(appendUvarint(nil, uint64(num)), 1, comm.CancelCall)
+}
+
+func (n *NodeConn) network() string {
+	switch n.types {
+	case ConnWithTCP:
+		return "TCP"
+	case ConnWithUDP:
+		return "UDP"
+	case ConnWithHTTP:
+		return "HTTP"
+	}
+	return "Local"
+}
+
+// send tcp request by the negotiated frame format
+func (n *NodeConn) WriteTCP(bts []byte, num, fid int) error {
+	return n.writeTCP(bts, num, fid, nil, false)
+}
+
+// send request with metadata, drop metadata when node cannot parse it,
+// zip is true when body was compressed
+func (n *NodeConn) writeTCP(bts []byte, num, fid int, md Metadata, zip bool) error {
+	if n.Frame() == FrameLength {
+		var f = &Frame{Num: num, Func: fid, Data: bts, Meta: md}
+		if zip {
+			f.Flags |= FrameFlagZip
+		}
+		_, err := n.tconn.Write(f.Encode())
+		return err
+	} else if !n.meta {
+		md = nil
+	}
+	for _, row := range tcpsplit.makeReqMeta(bts, num, fid, md) {
+		if zip {
+			row[5] |= BodyFlagZip
+		}
+		if _, err := n.tconn.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *NodeConn) pingTCP() error {
+	if n.Frame() == FrameLength {
+		return n.WriteTCP(nil, 1, comm.PingNetwork)
+	}
+	_, err := n.tconn.Write(tcpsplit.PingBytes)
+	return err
+}
+
+// send udp request with fixed-size frame
+func (n *NodeConn) WriteUDP(bts []byte, num, fid int) error {
+	return n.writeUDP(bts, num, fid, nil, false)
+}
+
+func (n *NodeConn) writeUDP(bts []byte, num, fid int, md Metadata, zip bool) error {
+	if !n.meta {
+		md = nil
+	}
+	var rows = udpsplit.makeReqMeta(bts, num, fid, md)
+	for _, row := range rows {
+		if zip {
+			row[5] |= BodyFlagZip
+		}
+	}
+	if n.rudp != nil {
+		return n.rudp.send(nil, rows)
+	}
+	for _, row := range rows {
+		if _, err := n.uconn.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next request event id of the connection, skip the built in id
+func (n *NodeConn) nextNum() int {
+	var max uint64 = EventNumFixed
+	if n.types == ConnWithTCP && n.Frame() == FrameLength {
+		max = EventNumFrame
+	}
+	seq := uint64(atomic.AddUint32(&n.seq, 1))
+	return int(EventNumStart + seq%(max-EventNumStart+1))
 }
 
-func (n *NodeConn) NewChan(num int) *RecvChan {
+// make new request event id and wait response by it,
+// must register before send request that response may be faster
+func (n *NodeConn) NewCall() (*RecvChan, error) {
+	return n.NewChan(n.nextNum())
+}
+
+func (n *NodeConn) NewChan(num int) (*RecvChan, error) {
 	var tmp = &RecvChan{
+		num:   num,
 		body:  make(chan []byte, 1),
 		err:   make(chan error, 1),
 		stamp: time.Now().UnixMilli(),
 	}
 	n.mut.Lock()
+	defer n.mut.Unlock()
+	if n.closed {
+		return nil, errors.New("connection was closed")
+	} else if _, ok := n.rc[num]; ok {
+		return nil, fmt.Errorf("request event id %d was in use", num)
+	}
 	n.rc[num] = tmp
-	n.mut.Unlock()
-	return tmp
+	return tmp, nil
 }
 
 func (n *NodeConn) DelChan(num int) {
@@ -81,25 +321,72 @@ func (n *NodeConn) Close() {
 		n.uconn.Close()
 	}
 	n.fc = make(map[string]bool)
-	n.rc = make(map[int]*RecvChan)
+	n.failChan(errors.New("connection was closed"))
+}
+
+// notify all waiting request, need lock
+func (n *NodeConn) failChan(err error) {
+	for num, c := range n.rc {
+		select {
+		case c.err <- err:
+		default:
+		}
+		if c.strm != nil {
+			c.strm.release()
+		}
+		delete(n.rc, num)
+	}
+}
+
+// response reader stopped, request by the connection cannot wait response,
+// ignore the old reader when connection was refreshed
+func (n *NodeConn) closeRecv(conn net.Conn) {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	if (n.types == ConnWithTCP && n.tconn != nil && net.Conn(n.tconn) == conn) ||
+		(n.types == ConnWithUDP && n.uconn != nil && net.Conn(n.uconn) == conn) {
+		n.closed = true
+		n.failChan(errors.New("connection was closed"))
+	}
+}
+
+// make connection to node without cache, node uuid can be null
+func (n *NodeDetail) DialNode(node *pb.NodeInfo) (*NodeConn, error) {
+	var conn = &NodeConn{
+		list: make(map[int]*ReadLink),
+		fc:   make(map[string]bool),
+		rc:   make(map[int]*RecvChan),
+	}
+	proto.Merge(&conn.NodeInfo, node)
+	return conn, n.RefreshConn(conn)
 }
 
 func (n *NodeDetail) RefreshConn(nc *NodeConn) error {
 	var err error
+	nc.mut.Lock()
+	nc.closed = false
+	nc.mut.Unlock()
+	nc.tlsc, nc.zipc = n.tlsc, n.zipc
+	// metadata only sent to node registered it can parse, or negotiated by tcp
+	nc.meta = nc.Metadata
 	if nc.Tport != 0 {
-		if nc.tconn, err = n.DialTCP(&net.TCPAddr{
+		var frame FrameType
+		var meta bool
+		if nc.tconn, frame, meta, err = n.dialTCP(&net.TCPAddr{
 			IP: net.ParseIP(nc.Host), Port: int(nc.Tport)}); err != nil {
 			return err
 		}
+		nc.meta = nc.meta || meta
+		nc.setFrame(frame)
 		if err = nc.TestTcpConn(); err != nil {
 			nc.tconn.Close()
 			return err
 		}
-		nc.tconn.SetKeepAlive(true)
 		nc.types = ConnWithTCP
 		go nc.ReadRespTCP()
 	}
-	if nc.Uport != 0 {
+	// udp was not encrypted, skip it when use tls
+	if nc.Uport != 0 && n.tlsc == nil {
 		if nc.uconn, err = n.DialUDP(&net.UDPAddr{
 			IP: net.ParseIP(nc.Host), Port: int(nc.Uport)}); err != nil {
 			return err
@@ -108,12 +395,16 @@ func (n *NodeDetail) RefreshConn(nc *NodeConn) error {
 			nc.uconn.Close()
 			return err
 		}
+		nc.rudp = nil
+		if n.reliable && nc.UdpReliable {
+			nc.rudp = newRudp(nc.uconn, nc.recvRudp)
+		}
 		nc.types = ConnWithUDP
 		go nc.ReadRespUDP()
 		return nil
 	}
 	if nc.Hport != 0 {
-		if HttpPingTest(nc.Host, nc.Hport) == nil {
+		if httpPing(nc.Host, nc.Hport, n.tlsc) == nil {
 			nc.types = ConnWithHTTP
 		}
 	}
@@ -139,7 +430,7 @@ func (n *NodeDetail) TimerCheck(nc *NodeConn) {
 				n.RefreshConn(nc)
 			}
 		case ConnWithHTTP:
-			if HttpPingTest(n.Host, nc.Hport) != nil {
+			if httpPing(nc.Host, nc.Hport, nc.tlsc) != nil {
 				n.RefreshConn(nc)
 			}
 		default:
@@ -166,46 +457,77 @@ func (n *NodeDetail) TimerCheck(nc *NodeConn) {
 // parse network request body
 // return request event id, function_id, request body, error
 func (n *NodeConn) ParseResp(nt NetworkBuffer, cb *ConnBody) (int, int, []byte, error) {
-	body, err := nt.parse(cb)
-	if err != nil || body == nil || body.Sort > body.Buck {
+	body, _, err := n.parseBody(nt, cb)
+	if body == nil {
 		return 0, 0, nil, err
 	}
+	return body.Uuid, body.Func, body.Data, err
+}
+
+// parse network body and split metadata, metadata is nil when body without it,
+// body is nil when not all received, failed response return *Status
+func (n *NodeConn) parseBody(nt NetworkBuffer, cb *ConnBody) (*JoinBodyData, Metadata, error) {
+	body, err := n.joinBody(nt, cb)
+	if err != nil || body == nil {
+		return body, nil, err
+	}
+	var md Metadata
+	if body.Meta {
+		if md, body.Data, err = splitMeta(body.Data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if body.Zip {
+		if body.Data, err = decompress(body.Data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if body.Code {
+		return body, md, statusError(body.Data)
+	}
+	return body, md, nil
+}
+
+// join split body, failed response return body with error message
+func (n *NodeConn) joinBody(nt NetworkBuffer, cb *ConnBody) (*JoinBodyData, error) {
+	body, err := nt.parse(cb)
+	if body == nil || body.Sort > body.Buck {
+		return nil, err
+	} else if err != nil {
+		return body, err
+	}
 	if body.Buck == 1 {
-		return body.Uuid, body.Func, body.Data, err
+		return body, nil
 
 	} else if body.Buck > 1 {
-		n.mut.RLock()
+		// bodies of one request were joined by concurrent goroutines
+		n.mut.Lock()
+		defer n.mut.Unlock()
 		tmp, ok := n.list[body.Uuid]
-		n.mut.RUnlock()
 		if ok {
 			if body.Buck != tmp.buck || body.Func != tmp.funcid {
-				n.mut.Lock()
 				delete(n.list, body.Uuid)
-				n.mut.Unlock()
-				return 0, 0, nil, errors.New("recv wrong")
+				return nil, errors.New("recv wrong")
 			}
 			if body.Sort-1 >= len(tmp.body) {
-				n.mut.Lock()
 				delete(n.list, body.Uuid)
-				n.mut.Unlock()
-				return 0, 0, nil, errors.New("recv uuid body buck wrong")
+				return nil, errors.New("recv uuid body buck wrong")
 			}
 			if len(tmp.body[body.Sort-1]) == 0 {
-				atomic.AddUint32(&tmp.recv, 1)
+				tmp.recv++
 				tmp.body[body.Sort-1] = body.Data
 
 				if int(tmp.recv) >= tmp.buck {
 					var result []byte
 					for i := range tmp.body {
 						if len(tmp.body[i]) == 0 {
-							return 0, 0, nil, nil
+							return nil, nil
 						}
 						result = append(result, tmp.body[i]...)
 					}
-					n.mut.Lock()
 					delete(n.list, body.Uuid)
-					n.mut.Unlock()
-					return body.Uuid, body.Func, result, nil
+					body.Data = result
+					return body, nil
 				}
 			}
 		} else if !ok {
@@ -215,27 +537,69 @@ func (n *NodeConn) ParseResp(nt NetworkBuffer, cb *ConnBody) (int, int, []byte,
 				uuid:   body.Uuid,
 				body:   make([][]byte, body.Buck),
 				recv:   1,
+				stamp:  time.Now().UnixMilli(),
 			}
 			if body.Sort <= 0 || body.Sort-1 >= len(tmp.body) {
-				n.mut.Lock()
 				delete(n.list, body.Uuid)
-				n.mut.Unlock()
-				return 0, 0, nil, errors.New("recv uuid body buck wrong")
+				return nil, errors.New("recv uuid body buck wrong")
 			}
 			tmp.body[body.Sort-1] = body.Data
-			n.mut.Lock()
+			n.clearLink(tmp.stamp)
 			n.list[body.Uuid] = tmp
-			n.mut.Unlock()
 		}
 	}
-	return 0, 0, nil, nil
+	return nil, nil
+}
+
+// drop body not all received in time, lost udp packet left it forever, need lock
+func (n *NodeConn) clearLink(now int64) {
+	var timeout = ReassemblyTimeout.Milliseconds()
+	if now-n.sweep < timeout {
+		return
+	}
+	n.sweep = now
+	for num, v := range n.list {
+		if now-v.stamp > timeout {
+			delete(n.list, num)
+		}
+	}
+}
+
+// 分发返回数据到等待的请求
+func (n *NodeConn) recvResp(num int, bts []byte, md Metadata, err error) {
+	n.mut.Lock()
+	c, ok := n.rc[num]
+	if ok && md != nil {
+		c.meta = md
+	}
+	n.mut.Unlock()
+	if !ok {
+		return
+	}
+	// drop repeated response, the reader cannot be blocked
+	if err != nil {
+		select {
+		case c.err <- err:
+		default:
+		}
+	} else {
+		select {
+		case c.body <- bts:
+		default:
+		}
+	}
 }
 
 // 用于接收处理自己请求出去的返回数据
 func (n *NodeConn) ReadRespTCP() {
+	defer n.closeRecv(n.tconn)
+	if n.Frame() == FrameLength {
+		n.readFrameTCP()
+		return
+	}
+
 	var num int
 	var err error
-	var bts []byte
 
 	for {
 		buff := NewTcpBuffer()
@@ -254,56 +618,71 @@ func (n *NodeConn) ReadRespTCP() {
 			}
 		}
 
-		num, _, bts, err = n.ParseResp(tcpsplit, buff)
-		if num > 0 {
-			n.mut.Lock()
-			c, ok := n.rc[num]
-			n.mut.Unlock()
-			if ok {
-				if err != nil {
-					c.err <- err
-				} else {
-					c.body <- bts
-				}
-			}
+		if body, md, err := n.parseBody(tcpsplit, buff); body != nil && body.Uuid > 0 {
+			n.recvResp(body.Uuid, body.Data, md, err)
 		}
 		PutTcpBuffer(buff)
 	}
 }
 
+// 接收长度前缀帧的返回数据
+func (n *NodeConn) readFrameTCP() {
+	var rd = bufio.NewReaderSize(n.tconn, FrameReadSize)
+	for {
+		f, err := ReadFrame(rd)
+		if err != nil {
+			return
+		}
+		if f.Flags&FrameFlagStream != 0 {
+			n.recvStream(f)
+		} else if f.Num > 0 && f.Flags&FrameFlagResp != 0 {
+			n.recvResp(f.Num, f.Data, f.Meta, f.Err())
+		}
+	}
+}
+
 // 用于接收处理自己请求出去的返回数据
 func (n *NodeConn) ReadRespUDP() {
+	defer n.closeRecv(n.uconn)
 	var num int
 	var err error
-	var bts []byte
+	var addr *net.UDPAddr
+	// reliable udp of the connection, stopped with the reader
+	var r = n.rudp
+	if r != nil {
+		defer r.close()
+	}
 
 	for {
 		buff := NewUdpBuffer()
-		num, _, err = n.uconn.ReadFromUDP(buff.Data)
+		num, addr, err = n.uconn.ReadFromUDP(buff.Data[:cap(buff.Data)])
 		if err != nil {
 			PutUdpBuffer(buff)
 			return
+		} else if r != nil && isRudpPacket(buff.Data[:num]) {
+			r.input(addr, buff.Data[:num])
+			PutUdpBuffer(buff)
+			continue
 		} else if num != udpsplit.TotalSize {
 			PutUdpBuffer(buff)
 			continue
 		}
-		num, _, bts, err = n.ParseResp(udpsplit, buff)
-		if num > 0 {
-			n.mut.Lock()
-			c, ok := n.rc[num]
-			n.mut.Unlock()
-			if ok {
-				if err != nil {
-					c.err <- err
-				} else {
-					c.body <- bts
-				}
-			}
+		if body, md, err := n.parseBody(udpsplit, buff); body != nil && body.Uuid > 0 {
+			n.recvResp(body.Uuid, body.Data, md, err)
 		}
 		PutUdpBuffer(buff)
 	}
 }
 
+// response received by reliable udp
+func (n *NodeConn) recvRudp(addr *net.UDPAddr, rows [][]byte) {
+	for _, row := range rows {
+		if body, md, err := n.parseBody(udpsplit, &ConnBody{Data: row}); body != nil && body.Uuid > 0 {
+			n.recvResp(body.Uuid, body.Data, md, err)
+		}
+	}
+}
+
 func (n *NodeConn) TestConn() error {
 	if n == nil {
 		return errors.New("connection was null")
@@ -314,7 +693,7 @@ func (n *NodeConn) TestConn() error {
 	case ConnWithUDP:
 		return n.TestUdpConn()
 	case ConnWithHTTP:
-		return HttpPingTest(n.Host, n.Hport)
+		return httpPing(n.Host, n.Hport, n.tlsc)
 	}
 	return errors.New("no tcp or udp connection")
 }
@@ -323,15 +702,16 @@ func (n *NodeConn) TestConn() error {
 func (n *NodeConn) TestTcpConn() error {
 	if n.Tport != 0 {
 		if n.tconn != nil {
-			if _, err := n.tconn.Write(tcpsplit.PingBytes); err == nil {
+			if n.pingTCP() == nil {
 				return nil
 			}
 		}
 		var err error
-		if n.tconn, err = net.DialTCP("tcp", nil, &net.TCPAddr{
-			IP: net.ParseIP(n.Host), Port: int(n.Tport)}); err != nil {
+		if n.tconn, err = dialConn(&net.TCPAddr{
+			IP: net.ParseIP(n.Host), Port: int(n.Tport)}, n.tlsc); err != nil {
 			return err
 		} else {
+			n.setFrame(FrameFixed)
 			_, err = n.tconn.Write(tcpsplit.PingBytes)
 			return err
 		}
@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"testing"
+)
+
+func TestNewCall(t *testing.T) {
+	var conn = &NodeConn{types: ConnWithTCP, rc: make(map[int]*RecvChan)}
+	c, err := conn.NewCall()
+	if err != nil || c.num < EventNumStart {
+		t.Fatal("new call wrong: ", err)
+	}
+	if _, err = conn.NewChan(c.num); err == nil {
+		t.Fatal("event id in use should be wrong")
+	}
+	conn.DelChan(c.num)
+	if _, err = conn.NewChan(c.num); err != nil {
+		t.Fatal(err)
+	}
+
+	// fixed-size frame wrap in three bytes, skip built in id
+	conn.seq = EventNumFixed - EventNumStart - 1
+	if num := conn.nextNum(); num != EventNumFixed {
+		t.Fatal("event id wrong: ", num)
+	}
+	if num := conn.nextNum(); num != EventNumStart {
+		t.Fatal("event id wrap wrong: ", num)
+	}
+
+	conn.setFrame(FrameLength)
+	conn.seq = EventNumFixed
+	if num := conn.nextNum(); num != EventNumStart+EventNumFixed+1 {
+		t.Fatal("frame event id wrong: ", num)
+	}
+
+	// repeated response cannot block reader
+	conn.recvResp(c.num, []byte("a"), nil, nil)
+	conn.recvResp(c.num, []byte("b"), nil, nil)
+	if bts := <-conn.rc[c.num].body; string(bts) != "a" {
+		t.Fatal("response wrong: ", string(bts))
+	}
+}
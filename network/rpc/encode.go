@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 
 	"micro/network/comm"
@@ -25,6 +26,10 @@ const (
 	BodyRespStart   = byte(15) // 请求体第一块
 	BodyRespMiddle  = byte(16) // 请求体中间部分
 	BodyRespFinaly  = byte(17) // 最后一块
+
+	BodyFlagMeta   = byte(0x80) // 分块类型标记，请求体前带元数据
+	BodyFlagStatus = byte(0x40) // 分块类型标记，返回体为 Status 编码的错误
+	BodyFlagZip    = byte(0x20) // 分块类型标记，请求体或返回体已压缩
 )
 
 type NetworkBuffer struct {
@@ -36,27 +41,115 @@ type NetworkBuffer struct {
 }
 
 type JoinBodyData struct {
-	Uuid int // request no
-	Func int // server api
-	Buck int // bucker number
-	Sort int // sort number
+	Uuid int  // request no
+	Func int  // server api
+	Buck int  // bucker number
+	Sort int  // sort number
+	Meta bool // body start with metadata
+	Code bool // body is encoded *Status
+	Zip  bool // body was compressed
 	Data []byte
 }
 
 type ConnBody struct{ Data []byte }
 
 func (n *NodeDetail) ParseRspByte(nb NetworkBuffer, nc *NodeConn, cb *ConnBody) [][]byte {
-	num, fid, bts, err := nc.ParseResp(nb, cb)
-	if err != nil || fid == 0 || num == 0 {
+	var result [][]byte
+	n.serveBody(nb, nc, cb, func(row []byte) error {
+		result = append(result, row)
 		return nil
-	} else if fid < comm.BUILT_IN_MAX {
-		bts, err = n.builtin(fid, nc, bts)
-	} else if fmsg := n.QueryFunc(uint32(fid), ""); fmsg != nil {
-		bts, err = n.findCall(fmsg, bts)
-	} else {
-		err = errors.New("not found server api mapping in server: " + nc.Uuid)
+	})
+	return result
+}
+
+// call server api by request body and write response,
+// drop response body not sent when caller cancelled the request,
+// running request was added to drain by caller before start goroutine
+func (n *NodeDetail) serveBody(nb NetworkBuffer, nc *NodeConn, cb *ConnBody, write func([]byte) error) error {
+	body, md, err := nc.parseBody(nb, cb)
+	if err != nil || body == nil || body.Func == 0 || body.Uuid == 0 {
+		return nil
+	}
+	ctx, done := nc.startCall(body.Uuid)
+	defer done()
+	bts, rmd, err := n.dispatch(ctx, nc, body.Func, body.Data, md)
+	if body.Func == comm.CancelCall {
+		return nil
+	} else if md == nil {
+		rmd = nil // old node cannot parse metadata and status
+	}
+	var rsp = &Frame{Func: body.Func, Data: bts}
+	var zip = err == nil && n.compressRsp(nc, body.Func, rsp)
+	err = respError(err, md != nil)
+	for _, row := range nb.makeRspMeta(rsp.Data, body.Uuid, body.Func, rmd, err) {
+		if zip {
+			row[5] |= BodyFlagZip
+		}
+		if ctx.Err() != nil {
+			return nil
+		} else if err := write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call built in or server api by function id, return response metadata
+func (n *NodeDetail) dispatch(ctx context.Context, nc *NodeConn, fid int, bts []byte, md Metadata) ([]byte, Metadata, error) {
+	if fid < comm.BUILT_IN_MAX {
+		bts, err := n.builtin(fid, nc, bts)
+		return bts, nil, err
+	} else if n.drain.closing() {
+		return nil, nil, errShutdown()
+	}
+	// deadline start before query function message
+	ctx, cancel := timeoutContext(ctx, md)
+	defer cancel()
+	if fmsg := n.QueryFunc(uint32(fid), ""); fmsg != nil {
+		return n.findCall(ctx, nc, fmsg, bts, md)
+	}
+	return nil, nil, NewStatus(CodeNotFound, "not found server api mapping in server: "+nc.Uuid)
+}
+
+// compress response body of server api, return true when compressed
+func (n *NodeDetail) compressRsp(nc *NodeConn, fid int, f *Frame) bool {
+	if fid < comm.BUILT_IN_MAX || len(f.Data) == 0 || nc.zipc == nil {
+		return false
+	}
+	var api string
+	if len(nc.zipc.apis) > 0 { // api name was cached by dispatch
+		api = n.QueryFunc(uint32(fid), "").GetApiName()
+	}
+	var zip bool
+	f.Data, zip = nc.compress(api, f.Data)
+	return zip
+}
+
+// make request body with metadata, no metadata when md is nil
+func (n NetworkBuffer) makeReqMeta(bts []byte, num, fid int, md Metadata) [][]byte {
+	if md == nil {
+		return n.MakeReqBody(bts, num, fid)
+	}
+	var result = n.MakeReqBody(joinMeta(md, bts), num, fid)
+	for _, row := range result {
+		row[5] |= BodyFlagMeta
+	}
+	return result
+}
+
+// failed response with *Status can be split, other failed message was limited and not with metadata
+func (n NetworkBuffer) makeRspMeta(bts []byte, num, fid int, md Metadata, err error) [][]byte {
+	var s *Status
+	if errors.As(err, &s) && fid != 0 {
+		var result = n.makeReqMeta(s.encode(), num, fid, md)
+		for _, row := range result {
+			row[5] |= BodyFlagStatus
+		}
+		return result
+	} else if err != nil || fid == 0 {
+		return n.MakeRspBody(bts, num, fid, err)
 	}
-	return nb.MakeRspBody(bts, num, fid, err)
+	return n.makeReqMeta(bts, num, fid, md)
 }
 
 // gen request body split
@@ -157,9 +250,12 @@ func (n NetworkBuffer) parse(b *ConnBody) (*JoinBodyData, error) {
 	var tmp = &JoinBodyData{
 		Uuid: int(b.Data[2])<<16 + int(b.Data[3])<<8 + int(b.Data[4]),
 		Func: int(b.Data[6])<<8 + int(b.Data[7]),
+		Meta: b.Data[5]&BodyFlagMeta != 0,
+		Code: b.Data[5]&BodyFlagStatus != 0,
+		Zip:  b.Data[5]&BodyFlagZip != 0,
 	}
 
-	switch b.Data[5] {
+	switch b.Data[5] &^ (BodyFlagMeta | BodyFlagStatus | BodyFlagZip) {
 	case BodyReqDataNil, BodyRespDataNil:
 		tmp.Buck, tmp.Sort = 1, 1
 	case BodyWholeData, BodyRespSuccess:
@@ -171,7 +267,13 @@ func (n NetworkBuffer) parse(b *ConnBody) (*JoinBodyData, error) {
 		tmp.Buck, tmp.Sort = 1, 1
 		lenght := int(b.Data[10])<<8 + int(b.Data[11])
 		msg := string(b.Data[n.BodyStart : n.BodyStart+lenght])
-		return tmp, errors.New(msg)
+		return tmp, NewStatus(CodeUnknown, msg)
+
+	case BodyRespNoFound:
+		tmp.Buck, tmp.Sort = 1, 1
+		lenght := int(b.Data[10])<<8 + int(b.Data[11])
+		msg := string(b.Data[n.BodyStart : n.BodyStart+lenght])
+		return tmp, NewStatus(CodeNotFound, msg)
 
 	case BodyBodyStart, BodyRespStart:
 		tmp.Buck = int(b.Data[8])<<8 + int(b.Data[9])
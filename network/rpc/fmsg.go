@@ -25,15 +25,33 @@ func (f *funcmap) PutMsg(arg *pb.FuncMsg) {
 	if arg.GetFuncID() == 0 || arg.GetApiName() == "" {
 		return
 	}
-	if data := f.Query(arg.FuncID, ""); data != nil {
+	if data := f.Query(arg.FuncID, ""); data != nil && data.msg.ApiName == arg.ApiName {
 		arg.FuncID = data.msg.FuncID
 	} else {
+		// function id deleted and reused by other api
+		if data != nil {
+			f.delStr(data)
+		}
 		var tmp = &funcdata{msg: arg}
 		f.ids.Store(arg.FuncID, tmp)
 		f.str.Store(arg.ApiName, tmp)
 	}
 }
 
+// function id deleted by watcher
+func (f *funcmap) DelMsg(fid uint32) {
+	if data := f.Query(fid, ""); data != nil {
+		f.ids.Delete(fid)
+		f.delStr(data)
+	}
+}
+
+func (f *funcmap) delStr(data *funcdata) {
+	if v, ok := f.str.Load(data.msg.ApiName); ok && v == data {
+		f.str.Delete(data.msg.ApiName)
+	}
+}
+
 func (f *funcmap) UpFuncNode(fid uint32, ids []string) {
 	if v, ok := f.ids.Load(fid); ok && v != nil {
 		if data, ok := v.(*funcdata); ok {
@@ -94,14 +112,14 @@ func (f *funcmap) ClearConn() {
 }
 
 func (f *funcmap) Query(fid uint32, name string) *funcdata {
-	if f != nil && fid == 0 {
+	if f != nil && fid != 0 {
 		if v, ok := f.ids.Load(fid); ok && v != nil {
 			if data, ok := v.(*funcdata); ok {
 				return data
 			}
 		}
 	}
-	if f != nil && name == "" {
+	if f != nil && name != "" {
 		if v, ok := f.str.Load(name); ok && v != nil {
 			if data, ok := v.(*funcdata); ok {
 				return data
@@ -123,6 +141,9 @@ func (f *funcmap) GetFuncConn(fid uint32, name string) []*NodeConn {
 			fmsg = v.(*funcdata)
 		}
 	}
+	if fmsg == nil {
+		return nil
+	}
 	var result []*NodeConn
 	for _, id := range fmsg.node {
 		if v, ok := f.ser.Load(id); ok && v != nil {
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"micro/network/comm"
+)
+
+// tcp frame format, negotiate when dial tcp connection
+type FrameType int32
+
+const (
+	FrameFixed  FrameType = 0 // NetworkBuffer 定长分块帧
+	FrameLength FrameType = 1 // varint 长度前缀帧
+
+	// Frame flags
+	FrameFlagResp   = byte(1)   // 返回帧
+	FrameFlagStream = byte(2)   // 流式消息
+	FrameFlagOpen   = byte(4)   // 打开流
+	FrameFlagEnd    = byte(8)   // 结束发送
+	FrameFlagWindow = byte(16)  // 增加可发送消息数量
+	FrameFlagCancel = byte(32)  // 取消流
+	FrameFlagMeta   = byte(64)  // 请求体前带元数据
+	FrameFlagZip    = byte(128) // 请求体或返回体已压缩
+
+	// Frame status
+	FrameStatusOK       = byte(0) // 请求成功
+	FrameStatusFailed   = byte(1) // 请求失败,返回错误信息
+	FrameStatusNotFound = byte(2) // 请求接口未找到
+	FrameStatusCode     = byte(3) // 请求失败,返回 Status 编码
+
+	MaxFrameSize   = 64 << 20 // 单帧最大长度
+	FrameReadSize  = 64 << 10 // 读取缓冲大小
+	FrameNegoTimes = time.Second * 3
+)
+
+// length-prefixed frame:
+// uvarint(length) | uvarint(event id) | uvarint(function id) | flags | status | body
+type Frame struct {
+	Num    int      // request event id
+	Func   int      // server api
+	Flags  byte     // frame flags
+	Status byte     // response status
+	Data   []byte   // request or response body
+	Meta   Metadata // request or response metadata
+}
+
+func (f *Frame) Encode() []byte {
+	var data, flags = f.Data, f.Flags
+	if f.Meta != nil {
+		data, flags = joinMeta(f.Meta, f.Data), flags|FrameFlagMeta
+	}
+	var head [binary.MaxVarintLen64*2 + 2]byte
+	var size = binary.PutUvarint(head[0:], uint64(f.Num))
+	size += binary.PutUvarint(head[size:], uint64(f.Func))
+	head[size], head[size+1] = flags, f.Status
+	size += 2
+
+	var result = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+size+len(data))
+	var pre = binary.PutUvarint(result, uint64(size+len(data)))
+	result = append(result[:pre], head[:size]...)
+	return append(result, data...)
+}
+
+func ReadFrame(rd *bufio.Reader) (*Frame, error) {
+	size, err := binary.ReadUvarint(rd)
+	if err != nil {
+		return nil, err
+	} else if size > MaxFrameSize {
+		return nil, fmt.Errorf("frame size %d over limit", size)
+	}
+	var bts = make([]byte, size)
+	if _, err = io.ReadFull(rd, bts); err != nil {
+		return nil, err
+	}
+
+	var result = &Frame{}
+	num, n1 := binary.Uvarint(bts)
+	if n1 <= 0 {
+		return nil, errors.New("frame event id was wrong")
+	}
+	fid, n2 := binary.Uvarint(bts[n1:])
+	if n2 <= 0 || n1+n2+2 > len(bts) {
+		return nil, errors.New("frame header was wrong")
+	}
+	result.Num, result.Func = int(num), int(fid)
+	result.Flags, result.Status = bts[n1+n2], bts[n1+n2+1]
+	if len(bts) > n1+n2+2 {
+		result.Data = bts[n1+n2+2:]
+	}
+	if result.Flags&FrameFlagMeta != 0 {
+		if result.Meta, result.Data, err = splitMeta(result.Data); err != nil {
+			return nil, err
+		}
+		result.Flags &^= FrameFlagMeta
+	}
+	if result.Flags&FrameFlagZip != 0 {
+		if result.Data, err = decompress(result.Data); err != nil {
+			return nil, err
+		}
+		result.Flags &^= FrameFlagZip
+	}
+	return result, nil
+}
+
+func MakeReqFrame(bts []byte, num, fid int) []byte {
+	return (&Frame{Num: num, Func: fid, Data: bts}).Encode()
+}
+
+func MakeRspFrame(bts []byte, num, fid int, err error) []byte {
+	return makeRspFrame(bts, num, fid, nil, err).Encode()
+}
+
+func makeRspFrame(bts []byte, num, fid int, md Metadata, err error) *Frame {
+	var result = &Frame{Num: num, Func: fid, Flags: FrameFlagResp, Data: bts, Meta: md}
+	var s *Status
+	if errors.As(err, &s) {
+		result.Status, result.Data = FrameStatusCode, s.encode()
+	} else if err != nil {
+		result.Status, result.Data = FrameStatusFailed, []byte(err.Error())
+	} else if fid == 0 {
+		result.Status = FrameStatusNotFound
+		result.Data = []byte("not found this server api by function id")
+	}
+	return result
+}
+
+// parse response frame, return *Status when failed
+func (f *Frame) Err() error {
+	switch f.Status {
+	case FrameStatusOK:
+		return nil
+	case FrameStatusCode:
+		return statusError(f.Data)
+	case FrameStatusNotFound:
+		return NewStatus(CodeNotFound, string(f.Data))
+	}
+	return NewStatus(CodeUnknown, string(f.Data))
+}
+
+func (n *NodeDetail) ParseRspFrame(nc *NodeConn, f *Frame) []byte {
+	if f.Func == 0 || f.Num == 0 || f.Flags&FrameFlagResp != 0 {
+		return nil
+	}
+	ctx, done := nc.startCall(f.Num)
+	defer done()
+	bts, md, err := n.dispatch(ctx, nc, f.Func, f.Data, f.Meta)
+	if f.Func == comm.CancelCall || ctx.Err() != nil {
+		return nil // caller cancelled, not response
+	}
+	var result = makeRspFrame(bts, f.Num, f.Func, md, respError(err, f.Meta != nil))
+	if err == nil && n.compressRsp(nc, f.Func, result) {
+		result.Flags |= FrameFlagZip
+	}
+	return result.Encode()
+}
+
+// request to use length-prefixed frame, old node return error and keep fixed frame,
+// return true when node can parse metadata of fixed frame
+func negotiateFrame(conn net.Conn) (FrameType, bool, error) {
+	conn.SetReadDeadline(time.Now().Add(FrameNegoTimes))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for _, row := range tcpsplit.MakeReqBody([]byte{byte(FrameLength)}, 1, comm.TcpFrameMode) {
+		if _, err := conn.Write(row); err != nil {
+			return FrameFixed, false, err
+		}
+	}
+	buff := NewTcpBuffer()
+	defer PutTcpBuffer(buff)
+	if _, err := io.ReadFull(conn, buff.Data); err != nil {
+		return FrameFixed, false, err
+	}
+	body, err := tcpsplit.parse(buff)
+	if body == nil {
+		return FrameFixed, false, err
+	} else if err != nil || body.Func != comm.TcpFrameMode || len(body.Data) != 1 {
+		return FrameFixed, false, nil
+	} else if FrameType(body.Data[0]) == FrameLength {
+		return FrameLength, true, nil
+	}
+	return FrameFixed, true, nil
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"micro/network/comm"
+)
+
+func TestFrameEncode(t *testing.T) {
+	var body = bytes.Repeat([]byte("frame测试"), 200000)
+	var buf bytes.Buffer
+	buf.Write(MakeReqFrame(body, 70000, 321))
+	buf.Write(MakeReqFrame(nil, 1, comm.PingNetwork))
+	buf.Write(MakeRspFrame(nil, 9, 321, errors.New("wrong")))
+
+	rd := bufio.NewReader(&buf)
+	f, err := ReadFrame(rd)
+	if err != nil || f.Num != 70000 || f.Func != 321 || !bytes.Equal(f.Data, body) {
+		t.Fatal("decode large frame wrong", err)
+	}
+	if f, err = ReadFrame(rd); err != nil || f.Func != comm.PingNetwork || len(f.Data) != 0 {
+		t.Fatal("decode empty frame wrong", err)
+	}
+	if f, err = ReadFrame(rd); err != nil || f.Flags&FrameFlagResp == 0 || f.Err() == nil {
+		t.Fatal("decode failed frame wrong", err)
+	}
+	if _, err = ReadFrame(bufio.NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0x7f}))); err == nil {
+		t.Fatal("frame size over limit")
+	}
+}
+
+func testFrameServer(t *testing.T, server *NodeDetail) *net.TCPAddr {
+	listen, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listen.Close() })
+	go func() {
+		for {
+			conn, err := listen.AcceptTCP()
+			if err != nil {
+				return
+			}
+			go server.tcpAccept(conn)
+		}
+	}()
+	return listen.Addr().(*net.TCPAddr)
+}
+
+func testFramePing(t *testing.T, legacy bool, want FrameType) {
+	var client = &NodeDetail{}
+	conn, frame, err := client.DialTCP(testFrameServer(t, &NodeDetail{legacy: legacy}))
+	if err != nil {
+		t.Fatal(err)
+	} else if frame != want {
+		t.Fatal("negotiate frame wrong: ", frame)
+	}
+	var nc = &NodeConn{tconn: conn, types: ConnWithTCP,
+		rc: make(map[int]*RecvChan), list: make(map[int]*ReadLink)}
+	nc.setFrame(frame)
+	defer nc.Close()
+	go nc.ReadRespTCP()
+
+	c, err := nc.NewCall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.DelChan(c.num)
+	if err = nc.WriteTCP(nil, c.num, comm.PingNetwork); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		t.Fatal("wait ping response timeout")
+	case err = <-c.err:
+		t.Fatal(err)
+	case bts := <-c.body:
+		if string(bts) != "PONG" {
+			t.Fatal("ping response wrong: ", string(bts))
+		}
+	}
+}
+
+func TestFrameNegotiate(t *testing.T) {
+	testFramePing(t, false, FrameLength)
+	testFramePing(t, true, FrameFixed)
+}
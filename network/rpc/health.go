@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"micro/common"
+	"micro/network/pb"
+)
+
+// SetServingState : report health state to watcher at once,
+// eg: NOT_SERVING to take node out of rotation for maintenance, SERVING to recover
+func (n *NodeDetail) SetServingState(state pb.ServingState) {
+	n.wser.mut.Lock()
+	changed := n.wser.serving != state
+	n.wser.serving = state
+	n.wser.mut.Unlock()
+	if changed && n.wser.uuid != "" {
+		go func() {
+			defer common.Recover()
+			n.wser.Heartbeats()
+		}()
+	}
+}
+
+// SetHealthCheck : health state callback run by every heartbeat,
+// state set by SetServingState was used first when it was not SERVING
+func (n *NodeDetail) SetHealthCheck(function func() pb.ServingState) {
+	n.wser.mut.Lock()
+	n.wser.check = function
+	n.wser.mut.Unlock()
+}
+
+// ServingState : health state to report
+func (n *NodeDetail) ServingState() pb.ServingState { return n.wser.servingState() }
+
+func (w *WatchNode) servingState() pb.ServingState {
+	w.mut.RLock()
+	state, check := w.serving, w.check
+	w.mut.RUnlock()
+	if state == pb.ServingState_SERVING && check != nil {
+		return check()
+	}
+	return state
+}
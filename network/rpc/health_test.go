@@ -0,0 +1,28 @@
+package rpc
+
+import (
+	"testing"
+
+	"micro/network/pb"
+)
+
+func TestServingState(t *testing.T) {
+	var node = &NodeDetail{wser: &WatchNode{}}
+	if node.ServingState() != pb.ServingState_SERVING || node.wser.SystemState().State != pb.ServingState_SERVING {
+		t.Fatal("default state must be serving")
+	}
+	var check = pb.ServingState_DEGRADED
+	node.SetHealthCheck(func() pb.ServingState { return check })
+	if node.wser.SystemState().State != pb.ServingState_DEGRADED {
+		t.Fatal("heartbeat state must be reported by callback")
+	}
+	// state was set first
+	node.SetServingState(pb.ServingState_DRAINING)
+	if node.ServingState() != pb.ServingState_DRAINING {
+		t.Fatal("set state must cover callback")
+	}
+	node.SetServingState(pb.ServingState_SERVING)
+	if check = pb.ServingState_NOT_SERVING; node.ServingState() != pb.ServingState_NOT_SERVING {
+		t.Fatal("callback must be used when serving")
+	}
+}
@@ -2,12 +2,16 @@ package rpc
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"net"
 	"net/http"
-	"reflect"
+	"strconv"
 
 	"micro/network/comm"
 	"micro/network/pb"
@@ -20,12 +24,42 @@ const (
 	HttpReqFailNetwork = "003" // network error, maybe no this api
 )
 
-func HttpPingTest(host string, port uint64) error {
-	var addr = fmt.Sprintf("http://%s:%d/Ping", host, port)
-	_, err := http.Post(addr, "application/octet-stream", nil)
+func HttpPingTest(host string, port uint64) error { return httpPing(host, port, nil) }
+
+func httpPing(host string, port uint64, c *tlsConfig) error {
+	var addr = fmt.Sprintf("%s://%s:%d/Ping", c.scheme(), host, port)
+	rsp, err := c.httpClient().Post(addr, "application/octet-stream", nil)
+	if err == nil {
+		rsp.Body.Close()
+	}
 	return err
 }
 
+// Http端口监听请求
+func (n *NodeDetail) HttpListen(port int) error {
+	listen, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	for key, function := range n.hlist {
+		mux.HandleFunc("/"+key, function)
+	}
+	// build-in
+	mux.HandleFunc("/"+comm.BUILT_IN_NAME, n.httpBuiltIn)
+	n.lis.http = &http.Server{Handler: mux}
+	if n.tlsc != nil {
+		n.lis.http.TLSConfig = n.tlsc.server
+		listen = tls.NewListener(listen, n.tlsc.server)
+	}
+	go func(s *http.Server) {
+		if err := s.Serve(listen); err != nil && err != http.ErrServerClosed {
+			log.Printf("rpc.Serve: http: %v\n", err)
+		}
+	}(n.lis.http)
+	return nil
+}
+
 func (n *NodeDetail) httpBuiltIn(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	bts, err := ioutil.ReadAll(r.Body)
@@ -38,16 +72,28 @@ func (n *NodeDetail) httpBuiltIn(w http.ResponseWriter, r *http.Request) {
 		makeHttpResp(w, nil, err)
 		return
 	}
-	n.builtin(req.Func, &NodeConn{}, req.Data.([]byte))
+	// http request without registered connection cannot push watcher message
+	if req.Func == comm.UpWatcherList || req.Func == comm.UpNodeConnMsg || req.Func == comm.UpAuthPolicy {
+		makeHttpResp(w, nil, NewStatus(CodePermissionDenied, "built in request only can be sent by watcher connection"))
+		return
+	}
+	bts, err = n.builtin(req.Func, &NodeConn{}, req.Data)
+	makeHttpResp(w, bts, err)
 }
 
 // http: apiname to call
 func (n *NodeDetail) httpCall(name string, s *Server, f *ServerFunc) {
-	if f.api == pb.ApiType_Multi {
+	if f.api == pb.ApiType_Multi || f.api == pb.ApiType_Stream {
 		return
 	}
 	var function = func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
+		n.drain.add()
+		defer n.drain.done()
+		if n.drain.closing() {
+			makeHttpResp(w, nil, errShutdown())
+			return
+		}
 		bts, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			makeHttpResp(w, nil, err)
@@ -55,27 +101,51 @@ func (n *NodeDetail) httpCall(name string, s *Server, f *ServerFunc) {
 		}
 		req, err := UnmarshalValue(s.proto, f.req, bts)
 		if err != nil {
-			makeHttpResp(w, nil, err)
+			makeHttpResp(w, nil, NewStatus(CodeInvalidArgument, err.Error()))
 			return
 		}
 
-		if f.api == pb.ApiType_Send {
-			err = f.ValueSend(s.rv, req)
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		md := metaFromHeader(r.Header)
+		node := callerNode(&pb.NodeInfo{Host: host}, md)
+		var info = &ServerInfo{Func: n.httpFunc(name, s, f), Node: node, Network: "HTTP"}
+		if r.TLS != nil {
+			info.Cert = peerCertificate(*r.TLS)
+		}
+		// node name of http header cannot be verified
+		if err = n.authorize(name, certName(info.Cert)); err != nil {
 			makeHttpResp(w, nil, err)
-		} else if f.api == pb.ApiType_Call {
-			rsp := reflect.New(f.rsp.Elem())
-			if err := f.ValueCall(s.rv, req, rsp); err != nil {
-				makeHttpResp(w, nil, err)
-			}
-			bts, err = MarshalValue(s.proto, rsp)
-			makeHttpResp(w, bts, err)
+			return
 		}
+		ctx, cancel := timeoutContext(withPeerCertificate(r.Context(), info.Cert), md)
+		defer cancel()
+		ctx = withIdentity(ctx, certName(info.Cert))
+		ctx, rm := newIncomingContext(ctx, node, md)
+		rsp, err := n.runUnary(ctx, info, req.Interface(), f.handler(s.rv, nil))
+		metaToHeader(rm.get(), w.Header())
+		if err != nil || rsp == nil {
+			makeHttpResp(w, nil, err)
+			return
+		}
+		bts, err = MarshalInterface(s.proto, rsp)
+		makeHttpResp(w, bts, err)
 	}
 	n.hlist[name] = function
 }
 
+// server api message of http request, function id was zero when not mapping
+func (n *NodeDetail) httpFunc(name string, s *Server, f *ServerFunc) *pb.FuncMsg {
+	if value := n.fmsg.Query(0, name); value != nil {
+		return value.GetMsg()
+	}
+	return &pb.FuncMsg{ApiName: name, ServName: s.sname,
+		FuncName: f.fname, ApiType: f.api, Protocal: s.proto}
+}
+
 func makeHttpResp(w http.ResponseWriter, bts []byte, err error) {
 	if err != nil {
+		st, _ := FromError(err)
+		statusToHeader(st, w.Header())
 		w.Header().Set("code", HttpReqFailMessage)
 		w.Write([]byte(err.Error()))
 	} else if len(bts) == 0 {
@@ -87,29 +157,54 @@ func makeHttpResp(w http.ResponseWriter, bts []byte, err error) {
 }
 
 // return api error, post error
-func postHttpApi(fmsg *pb.FuncMsg, host string, port uint64, body []byte, rsp interface{}) (error, error) {
-	var addr = fmt.Sprintf("http://%s:%d/%s", host, port, fmsg.ApiName)
-	resp, err := http.Post(addr, "application/octet-stream",
-		bytes.NewBuffer(body))
+func postHttpApi(ctx context.Context, nc *NodeConn, fmsg *pb.FuncMsg, body []byte, rsp interface{}) (error, error) {
+	body, apierr, err := postHttpByte(ctx, nc, fmsg, body)
+	if err != nil || apierr != nil {
+		return apierr, err
+	} else if len(body) > 0 && rsp != nil {
+		return UnmarshalInterface(fmsg.Protocal, rsp, body), nil
+	}
+	return nil, nil
+}
+
+// post request body with metadata of context, return response body, api error and network error
+func postHttpByte(ctx context.Context, nc *NodeConn, fmsg *pb.FuncMsg, body []byte) ([]byte, error, error) {
+	var addr = fmt.Sprintf("%s://%s:%d/%s", nc.tlsc.scheme(), nc.Host, nc.Hport, fmsg.ApiName)
+	if fmsg.ApiName == "" && fmsg.FuncID < comm.BUILT_IN_MAX {
+		// build-in request
+		bts, err := json.Marshal(&comm.CommReq{Data: body, Func: int(fmsg.FuncID)})
+		if err != nil {
+			return nil, nil, err
+		}
+		addr, body = fmt.Sprintf("%s://%s:%d/%s", nc.tlsc.scheme(), nc.Host, nc.Hport, comm.BUILT_IN_NAME), bts
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if md, ok := FromOutgoingContext(ctx); ok {
+		metaToHeader(md, req.Header)
+	}
+	resp, err := nc.tlsc.httpClient().Do(req)
 	if err == nil {
 		defer resp.Body.Close()
 		code := resp.Header.Get("code")
+		recvRespMeta(ctx, metaFromHeader(resp.Header))
 
-		if code == HttpReqSuccessBody && rsp != nil {
-			if body, err = ioutil.ReadAll(resp.Body); err == nil {
-				return UnmarshalInterface(fmsg.Protocal, rsp, body), nil
-			}
+		if code == HttpReqSuccessBody {
+			body, err = ioutil.ReadAll(resp.Body)
+			return body, nil, err
 		} else if code == HttpReqSuccessNull {
-			return nil, nil
+			return nil, nil, nil
 		} else if code == HttpReqFailMessage {
-			body, err = ioutil.ReadAll(resp.Body)
-			if err == nil && len(body) > 0 {
-				return errors.New(string(body)), nil
+			if body, err = ioutil.ReadAll(resp.Body); err != nil {
+				return nil, nil, err
 			}
-			return nil, err
+			return nil, statusFromHeader(resp.Header, string(body)), nil
 		}
 	} else {
-		return nil, err
+		return nil, nil, err
 	}
-	return nil, errors.New("http post server api wrong")
+	return nil, nil, errors.New("http post server api wrong")
 }
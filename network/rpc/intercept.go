@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"reflect"
+
+	"micro/network/comm"
+	"micro/network/pb"
+)
+
+// request message pass to server interceptor
+type ServerInfo struct {
+	Func    *pb.FuncMsg       // server api
+	Node    *pb.NodeInfo      // caller node, only host when node not registered
+	Network string            // TCP, UDP, HTTP, Local
+	Cert    *x509.Certificate // caller certificate verified by mutual tls, nil when not use
+}
+
+// call server api, req is []interface{} when api is Multi,
+// return response struct, nil when api is Send
+type UnaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// server api middleware, call next to continue
+type UnaryInterceptor func(ctx context.Context, info *ServerInfo, req interface{}, next UnaryHandler) (interface{}, error)
+
+type StreamHandler func(req interface{}, stream ServerStream) error
+
+// server stream api middleware, stream can be wrapped before call next
+type StreamInterceptor func(info *ServerInfo, req interface{}, stream ServerStream, next StreamHandler) error
+
+// add server interceptor, run in order for TCP, UDP, HTTP and local call,
+// need use before RunServer
+func (n *NodeDetail) Use(inters ...UnaryInterceptor) {
+	n.unary = append(n.unary, inters...)
+}
+
+// add server stream interceptor, need use before RunServer
+func (n *NodeDetail) UseStream(inters ...StreamInterceptor) {
+	n.stream = append(n.stream, inters...)
+}
+
+func (n *NodeDetail) runUnary(ctx context.Context, info *ServerInfo, req interface{}, final UnaryHandler) (interface{}, error) {
+	var next = final
+	for i := len(n.unary) - 1; i >= 0; i-- {
+		inter, handler := n.unary[i], next
+		next = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return inter(ctx, info, req, handler)
+		}
+	}
+	return next(ctx, req)
+}
+
+func (n *NodeDetail) runStream(info *ServerInfo, req interface{}, stream ServerStream, final StreamHandler) error {
+	var next = final
+	for i := len(n.stream) - 1; i >= 0; i-- {
+		inter, handler := n.stream[i], next
+		next = func(req interface{}, stream ServerStream) error {
+			return inter(info, req, stream, handler)
+		}
+	}
+	return next(req, stream)
+}
+
+func argValue(t reflect.Type, v interface{}) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
+}
+
+// final handler to call server function, new response when rsp is nil
+func (server *ServerFunc) handler(rv reflect.Value, rsp interface{}) UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		// drop request when caller deadline was passed
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var values = []reflect.Value{rv}
+		if server.ctx {
+			values = append(values, reflect.ValueOf(ctx))
+		}
+		switch server.api {
+		case pb.ApiType_Send:
+			return nil, server.ValueMulti(append(values, argValue(server.req, req)))
+
+		case pb.ApiType_Call:
+			if rsp == nil {
+				rsp = reflect.New(server.rsp.Elem()).Interface()
+			}
+			return rsp, server.ValueMulti(append(values, argValue(server.req, req), reflect.ValueOf(rsp)))
+
+		case pb.ApiType_Multi:
+			args, ok := req.([]interface{})
+			if !ok || len(args) != len(server.args) {
+				return nil, errors.New("request args number were wrong")
+			}
+			for i, arg := range args {
+				values = append(values, argValue(server.args[i], arg))
+			}
+			if rsp == nil {
+				rsp = reflect.New(server.rsp.Elem()).Interface()
+			}
+			return rsp, server.ValueMulti(append(values, reflect.ValueOf(rsp)))
+		}
+		return nil, errors.New("api type cannot call by unary handler")
+	}
+}
+
+// final handler to call server stream function
+func (server *ServerFunc) streamHandler(rv reflect.Value) StreamHandler {
+	return func(req interface{}, stream ServerStream) error {
+		var values = []reflect.Value{rv}
+		if server.ctx {
+			values = append(values, reflect.ValueOf(stream.Context()))
+		}
+		return server.ValueMulti(append(values, argValue(server.req, req), reflect.ValueOf(stream)))
+	}
+}
+
+// call local server api by interceptor, args of Multi api end with response
+func (n *NodeDetail) localCall(ctx context.Context, fmsg *pb.FuncMsg, s *Server, f *ServerFunc, req, rsp interface{}) error {
+	var info = &ServerInfo{Func: fmsg, Node: &n.NodeInfo, Network: "Local"}
+	if args, ok := req.([]interface{}); ok && f.api == pb.ApiType_Multi {
+		if len(args) == 0 {
+			return errors.New("request args number were wrong")
+		}
+		req, rsp = args[:len(args)-1], args[len(args)-1]
+	}
+	md, _ := FromOutgoingContext(n.callerMeta(ctx))
+	sctx, rm := newIncomingContext(ctx, &n.NodeInfo, md)
+	_, err := n.runUnary(sctx, info, req, f.handler(s.rv, rsp))
+	recvRespMeta(ctx, rm.get())
+	return err
+}
+
+// request message pass to client interceptor
+type ClientInfo struct {
+	Func    *pb.FuncMsg // request api
+	Conn    *NodeConn   // target node connection
+	Network string      // TCP, UDP, HTTP
+}
+
+// send request body by the connection, return response body
+type Invoker func(ctx context.Context, req []byte) ([]byte, error)
+
+// client request middleware, call next to send request
+type ClientInterceptor func(ctx context.Context, info *ClientInfo, req []byte, next Invoker) ([]byte, error)
+
+// connection cannot send request, try next connection
+type connError struct{ err error }
+
+func (e *connError) Error() string { return e.err.Error() }
+func (e *connError) Unwrap() error { return e.err }
+
+func isConnError(err error) bool {
+	var ce *connError
+	return errors.As(err, &ce)
+}
+
+// add client interceptor, run in order for every remote request,
+// need use before RunServer
+func (n *NodeDetail) UseClient(inters ...ClientInterceptor) {
+	n.client = append(n.client, inters...)
+}
+
+// request metadata with caller node
+func (n *NodeDetail) callerMeta(ctx context.Context) context.Context {
+	return AppendOutgoingContext(ctx, MetaNodeUuid, n.Uuid, MetaNodeName, n.Name)
+}
+
+// request the connection by client interceptor
+func (n *NodeDetail) invoke(ctx context.Context, conn *NodeConn, fmsg *pb.FuncMsg, req []byte, final Invoker) ([]byte, error) {
+	var info = &ClientInfo{Func: fmsg, Conn: conn, Network: conn.network()}
+	var next = final
+	ctx = n.callerMeta(ctx)
+	for i := len(n.client) - 1; i >= 0; i-- {
+		inter, invoker := n.client[i], next
+		next = func(ctx context.Context, req []byte) ([]byte, error) {
+			return inter(ctx, info, req, invoker)
+		}
+	}
+	return next(ctx, req)
+}
+
+// final invoker to request and wait response by the connection,
+// send metadata of context and receive response metadata
+func (n *NodeConn) invoker(fmsg *pb.FuncMsg) Invoker {
+	return func(ctx context.Context, req []byte) ([]byte, error) {
+		md, _ := FromOutgoingContext(ctx)
+		md, err := withTimeout(ctx, md)
+		if err != nil {
+			return nil, err
+		}
+		// built in request was sent by tcp registered this node, udp and http cannot verify caller
+		var types = n.types
+		if fmsg.FuncID < comm.BUILT_IN_MAX && n.tconn != nil {
+			types = ConnWithTCP
+		}
+		switch types {
+		case ConnWithTCP, ConnWithUDP:
+			c, err := n.NewCall()
+			if err != nil {
+				return nil, &connError{err}
+			}
+			bts, zip := n.compress(fmsg.ApiName, req)
+			if types == ConnWithTCP {
+				err = n.writeTCP(bts, c.num, int(fmsg.FuncID), md, zip)
+			} else {
+				err = n.writeUDP(bts, c.num, int(fmsg.FuncID), md, zip)
+			}
+			if err != nil {
+				n.DelChan(c.num)
+				return nil, &connError{err}
+			}
+			body, err := n.WaitRspByte(ctx, c)
+			n.mut.RLock()
+			recvRespMeta(ctx, c.meta)
+			n.mut.RUnlock()
+			return body, err
+
+		case ConnWithHTTP:
+			body, apierr, err := postHttpByte(ctx, n, fmsg, req)
+			if err != nil {
+				return nil, &connError{err}
+			}
+			return body, apierr
+		}
+		return nil, &connError{errors.New("connection type was wrong")}
+	}
+}
@@ -0,0 +1,183 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type TstInterReq struct {
+	Num int `json:"num"`
+}
+type TstInterRsp struct {
+	Num int `json:"num"`
+}
+type TstInter struct{}
+
+func (s *TstInter) Compiler_JSON() {}
+
+func (s *TstInter) Double(req *TstInterReq, rsp *TstInterRsp) error {
+	rsp.Num = req.Num * 2
+	return nil
+}
+
+func (s *TstInter) Sum(a, b *TstInterReq, rsp *TstInterRsp) error {
+	rsp.Num = a.Num + b.Num
+	return nil
+}
+
+func (s *TstInter) Crash(req *TstInterReq, rsp *TstInterRsp) error {
+	panic("crash")
+}
+
+func (s *TstInter) Count(req *TstInterReq, stream ServerStream) error {
+	for i := 0; i < req.Num; i++ {
+		if err := stream.Send(&TstInterRsp{Num: i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tstInterLog struct {
+	mut  sync.Mutex
+	list []string
+}
+
+func (l *tstInterLog) add(row string) {
+	l.mut.Lock()
+	l.list = append(l.list, row)
+	l.mut.Unlock()
+}
+
+func (l *tstInterLog) take() string {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	result := strings.Join(l.list, ",")
+	l.list = nil
+	return result
+}
+
+func TestInterceptor(t *testing.T) {
+	client, server := testNodePair(t, &TstInter{})
+	var logs = &tstInterLog{}
+
+	server.Use(func(ctx context.Context, info *ServerInfo, req interface{}, next UnaryHandler) (rsp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				rsp, err = nil, fmt.Errorf("recover: %v", r)
+			}
+		}()
+		logs.add("recover")
+		return next(ctx, req)
+	}, func(ctx context.Context, info *ServerInfo, req interface{}, next UnaryHandler) (interface{}, error) {
+		logs.add(info.Network + ":" + info.Func.ApiName)
+		if r, ok := req.(*TstInterReq); ok && r.Num < 0 {
+			return nil, errors.New("denied")
+		} else if ok {
+			r.Num++ // change request
+		}
+		return next(ctx, req)
+	})
+	server.UseStream(func(info *ServerInfo, req interface{}, stream ServerStream, next StreamHandler) error {
+		logs.add("stream:" + info.Func.ApiName + ":" + info.Node.Host)
+		return next(req, stream)
+	})
+
+	// remote by tcp
+	var rsp = &TstInterRsp{}
+	if err := client.CallAuto("TstInter.Double", &TstInterReq{Num: 2}, rsp).Err(); err != nil || rsp.Num != 6 {
+		t.Fatal("interceptor call wrong: ", rsp.Num, err)
+	} else if row := logs.take(); row != "recover,TCP:TstInter.Double" {
+		t.Fatal("interceptor order wrong: ", row)
+	}
+	if err := client.CallAuto("TstInter.Double", &TstInterReq{Num: -1}, rsp).Err(); err == nil || err.Error() != "denied" {
+		t.Fatal("interceptor reject wrong: ", err)
+	}
+	if err := client.CallAuto("TstInter.Crash", &TstInterReq{}, rsp).Err(); err == nil || err.Error() != "recover: crash" {
+		t.Fatal("interceptor recover wrong: ", err)
+	}
+	logs.take()
+	if err := client.CallMultiAuto("TstInter.Sum", &TstInterReq{Num: 1}, &TstInterReq{Num: 2}, rsp).Err(); err != nil || rsp.Num != 3 {
+		t.Fatal("interceptor multi wrong: ", rsp.Num, err)
+	} else if row := logs.take(); row != "recover,TCP:TstInter.Sum" {
+		t.Fatal("interceptor multi order wrong: ", row)
+	}
+
+	// local call run the same chain
+	if err := server.CallAuto("TstInter.Double", &TstInterReq{Num: 2}, rsp).Err(); err != nil || rsp.Num != 6 {
+		t.Fatal("interceptor local call wrong: ", rsp.Num, err)
+	} else if row := logs.take(); row != "recover,Local:TstInter.Double" {
+		t.Fatal("interceptor local order wrong: ", row)
+	}
+	if err := server.CallMultiAuto("TstInter.Sum", &TstInterReq{Num: 1}, &TstInterReq{Num: 2}, rsp).Err(); err != nil || rsp.Num != 3 {
+		t.Fatal("interceptor local multi wrong: ", rsp.Num, err)
+	}
+	logs.take()
+
+	// stream
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*5)
+	defer cancel()
+	st, err := client.CallStream(ctx, "TstInter.Count", &TstInterReq{Num: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = st.Next(&TstInterRsp{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.Close()
+	if row := logs.take(); row != "stream:TstInter.Count:127.0.0.1" {
+		t.Fatal("stream interceptor wrong: ", row)
+	}
+}
+
+func TestClientInterceptor(t *testing.T) {
+	client, _ := testNodePair(t, &TstInter{})
+	var logs = &tstInterLog{}
+
+	client.UseClient(func(ctx context.Context, info *ClientInfo, req []byte, next Invoker) ([]byte, error) {
+		logs.add(info.Network + ":" + info.Func.ApiName + ":" + info.Conn.Uuid)
+		rsp, err := next(ctx, req)
+		if err != nil && err.Error() == "retry" {
+			return next(ctx, []byte(`{"num":10}`))
+		}
+		return rsp, err
+	}, func(ctx context.Context, info *ClientInfo, req []byte, next Invoker) ([]byte, error) {
+		logs.add("rewrite")
+		if string(req) == `{"num":-1}` {
+			return nil, errors.New("retry")
+		} else if string(req) == `{"num":-2}` {
+			return nil, errors.New("denied")
+		}
+		return next(ctx, req)
+	})
+
+	var rsp = &TstInterRsp{}
+	if err := client.CallAuto("TstInter.Double", &TstInterReq{Num: 2}, rsp).Err(); err != nil || rsp.Num != 4 {
+		t.Fatal("client interceptor call wrong: ", rsp.Num, err)
+	} else if row := logs.take(); row != "TCP:TstInter.Double:S,rewrite" {
+		t.Fatal("client interceptor order wrong: ", row)
+	}
+	if err := client.CallAuto("TstInter.Double", &TstInterReq{Num: -1}, rsp).Err(); err != nil || rsp.Num != 20 {
+		t.Fatal("client interceptor retry wrong: ", rsp.Num, err)
+	} else if row := logs.take(); row != "TCP:TstInter.Double:S,rewrite,rewrite" {
+		t.Fatal("client interceptor retry order wrong: ", row)
+	}
+	if err := client.CallAuto("TstInter.Double", &TstInterReq{Num: -2}, rsp).Err(); err == nil || err.Error() != "denied" {
+		t.Fatal("client interceptor reject wrong: ", err)
+	}
+	logs.take()
+
+	body := client.CallRemoteByte(time.Second, "", "TstInter.Double", []byte(`{"num":3}`))
+	if body.Err() != nil || string(body.RespBody()) != `{"num":6}` {
+		t.Fatal("client interceptor byte call wrong: ", body.Err(), string(body.RespBody()))
+	} else if row := logs.take(); row != "TCP:TstInter.Double:S,rewrite" {
+		t.Fatal("client interceptor byte order wrong: ", row)
+	}
+}
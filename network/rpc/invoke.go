@@ -2,13 +2,9 @@ package rpc
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"strings"
 	"sync"
-	"sync/atomic"
 
-	"micro/network/comm"
 	"micro/network/pb"
 )
 
@@ -44,29 +40,29 @@ func (c *CallResp) RespBody() []byte {
 
 func (n *NodeDetail) send(ctx context.Context, uuid, name string, req interface{}) *CallResp {
 	if name == "" {
-		return &CallResp{err: errors.New("send server api name cannot be null"), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeInvalidArgument, "send server api name cannot be null"), msg: &n.NodeInfo}
 	}
 	nodename, apiname := SplitApiName(name)
 	fmsg := n.QueryFunc(0, apiname)
 	if fmsg == nil || fmsg.ApiType != pb.ApiType_Send {
-		return &CallResp{err: errors.New("call not found this server api: " + name), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "call not found this server api: "+name), msg: &n.NodeInfo}
 	}
 
 	// check local server function
 	if uuid != "" && n.Uuid == uuid {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
 			f, ok := s.funcs[fmsg.FuncName]
-			if !ok || f.api != pb.ApiType_Call {
-				return &CallResp{err: errors.New("local not found this api or input values wrong"),
+			if !ok || f.api != pb.ApiType_Send {
+				return &CallResp{err: NewStatus(CodeNotFound, "local not found this api or input values wrong"),
 					msg: &n.NodeInfo, con: "Local"}
 			}
-			return &CallResp{err: f.LocalSend(s.rv, req), msg: &n.NodeInfo, con: "Local"}
+			return &CallResp{err: n.localCall(ctx, fmsg, s, f, req, nil), msg: &n.NodeInfo, con: "Local"}
 		}
-		return &CallResp{err: errors.New("local server node not found struct service"), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "local server node not found struct service"), msg: &n.NodeInfo}
 	} else if nodename == "" || n.Name == nodename {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
-			if f, ok := s.funcs[fmsg.FuncName]; ok || f.api != pb.ApiType_Call {
-				return &CallResp{err: f.LocalSend(s.rv, req), msg: &n.NodeInfo, con: "Local"}
+			if f, ok := s.funcs[fmsg.FuncName]; ok && f.api == pb.ApiType_Send {
+				return &CallResp{err: n.localCall(ctx, fmsg, s, f, req, nil), msg: &n.NodeInfo, con: "Local"}
 			}
 		}
 	}
@@ -82,26 +78,22 @@ type WaitDone struct {
 
 func (n *NodeDetail) sendall(ctx context.Context, name string, req interface{}, rsp *pb.SendAllRsp) error {
 	if name == "" {
-		return errors.New("send server api name cannot be null")
+		return NewStatus(CodeInvalidArgument, "send server api name cannot be null")
 	}
 	_, apiname := SplitApiName(name)
 	fmsg := n.QueryFunc(0, apiname)
 	if fmsg == nil {
-		return errors.New("not found this server api: " + name)
+		return NewStatus(CodeNotFound, "not found this server api: "+name)
 	}
 	conns := n.GetRemoteConn(ctx, fmsg)
 	if len(conns) <= 0 {
-		return fmt.Errorf("not found remote node")
+		return NewStatus(CodeUnavailable, "not found remote node")
 	}
 
 	bts, err := MarshalInterface(fmsg.Protocal, req)
 	if err != nil {
-		return errors.New("remoteCall marshal error: " + err.Error())
+		return NewStatus(CodeInternal, "remoteCall marshal error: "+err.Error())
 	}
-	var num = n.serial(fmsg.FuncID)
-	var tcpRow = tcpsplit.MakeReqBody(bts, num, int(fmsg.FuncID))
-	var udpRow [][]byte
-
 	var wait = &WaitDone{}
 	for _, conn := range conns {
 		wait.wg.Add(1)
@@ -109,33 +101,28 @@ func (n *NodeDetail) sendall(ctx context.Context, name string, req interface{},
 			defer wait.wg.Done()
 
 			var result = &pb.SendRsp{Uuid: nc.Uuid}
-			if nc.tconn != nil { // try request by tcp
-				result.Network, result.Success = "TCP", true
-				for _, row := range tcpRow {
-					if _, err = nc.tconn.Write(row); err != nil {
-						result.Success = false
-						break
-					}
+			n.invoke(ctx, nc, fmsg, bts, func(ctx context.Context, req []byte) ([]byte, error) {
+				md, _ := FromOutgoingContext(ctx)
+				bts, zip := nc.compress(fmsg.ApiName, req)
+				if nc.tconn != nil { // try request by tcp
+					result.Network = "TCP"
+					result.Success = nc.writeTCP(bts, nc.nextNum(), int(fmsg.FuncID), md, zip) == nil
 				}
-			}
-			if !result.Success && nc.uconn != nil { // try request by udp
-				if len(udpRow) == 0 {
-					udpRow = udpsplit.MakeReqBody(bts, num, int(fmsg.FuncID))
+				if !result.Success && nc.uconn != nil { // try request by udp
+					result.Network = "UDP"
+					result.Success = nc.writeUDP(bts, nc.nextNum(), int(fmsg.FuncID), md, zip) == nil
 				}
-				result.Network, result.Success = "UDP", true
-				for _, row := range udpRow {
-					if _, err = nc.uconn.Write(row); err != nil {
-						result.Success = false
-						break
+				if !result.Success && nc.Hport != 0 { // try request by http
+					aerr, perr := postHttpApi(ctx, nc, fmsg, req, nil)
+					if aerr == nil && perr == nil {
+						result.Network, result.Success = "HTTP", true
 					}
 				}
-			}
-			if !result.Success && nc.Hport != 0 { // try request by http
-				aerr, perr := postHttpApi(fmsg, nc.Host, nc.Hport, bts, nil)
-				if aerr == nil && perr == nil {
-					result.Network, result.Success = "HTTP", true
+				if !result.Success {
+					return nil, &connError{NewStatus(CodeUnavailable, "send request by all network failed")}
 				}
-			}
+				return nil, nil
+			})
 			wait.mut.Lock()
 			rsp.Result = append(rsp.Result, result)
 			wait.mut.Unlock()
@@ -146,34 +133,34 @@ func (n *NodeDetail) sendall(ctx context.Context, name string, req interface{},
 	if len(wait.msg) == 0 {
 		return nil
 	} else {
-		return fmt.Errorf("list server uuid send error: [%s]", strings.Join(wait.msg, ", "))
+		return Errorf(CodeUnavailable, "list server uuid send error: [%s]", strings.Join(wait.msg, ", "))
 	}
 }
 
 func (n *NodeDetail) call(ctx context.Context, uuid, name string, req, rsp interface{}) *CallResp {
 	if name == "" {
-		return &CallResp{err: errors.New("call server api name cannot be null"), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeInvalidArgument, "call server api name cannot be null"), msg: &n.NodeInfo}
 	}
 	nodename, apiname := SplitApiName(name)
 	fmsg := n.QueryFunc(0, apiname)
 	if fmsg == nil || fmsg.ApiType != pb.ApiType_Call {
-		return &CallResp{err: errors.New("call not found this server api: " + name), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "call not found this server api: "+name), msg: &n.NodeInfo}
 	}
 	// check local server function
 	if uuid != "" && n.Uuid == uuid {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
 			f, ok := s.funcs[fmsg.FuncName]
 			if !ok || f.api != pb.ApiType_Call {
-				return &CallResp{err: errors.New("local not found this api or input values wrong"),
+				return &CallResp{err: NewStatus(CodeNotFound, "local not found this api or input values wrong"),
 					msg: &n.NodeInfo, con: "Local"}
 			}
-			return &CallResp{err: f.LocalCall(s.rv, req, rsp), msg: &n.NodeInfo, con: "Local"}
+			return &CallResp{err: n.localCall(ctx, fmsg, s, f, req, rsp), msg: &n.NodeInfo, con: "Local"}
 		}
-		return &CallResp{err: errors.New("local server node not found struct service"), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "local server node not found struct service"), msg: &n.NodeInfo}
 	} else if nodename == "" || n.Name == nodename {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
 			if f, ok := s.funcs[fmsg.FuncName]; ok || f.api != pb.ApiType_Call {
-				return &CallResp{err: f.LocalCall(s.rv, req, rsp), msg: &n.NodeInfo, con: "Local"}
+				return &CallResp{err: n.localCall(ctx, fmsg, s, f, req, rsp), msg: &n.NodeInfo, con: "Local"}
 			}
 		}
 	}
@@ -185,23 +172,23 @@ func (n *NodeDetail) multi(ctx context.Context, uuid, name string, args ...inter
 	nodename, apiname := SplitApiName(name)
 	fmsg := n.QueryFunc(0, apiname)
 	if fmsg == nil || fmsg.ApiType != pb.ApiType_Multi {
-		return &CallResp{err: errors.New("call not found this server api: " + name), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "call not found this server api: "+name), msg: &n.NodeInfo}
 	}
 	// check local server function
 	if uuid != "" && n.Uuid == uuid {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
 			f, ok := s.funcs[fmsg.FuncName]
 			if !ok {
-				return &CallResp{err: errors.New("local not found this api or input values wrong"),
+				return &CallResp{err: NewStatus(CodeNotFound, "local not found this api or input values wrong"),
 					msg: &n.NodeInfo, con: "Local"}
 			}
-			return &CallResp{err: f.LocalMulti(s.rv, args), msg: &n.NodeInfo, con: "Local"}
+			return &CallResp{err: n.localCall(ctx, fmsg, s, f, args, nil), msg: &n.NodeInfo, con: "Local"}
 		}
-		return &CallResp{err: errors.New("local server node not found struct service"), msg: &n.NodeInfo}
+		return &CallResp{err: NewStatus(CodeNotFound, "local server node not found struct service"), msg: &n.NodeInfo}
 	} else if nodename == "" || n.Name == nodename {
 		if s, ok := n.funcs[fmsg.ServName]; ok {
 			if f, ok := s.funcs[fmsg.FuncName]; ok {
-				return &CallResp{err: f.LocalMulti(s.rv, args), msg: &n.NodeInfo, con: "Local"}
+				return &CallResp{err: n.localCall(ctx, fmsg, s, f, args, nil), msg: &n.NodeInfo, con: "Local"}
 			}
 		}
 	}
@@ -214,7 +201,7 @@ func (n *NodeDetail) remoteCall(ctx context.Context, uuid, nodename string,
 	fmsg *pb.FuncMsg, req, rsp interface{}) *CallResp {
 	bts, err := MarshalInterface(fmsg.Protocal, req)
 	if err != nil {
-		return &CallResp{err: errors.New("remoteCall marshal error: " + err.Error()),
+		return &CallResp{err: NewStatus(CodeInternal, "remoteCall marshal error: "+err.Error()),
 			msg: &pb.NodeInfo{}, con: "Local"}
 	}
 
@@ -225,7 +212,7 @@ func (n *NodeDetail) remoteCall(ctx context.Context, uuid, nodename string,
 		conns = n.GetRemoteConn(ctx, fmsg)
 	}
 	if len(conns) <= 0 {
-		return &CallResp{err: fmt.Errorf("no multi remote or get remote error"),
+		return &CallResp{err: NewStatus(CodeUnavailable, "no multi remote or get remote error"),
 			msg: &pb.NodeInfo{}, con: "Comm"}
 	}
 	return n.connsCall(ctx, conns, fmsg, bts, rsp)
@@ -239,7 +226,7 @@ func (n *NodeDetail) remoteMulti(ctx context.Context, uuid, nodename string,
 		if bts, err := MarshalInterface(fmsg.Protocal, args[i]); err == nil {
 			req.Data = append(req.Data, bts)
 		} else {
-			return &CallResp{err: errors.New("remoteCall marshal error: " + err.Error()),
+			return &CallResp{err: NewStatus(CodeInternal, "remoteCall marshal error: "+err.Error()),
 				msg: &pb.NodeInfo{}, con: "Local"}
 		}
 	}
@@ -251,81 +238,35 @@ func (n *NodeDetail) remoteMulti(ctx context.Context, uuid, nodename string,
 		conns = n.GetRemoteConn(ctx, fmsg)
 	}
 	if len(conns) <= 0 || len(args) < 2 {
-		return &CallResp{err: fmt.Errorf("no multi remote or get remote error"),
+		return &CallResp{err: NewStatus(CodeUnavailable, "no multi remote or get remote error"),
 			msg: &pb.NodeInfo{}, con: "Comm"}
 	}
 
 	if bts, err := MarshalInterface(pb.Compiler_PROTO, req); err == nil {
 		return n.connsCall(ctx, conns, fmsg, bts, args[len(args)-1])
 	} else {
-		return &CallResp{err: errors.New("remoteCall marshal error: " + err.Error()),
+		return &CallResp{err: NewStatus(CodeInternal, "remoteCall marshal error: "+err.Error()),
 			msg: &pb.NodeInfo{}, con: "Local"}
 	}
 }
 
-func (n *NodeDetail) serial(fid uint32) int {
-	if int(fid) < comm.WATCH_IN_MAX {
-		return int(fid)
-	} else {
-		var num = int(atomic.AddUint32(&n.reqnum, 1))
-		if num < comm.WATCH_IN_MAX {
-			atomic.SwapUint32(&n.reqnum, uint32(comm.WATCH_IN_MAX))
-			num = int(atomic.AddUint32(&n.reqnum, 1))
-		}
-		return num % 65535
-	}
-}
-
 func (n *NodeDetail) connsCall(ctx context.Context, conns []*NodeConn,
 	fmsg *pb.FuncMsg, bts []byte, rsp interface{}) *CallResp {
-	var num = n.serial(fmsg.FuncID)
-	var tcpRow, udpRow [][]byte
-	for _, conn := range conns {
-		if conn.Uuid == n.Uuid || conn.wrong {
-			continue
-		} else {
-			switch conn.types {
-			case ConnWithTCP:
-				if len(tcpRow) == 0 {
-					tcpRow = tcpsplit.MakeReqBody(bts, num, int(fmsg.FuncID))
-				}
-				for _, row := range tcpRow {
-					if _, err := conn.tconn.Write(row); err != nil {
-						goto NextConn
-					}
-				}
-				return &CallResp{err: conn.WaitRsp(ctx, num, fmsg, rsp), msg: &conn.NodeInfo, con: "TCP"}
-
-			case ConnWithUDP:
-				if len(udpRow) == 0 {
-					udpRow = udpsplit.MakeReqBody(bts, num, int(fmsg.FuncID))
-				}
-				for _, row := range udpRow {
-					if _, err := conn.uconn.Write(row); err != nil {
-						goto NextConn
-					}
-				}
-				return &CallResp{err: conn.WaitRsp(ctx, num, fmsg, rsp), msg: &conn.NodeInfo, con: "UDP"}
-
-			case ConnWithHTTP:
-				apierr, err := postHttpApi(fmsg, conn.Host, conn.Hport, bts, rsp)
-				if err == nil {
-					return &CallResp{err: apierr, msg: &conn.NodeInfo, con: "HTTP"}
-				}
-			}
-		}
-	NextConn:
+	body, conn, err := n.retryCall(ctx, conns, fmsg, bts)
+	if conn == nil {
+		return &CallResp{err: err, msg: &pb.NodeInfo{}, con: "Remote"}
+	} else if err == nil && len(body) > 0 && rsp != nil {
+		err = UnmarshalInterface(fmsg.Protocal, rsp, body)
 	}
-	return &CallResp{err: errors.New("call all server node with api, but all wrong"),
-		msg: &pb.NodeInfo{}, con: "Remote"}
+	return &CallResp{err: err, msg: &conn.NodeInfo, con: conn.network()}
 }
 
-func (n *NodeConn) WaitRsp(ctx context.Context, num int, fmsg *pb.FuncMsg, rsp interface{}) error {
-	var c = n.NewChan(num)
-	defer n.DelChan(num)
+func (n *NodeConn) WaitRsp(ctx context.Context, c *RecvChan, fmsg *pb.FuncMsg, rsp interface{}) error {
+	defer n.DelChan(c.num)
 
 	select {
 	case <-ctx.Done():
+		n.sendCancel(c.num)
 		return ctx.Err()
 	case buff := <-c.body:
 		if len(buff) == 0 || rsp == nil {
@@ -360,13 +301,24 @@ func (n *NodeDetail) GetRemoteConn(ctx context.Context, fmsg *pb.FuncMsg) []*Nod
 				rows = append(rows, conn)
 			}
 		}
-		if len(ids) < len(rsp.List) {
-			n.fmsg.UpFuncNode(fmsg.FuncID, ids)
-		}
+		upConnStatus(rows, rsp.Stat)
+		n.fmsg.UpFuncNode(fmsg.FuncID, ids)
 	}
 	return rows
 }
 
+// update connection system status by watcher response
+func upConnStatus(conns []*NodeConn, stats []*pb.SystemStatus) {
+	for _, stat := range stats {
+		for _, conn := range conns {
+			if conn.Uuid == stat.GetUuid() {
+				conn.setSystemStatus(stat)
+				break
+			}
+		}
+	}
+}
+
 // Get server remote connect list by node uuid or node name
 func (n *NodeDetail) GetAssignConn(ctx context.Context, uuid, name string) []*NodeConn {
 	if uuid != "" {
@@ -0,0 +1,249 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"micro/network/pb"
+)
+
+// 请求和返回的元数据，随请求帧发送:
+// 长度前缀帧设置 FrameFlagMeta，定长帧分块类型设置 BodyFlagMeta，
+// 请求体前加 uvarint(元数据长度) | 元数据，http 请求使用 MetaHttpPrefix 开头的 header
+type Metadata map[string]string
+
+const (
+	MetaNodeUuid   = "node-uuid"   // caller node uuid, set by client
+	MetaNodeName   = "node-name"   // caller node name, set by client
+	MetaTimeout    = "rpc-timeout" // remaining milliseconds of caller deadline, set by client
+	MetaHttpPrefix = "Rpc-Meta-"
+)
+
+// make metadata by key value pairs, key was lower case
+func NewMetadata(kv ...string) Metadata {
+	var result = make(Metadata, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		result.Set(kv[i], kv[i+1])
+	}
+	return result
+}
+
+func (md Metadata) Get(key string) string { return md[strings.ToLower(key)] }
+
+func (md Metadata) Set(key, value string) { md[strings.ToLower(key)] = value }
+
+func (md Metadata) Copy() Metadata {
+	var result = make(Metadata, len(md))
+	for k, v := range md {
+		result[k] = v
+	}
+	return result
+}
+
+// uvarint(count) | uvarint(key length) | key | uvarint(value length) | value ...
+func (md Metadata) encode() []byte {
+	var result = appendUvarint(nil, uint64(len(md)))
+	for k, v := range md {
+		result = appendUvarint(result, uint64(len(k)))
+		result = append(result, k...)
+		result = appendUvarint(result, uint64(len(v)))
+		result = append(result, v...)
+	}
+	return result
+}
+
+func appendUvarint(bts []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(bts, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func decodeMeta(bts []byte) (Metadata, error) {
+	count, n := binary.Uvarint(bts)
+	if n <= 0 || count > uint64(len(bts)) {
+		return nil, errors.New("metadata count was wrong")
+	}
+	var result = make(Metadata, count)
+	var rows [2]string
+	for bts = bts[n:]; count > 0; count-- {
+		for i := range rows {
+			size, n := binary.Uvarint(bts)
+			if n <= 0 || uint64(len(bts)-n) < size {
+				return nil, errors.New("metadata body was wrong")
+			}
+			rows[i], bts = string(bts[n:n+int(size)]), bts[n+int(size):]
+		}
+		result[rows[0]] = rows[1]
+	}
+	return result, nil
+}
+
+// uvarint(metadata length) | metadata | body
+func joinMeta(md Metadata, body []byte) []byte {
+	var meta = md.encode()
+	var result = appendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(meta)+len(body)), uint64(len(meta)))
+	return append(append(result, meta...), body...)
+}
+
+func splitMeta(bts []byte) (Metadata, []byte, error) {
+	size, n := binary.Uvarint(bts)
+	if n <= 0 || uint64(len(bts)-n) < size {
+		return nil, nil, errors.New("metadata length was wrong")
+	}
+	md, err := decodeMeta(bts[n : n+int(size)])
+	if err != nil {
+		return nil, nil, err
+	}
+	if bts = bts[n+int(size):]; len(bts) == 0 {
+		bts = nil
+	}
+	return md, bts, nil
+}
+
+func metaToHeader(md Metadata, h http.Header) {
+	for k, v := range md {
+		h.Set(MetaHttpPrefix+k, v)
+	}
+}
+
+func metaFromHeader(h http.Header) Metadata {
+	var result = Metadata{}
+	for k, v := range h {
+		if len(v) > 0 && len(k) > len(MetaHttpPrefix) && strings.EqualFold(k[:len(MetaHttpPrefix)], MetaHttpPrefix) {
+			result.Set(k[len(MetaHttpPrefix):], v[0])
+		}
+	}
+	return result
+}
+
+// add remaining time of context deadline, error when deadline was passed
+func withTimeout(ctx context.Context, md Metadata) (Metadata, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return md, nil
+	}
+	left := time.Until(deadline)
+	if left <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	md = md.Copy()
+	md[MetaTimeout] = strconv.FormatInt(int64((left+time.Millisecond-1)/time.Millisecond), 10)
+	return md, nil
+}
+
+// server context bounded by caller deadline, remove timeout from metadata
+func timeoutContext(ctx context.Context, md Metadata) (context.Context, context.CancelFunc) {
+	v, ok := md[MetaTimeout]
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	delete(md, MetaTimeout)
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+type (
+	outgoingKey struct{}
+	incomingKey struct{}
+	respMetaKey struct{}
+	respRecvKey struct{}
+)
+
+// response metadata of server, set by handler or interceptor
+type respMeta struct {
+	mut sync.Mutex
+	md  Metadata
+}
+
+// client request with metadata, replace metadata of parent context
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingKey{}, md)
+}
+
+// client request add metadata by key value pairs
+func AppendOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+	if md == nil {
+		md = Metadata{}
+	} else {
+		md = md.Copy()
+	}
+	for k, v := range NewMetadata(kv...) {
+		md[k] = v
+	}
+	return NewOutgoingContext(ctx, md)
+}
+
+func FromOutgoingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(outgoingKey{}).(Metadata)
+	return md, ok
+}
+
+// request metadata of server handler
+func FromIncomingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(incomingKey{}).(Metadata)
+	return md, ok
+}
+
+// caller node of request, registered node first, else by metadata
+func callerNode(node *pb.NodeInfo, md Metadata) *pb.NodeInfo {
+	if node.Uuid != "" || md.Get(MetaNodeUuid) == "" {
+		return node
+	}
+	return &pb.NodeInfo{Uuid: md.Get(MetaNodeUuid), Name: md.Get(MetaNodeName), Host: node.Host}
+}
+
+// server context with caller node, request metadata and response metadata holder,
+// CtxKeyNodeUuid is caller uuid, CtxKeyNodeBase is *pb.NodeInfo of caller
+func newIncomingContext(ctx context.Context, node *pb.NodeInfo, md Metadata) (context.Context, *respMeta) {
+	if md == nil {
+		md = Metadata{}
+	}
+	var rm = &respMeta{}
+	ctx = context.WithValue(ctx, CtxKeyNodeUuid, node.Uuid)
+	ctx = context.WithValue(ctx, CtxKeyNodeBase, node)
+	ctx = context.WithValue(ctx, incomingKey{}, md)
+	return context.WithValue(ctx, respMetaKey{}, rm), rm
+}
+
+// server handler add response metadata
+func SetResponseMeta(ctx context.Context, md Metadata) error {
+	rm, ok := ctx.Value(respMetaKey{}).(*respMeta)
+	if !ok {
+		return errors.New("context was not server request")
+	}
+	rm.mut.Lock()
+	defer rm.mut.Unlock()
+	if rm.md == nil {
+		rm.md = Metadata{}
+	}
+	for k, v := range md {
+		rm.md[k] = v
+	}
+	return nil
+}
+
+func (rm *respMeta) get() Metadata {
+	rm.mut.Lock()
+	defer rm.mut.Unlock()
+	return rm.md
+}
+
+// client receive response metadata into md
+func WithResponseMeta(ctx context.Context, md *Metadata) context.Context {
+	return context.WithValue(ctx, respRecvKey{}, md)
+}
+
+func recvRespMeta(ctx context.Context, md Metadata) {
+	if p, ok := ctx.Value(respRecvKey{}).(*Metadata); ok && p != nil {
+		*p = md
+	}
+}
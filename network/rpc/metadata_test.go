@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"micro/network/pb"
+)
+
+type TstMetaReq struct {
+	Key string `json:"key"`
+}
+type TstMetaRsp struct {
+	Value string `json:"value"`
+}
+type TstMeta struct{ done chan error }
+
+func (s *TstMeta) Compiler_JSON() {}
+
+func (s *TstMeta) Get(ctx context.Context, req *TstMetaReq, rsp *TstMetaRsp) error {
+	md, _ := FromIncomingContext(ctx)
+	rsp.Value = md.Get(req.Key)
+	return SetResponseMeta(ctx, NewMetadata("Server", md.Get(MetaNodeUuid)))
+}
+
+// wait caller disconnected
+func (s *TstMeta) Wait(ctx context.Context, req *TstMetaReq, rsp *TstMetaRsp) error {
+	if ctx.Value(CtxKeyNodeUuid) != req.Key || ctx.Value(CtxKeyNodeBase).(*pb.NodeInfo).Uuid != req.Key {
+		s.done <- errors.New("caller node wrong")
+		return nil
+	}
+	<-ctx.Done()
+	s.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestMetadataEncode(t *testing.T) {
+	var md = NewMetadata("Trace-Id", "abc", "empty", "", "名称", "值")
+	got, body, err := splitMeta(joinMeta(md, []byte("body")))
+	if err != nil || string(body) != "body" || len(got) != 3 ||
+		got.Get("trace-id") != "abc" || got["名称"] != "值" {
+		t.Fatal("metadata decode wrong: ", got, err)
+	}
+	if _, _, err = splitMeta([]byte{5, 1, 2}); err == nil {
+		t.Fatal("metadata length over body")
+	}
+
+	// fixed frame split into many bucket
+	var large = bytes.Repeat([]byte("x"), udpsplit.BodySplit*3)
+	var nc = &NodeConn{list: make(map[int]*ReadLink)}
+	var rows = udpsplit.makeReqMeta(large, 123, 456, md)
+	for i, row := range rows {
+		b, meta, err := nc.parseBody(udpsplit, &ConnBody{Data: row})
+		if i < len(rows)-1 {
+			if b != nil || err != nil {
+				t.Fatal("body not all received: ", err)
+			}
+			continue
+		}
+		if err != nil || b.Uuid != 123 || b.Func != 456 || !bytes.Equal(b.Data, large) || meta.Get("Trace-Id") != "abc" {
+			t.Fatal("fixed frame metadata wrong: ", err, meta)
+		}
+	}
+	if num, fid, bts, err := nc.ParseResp(udpsplit, &ConnBody{Data: udpsplit.makeReqMeta([]byte("a"), 1, 2, md)[0]}); err != nil ||
+		num != 1 || fid != 2 || string(bts) != "a" {
+		t.Fatal("parse body with metadata wrong: ", err)
+	}
+}
+
+func testMetadataCall(t *testing.T, client *NodeDetail) {
+	var recv Metadata
+	ctx := AppendOutgoingContext(context.TODO(), "Trace-Id", "abc")
+	ctx = WithResponseMeta(ctx, &recv)
+
+	var rsp = &TstMetaRsp{}
+	if err := client.CallAutoContext(ctx, "TstMeta.Get", &TstMetaReq{Key: "trace-id"}, rsp).Err(); err != nil || rsp.Value != "abc" {
+		t.Fatal("request metadata wrong: ", rsp.Value, err)
+	} else if recv.Get("server") != client.Uuid {
+		t.Fatal("response metadata wrong: ", recv)
+	}
+	if err := client.CallAutoContext(ctx, "TstMeta.Get", &TstMetaReq{Key: MetaNodeUuid}, rsp).Err(); err != nil || rsp.Value != client.Uuid {
+		t.Fatal("caller node metadata wrong: ", rsp.Value, err)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	// length-prefixed frame
+	client, server := testNodeLink(t, &TstMeta{}, false)
+	testMetadataCall(t, client)
+
+	// local call
+	testMetadataCall(t, server)
+
+	// fixed-size frame
+	client, _ = testNodeLink(t, &TstMeta{}, true)
+	testMetadataCall(t, client)
+}
+
+// udp node cannot negotiate, metadata only sent when node registered it can parse
+func TestMetadataUdp(t *testing.T) {
+	lis, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	var port = uint64(lis.LocalAddr().(*net.UDPAddr).Port)
+	var client = &NodeDetail{}
+	for _, meta := range []bool{false, true} {
+		var nc = &NodeConn{NodeInfo: pb.NodeInfo{Host: "127.0.0.1", Uport: port, Metadata: meta}}
+		if err = client.RefreshConn(nc); err != nil {
+			t.Fatal(err)
+		} else if nc.uconn.Close(); nc.meta != meta {
+			t.Fatal("udp metadata must be same as node registered: ", meta)
+		}
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	var svc = &TstMeta{done: make(chan error, 1)}
+	client, _ := testNodePair(t, svc)
+	go client.CallAutoTimeout(time.Second*5, "TstMeta.Wait", &TstMetaReq{Key: client.Uuid}, &TstMetaRsp{})
+
+	time.Sleep(time.Millisecond * 100)
+	client.fmsg.GetNodeConn("S").Close()
+	select {
+	case err := <-svc.done:
+		if err != context.Canceled {
+			t.Fatal("server context wrong: ", err)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("server context not cancelled when caller disconnected")
+	}
+}
+
+func testCancelCall(t *testing.T, legacy bool) {
+	var svc = &TstMeta{done: make(chan error, 1)}
+	client, _ := testNodeLink(t, svc, legacy)
+	ctx, cancel := context.WithCancel(context.TODO())
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		cancel()
+	}()
+	if err := client.CallAutoContext(ctx, "TstMeta.Wait", &TstMetaReq{Key: client.Uuid}, &TstMetaRsp{}).Err(); err != context.Canceled {
+		t.Fatal("call must be cancelled: ", err)
+	}
+	select {
+	case err := <-svc.done:
+		if err != context.Canceled {
+			t.Fatal("server context wrong: ", err)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("server context not cancelled by caller")
+	}
+
+	// connection still work after cancel
+	var rsp = &TstMetaRsp{}
+	if err := client.CallAuto("TstMeta.Get", &TstMetaReq{Key: MetaNodeUuid}, rsp).Err(); err != nil || rsp.Value != client.Uuid {
+		t.Fatal("call after cancel wrong: ", rsp.Value, err)
+	}
+}
+
+func TestCancelCall(t *testing.T) {
+	testCancelCall(t, false)
+	testCancelCall(t, true)
+}
+
+type TstDeadline struct {
+	done chan error
+	runs int32
+}
+
+func (s *TstDeadline) Compiler_JSON() {}
+
+func (s *TstDeadline) Slow(ctx context.Context, req *TstMetaReq, rsp *TstMetaRsp) error {
+	atomic.AddInt32(&s.runs, 1)
+	md, _ := FromIncomingContext(ctx)
+	if dl, ok := ctx.Deadline(); !ok || time.Until(dl) > time.Millisecond*200 || md.Get(MetaTimeout) != "" {
+		s.done <- errors.New("caller deadline wrong")
+		return nil
+	}
+	<-ctx.Done()
+	s.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func testDeadline(t *testing.T, legacy bool) {
+	var svc = &TstDeadline{done: make(chan error, 1)}
+	client, server := testNodeLink(t, svc, legacy)
+	if err := client.CallAutoTimeout(time.Millisecond*200, "TstDeadline.Slow", &TstMetaReq{}, &TstMetaRsp{}).Err(); err == nil {
+		t.Fatal("call must be timeout")
+	}
+	// caller may cancel the request by timeout before server deadline
+	select {
+	case err := <-svc.done:
+		if err != context.DeadlineExceeded && err != context.Canceled {
+			t.Fatal("server deadline wrong: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server api not bounded by caller deadline")
+	}
+
+	// request waiting in server over deadline, not run api
+	var passed = make(chan error, 1)
+	server.Use(func(ctx context.Context, info *ServerInfo, req interface{}, next UnaryHandler) (interface{}, error) {
+		<-ctx.Done()
+		rsp, err := next(ctx, req)
+		passed <- err
+		return rsp, err
+	})
+	atomic.StoreInt32(&svc.runs, 0)
+	client.CallAutoTimeout(time.Millisecond*100, "TstDeadline.Slow", &TstMetaReq{}, &TstMetaRsp{})
+	select {
+	case err := <-passed:
+		if err == nil || atomic.LoadInt32(&svc.runs) != 0 {
+			t.Fatal("expired request was not dropped: ", err)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("server request not bounded by caller deadline")
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	testDeadline(t, false)
+	testDeadline(t, true)
+}
@@ -35,8 +35,6 @@ type NodeDetail struct {
 	hlist map[string]func(http.ResponseWriter, *http.Request)
 	// timer to run
 	ticker timer.TimerStruct
-	// request timers
-	reqnum uint32
 
 	// function message and connection
 	fmsg *funcmap
@@ -44,10 +42,40 @@ type NodeDetail struct {
 	// watcher node detail to connect
 	wser *WatchNode
 
-	// dail network to register link
+	// default load balance strategy, balanceValue
+	balance atomic.Value
+	// api name load balance strategy
+	apibal sync.Map // map[api-name]Balancer
+	// default retry policy, nil when not retry
+	retry *retryPolicy
+	// api name retry policy
+	apiretry sync.Map // map[api-name]*retryPolicy
+	// circuit breaker config of remote node, nil when not use
+	breaker *network.BreakerConfig
+	// only use fixed-size tcp frame
+	legacy bool
+	// tls of tcp and http, nil when not use
+	tlsc *tlsConfig
+	// sign register message and verify registered node
+	sign *signer
+	// compressor to send body
+	zipc *compressConfig
+	// send udp request by reliable udp when remote node support it
+	reliable bool
+	// authorization policy of server api from watcher, *pb.AuthPolicy
+	auth atomic.Value
+	// server interceptor chain
+	unary  []UnaryInterceptor
+	stream []StreamInterceptor
+	// client interceptor chain
+	client []ClientInterceptor
+	// running server requests and listeners to shutdown
+	drain drain
+	lis   listeners
+
+	// node message to register the dialed link, signed when dial
 	tmps struct {
-		tcpreg [][]byte
-		udpreg [][]byte
+		node *pb.NodeInfo
 	}
 	run sync.Once
 }
@@ -84,27 +112,72 @@ func (n *NodeDetail) GetNodeMsg() pb.NodeInfo { return n.NodeInfo }
 func WatchClient(config *network.NodeConfig) (*NodeDetail, error) { return newClient(config) }
 
 func newClient(config *network.NodeConfig) (*NodeDetail, error) {
+	var err error
 	var result = &NodeDetail{
 		NodeInfo: pb.NodeInfo{
-			Pid:   uint64(syscall.Getpid()),
-			Ver:   config.Version,
-			Name:  config.NodeName,
-			Tport: config.TcpPort,
-			Uport: config.UdpPort,
-			Hport: config.HttpPort,
+			Pid:         uint64(syscall.Getpid()),
+			Ver:         config.Version,
+			Name:        config.NodeName,
+			Tport:       config.TcpPort,
+			Uport:       config.UdpPort,
+			Hport:       config.HttpPort,
+			Weight:      config.Weight,
+			WarmUp:      uint64(config.WarmUp.Milliseconds()),
+			Compress:    compressorNames(),
+			UdpReliable: true,
+			Metadata:    true,
 		},
-		ticker: timer.NewTimer(time.Millisecond * 200),
-		wser:   &WatchNode{},
-		fmsg:   &funcmap{},
-		funcs:  make(map[string]*Server),
-		hlist:  make(map[string]func(http.ResponseWriter, *http.Request)),
+		ticker:   timer.NewTimer(time.Millisecond * 200),
+		wser:     &WatchNode{},
+		fmsg:     &funcmap{},
+		funcs:    make(map[string]*Server),
+		hlist:    make(map[string]func(http.ResponseWriter, *http.Request)),
+		legacy:   config.TcpFrameLegacy,
+		reliable: config.UdpReliable,
+	}
+	if result.Weight == 0 {
+		result.Weight = DefaultWeight
+	}
+	if b, err := NewBalancer(config.Balance); err != nil {
+		return nil, err
+	} else {
+		result.SetBalancer("", b)
+	}
+	for name, mode := range config.ApiBalance {
+		if b, err := NewBalancer(mode); err != nil {
+			return nil, err
+		} else {
+			result.apibal.Store(name, b)
+		}
+	}
+	if result.retry, err = newRetryPolicy(config.Retry); err != nil {
+		return nil, err
+	}
+	for name, policy := range config.ApiRetry {
+		if err = result.SetRetryPolicy(name, policy); err != nil {
+			return nil, err
+		}
+	}
+	if !config.BreakerOff {
+		result.breaker = newBreakerConfig(config.Breaker)
+	}
+	if result.tlsc, err = newTLSConfig(config.TLS); err != nil {
+		return nil, err
+	} else if result.tlsc != nil && config.UdpListenOn {
+		return nil, errors.New("udp cannot be used with tls")
+	}
+	if result.sign, err = newSigner(config.SignKey, config.SignKeys); err != nil {
+		return nil, err
+	}
+	result.wser.sign = result.sign
+	if result.zipc, err = newCompressConfig(config.Compress, config.CompressMin, config.ApiCompress); err != nil {
+		return nil, err
 	}
 	for _, row := range config.Watchers {
 		result.wser.config = append(result.wser.config, &pb.NodeInfo{
 			Host: row.Host, Tport: row.TcpPort, Uport: row.UdpPort})
 	}
 
-	var err error
 	if result.Host, err = common.GetLocalIp(); err != nil {
 		result.Host = "0.0.0.0"
 	}
@@ -157,24 +230,26 @@ func newClient(config *network.NodeConfig) (*NodeDetail, error) {
 }
 
 func (n *NodeDetail) initMsgByte() error {
-	if bts, err := proto.Marshal(&n.NodeInfo); err != nil {
+	var node = proto.Clone(&n.NodeInfo).(*pb.NodeInfo)
+	if bts, err := n.sign.marshal(node); err != nil {
 		return err
-	} else {
-		rows := tcpsplit.MakeReqBody(bts, 1, comm.DialRegister)
-		if len(rows) < 1 {
-			return errors.New("node message have mistake")
-		}
-		n.tmps.tcpreg = rows
-
-		rows = udpsplit.MakeReqBody(bts, 1, comm.DialRegister)
-		if len(rows) < 1 {
-			return errors.New("node message have mistake")
-		}
-		n.tmps.udpreg = rows
+	} else if len(tcpsplit.MakeReqBody(bts, 1, comm.DialRegister)) < 1 ||
+		len(udpsplit.MakeReqBody(bts, 1, comm.DialRegister)) < 1 {
+		return errors.New("node message have mistake")
 	}
+	n.tmps.node = node
 	return nil
 }
 
+// register message of the dialed link, signed every time to avoid replay,
+// nil when node message was not init
+func (n *NodeDetail) dialRegister() ([]byte, error) {
+	if n.tmps.node == nil {
+		return nil, nil
+	}
+	return n.sign.marshal(n.tmps.node)
+}
+
 // arge: name, port
 func (n *NodeDetail) runServer() error {
 	// make tcp listen
@@ -191,19 +266,9 @@ func (n *NodeDetail) runServer() error {
 	}
 	// make http listen server
 	if n.Hport > 0 {
-		go func(nd *NodeDetail) {
-			mux := http.NewServeMux()
-			for key, function := range nd.hlist {
-				mux.HandleFunc("/"+key, function)
-			}
-			// build-in
-			mux.HandleFunc("/"+comm.BUILT_IN_NAME, nd.httpBuiltIn)
-			err := http.ListenAndServe(":"+strconv.FormatUint(nd.Hport, 10), mux)
-			if err != nil {
-				panic(err)
-			}
-			nd.hlist = nil
-		}(n)
+		if err := n.HttpListen(int(n.Hport)); err != nil {
+			return fmt.Errorf("http listen: %v", err)
+		}
 	}
 
 	if n.NodeInfo.Name != comm.WatchNodeName {
@@ -233,6 +298,9 @@ func (n *NodeDetail) InitTimer() {
 	// timer make heartbeat to watcher
 	if n.Name != comm.WatchNodeName {
 		n.ticker.AddDurationFunction(comm.HeartbeatInterval, -1, func() {
+			if n.isShutdown() {
+				return // deregistered, not register again
+			}
 			if err := n.WatchApi().Heartbeats(); err != nil {
 				if atomic.AddUint32(&heartbeatsWrong, 1)%3 != 0 {
 					return
@@ -259,6 +327,9 @@ func (n *NodeDetail) NodeBaseToConn(node *pb.NodeInfo) (*NodeConn, error) {
 		if !conn.wrong && conn.Host != node.Host {
 			return nil, errors.New("same uuid but host not same")
 		} else if conn.TestConn() == nil {
+			if conn.wrong {
+				conn.startWarmUp()
+			}
 			conn.wrong = false
 			return conn, nil
 		} else if conn != nil {
@@ -276,6 +347,7 @@ func (n *NodeDetail) NodeBaseToConn(node *pb.NodeInfo) (*NodeConn, error) {
 	}
 	err := n.RefreshConn(conn)
 	if err == nil {
+		conn.startWarmUp()
 		n.TimerCheck(conn)
 		n.fmsg.PutConn(conn)
 	}
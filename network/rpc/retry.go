@@ -0,0 +1,174 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"micro/network"
+	"micro/network/pb"
+)
+
+const (
+	DefaultRetryBackoff    = time.Millisecond * 50
+	DefaultRetryMaxBackoff = time.Second
+	DefaultRetryJitter     = 0.2
+)
+
+// retry policy with parsed status code
+type retryPolicy struct {
+	network.RetryPolicy
+	codes map[Code]bool
+}
+
+// make retry policy, fill default value, nil when not retry
+func newRetryPolicy(config *network.RetryPolicy) (*retryPolicy, error) {
+	if config == nil || config.MaxAttempts <= 1 {
+		return nil, nil
+	}
+	var result = &retryPolicy{RetryPolicy: *config, codes: make(map[Code]bool)}
+	if result.Backoff <= 0 {
+		result.Backoff = DefaultRetryBackoff
+	}
+	if result.MaxBackoff < result.Backoff {
+		result.MaxBackoff = DefaultRetryMaxBackoff
+		if result.MaxBackoff < result.Backoff {
+			result.MaxBackoff = result.Backoff
+		}
+	}
+	if result.Jitter <= 0 || result.Jitter > 1 {
+		result.Jitter = DefaultRetryJitter
+	}
+	if len(result.RetryOn) == 0 {
+		result.RetryOn = []string{CodeUnavailable.String()}
+	}
+	for _, name := range result.RetryOn {
+		code, ok := codeByName(name)
+		if !ok {
+			return nil, errors.New("not found retry status code: " + name)
+		}
+		result.codes[code] = true
+	}
+	return result, nil
+}
+
+func codeByName(name string) (Code, bool) {
+	for code, value := range codeNames {
+		if value == name {
+			return code, true
+		}
+	}
+	return CodeOK, false
+}
+
+// SetRetryPolicy : set retry policy of api name, empty name is default, nil policy not retry
+func (n *NodeDetail) SetRetryPolicy(apiname string, config *network.RetryPolicy) error {
+	policy, err := newRetryPolicy(config)
+	if err != nil {
+		return err
+	}
+	if apiname == "" {
+		n.retry = policy
+	} else {
+		n.apiretry.Store(apiname, policy)
+	}
+	return nil
+}
+
+func (n *NodeDetail) retryPolicy(fmsg *pb.FuncMsg) *retryPolicy {
+	if v, ok := n.apiretry.Load(fmsg.ApiName); ok {
+		return v.(*retryPolicy)
+	}
+	return n.retry
+}
+
+// failed request can retry by the policy, connection error was not sent and always try next node
+func (p *retryPolicy) retryable(ctx context.Context, fmsg *pb.FuncMsg, err error, attempts int) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	} else if isConnError(err) {
+		return true
+	} else if p == nil || attempts >= p.MaxAttempts || (fmsg.ApiType == pb.ApiType_Send && !p.RetrySend) {
+		return false
+	}
+	code := ErrorCode(err)
+	return p.codes[code] && (p.Idempotent || code == CodeUnavailable)
+}
+
+// exponential backoff with jitter
+func (p *retryPolicy) backoff(attempts int) time.Duration {
+	var wait = p.Backoff
+	for i := 1; i < attempts && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return time.Duration(float64(wait) * (1 + p.Jitter*(rand.Float64()*2-1)))
+}
+
+func (p *retryPolicy) wait(ctx context.Context, attempts int) error {
+	var timer = time.NewTimer(p.backoff(attempts))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *retryPolicy) tryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p == nil || p.PerTryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, p.PerTryTimeout)
+}
+
+// request the api by connections in load balance order, skip node of open breaker,
+// retry failed request by other node first, return nil connection when all connections were wrong
+func (n *NodeDetail) retryCall(ctx context.Context, conns []*NodeConn, fmsg *pb.FuncMsg, req []byte) ([]byte, *NodeConn, error) {
+	var usable = make([]*NodeConn, 0, len(conns))
+	for _, conn := range n.selectConn(fmsg, conns) {
+		if conn.Uuid != n.Uuid && !conn.wrong {
+			usable = append(usable, conn)
+		}
+	}
+	var policy = n.retryPolicy(fmsg)
+	var tried = make(map[*NodeConn]bool, len(usable))
+	for attempts := 0; ; {
+		var conn *NodeConn
+		for _, row := range usable {
+			if !tried[row] && row.allow() {
+				conn = row
+				break
+			}
+		}
+		if conn == nil {
+			return nil, nil, NewStatus(CodeUnavailable, "call all server node with api, but all wrong")
+		}
+		tried[conn] = true
+
+		tctx, cancel := policy.tryContext(ctx)
+		start := time.Now()
+		body, err := n.invoke(tctx, conn, fmsg, req, conn.invoker(fmsg))
+		n.report(conn, err, time.Since(start))
+		cancel()
+		if !isConnError(err) {
+			attempts++
+		}
+		if !policy.retryable(ctx, fmsg, err, attempts) {
+			return body, conn, err
+		} else if isConnError(err) {
+			continue
+		}
+		if err = policy.wait(ctx, attempts); err != nil {
+			return nil, conn, err
+		}
+		// all nodes were tried, retry from the first again
+		if len(tried) == len(usable) {
+			tried = make(map[*NodeConn]bool, len(usable))
+		}
+	}
+}
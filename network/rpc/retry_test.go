@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"micro/network"
+)
+
+type TstRetryReq struct {
+	Fail int32 `json:"fail"` // failed times before success
+}
+type TstRetryRsp struct {
+	Runs int32 `json:"runs"`
+}
+type TstRetry struct {
+	runs int32
+	down bool // node always unavailable
+}
+
+func (s *TstRetry) Compiler_JSON() {}
+
+func (s *TstRetry) Flaky(req *TstRetryReq, rsp *TstRetryRsp) error {
+	rsp.Runs = atomic.AddInt32(&s.runs, 1)
+	if s.down || rsp.Runs <= req.Fail {
+		return NewStatus(CodeUnavailable, "unavailable")
+	}
+	return nil
+}
+
+func (s *TstRetry) Slow(ctx context.Context, req *TstRetryReq, rsp *TstRetryRsp) error {
+	if rsp.Runs = atomic.AddInt32(&s.runs, 1); rsp.Runs <= req.Fail {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (s *TstRetry) Notify(req *TstRetryReq) error {
+	atomic.AddInt32(&s.runs, 1)
+	return NewStatus(CodeUnavailable, "unavailable")
+}
+
+func (s *TstRetry) take() int32 { return atomic.SwapInt32(&s.runs, 0) }
+
+func TestRetryBackoff(t *testing.T) {
+	policy, err := newRetryPolicy(&network.RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond * 10,
+		MaxBackoff: time.Millisecond * 30, Jitter: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempts, base := range []time.Duration{10, 20, 30, 30, 30} {
+		if wait := policy.backoff(attempts + 1); wait < base*time.Millisecond/2 || wait > base*time.Millisecond*3/2 {
+			t.Fatal("backoff wrong: ", attempts+1, wait)
+		}
+	}
+	if _, err = newRetryPolicy(&network.RetryPolicy{MaxAttempts: 2, RetryOn: []string{"Timeout"}}); err == nil {
+		t.Fatal("retry code name must be checked")
+	}
+	if policy, _ = newRetryPolicy(&network.RetryPolicy{MaxAttempts: 1}); policy != nil {
+		t.Fatal("one attempt was not retry")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var svc = &TstRetry{}
+	client, _ := testNodePair(t, svc)
+
+	// default not retry
+	var rsp = &TstRetryRsp{}
+	if err := client.CallAuto("TstRetry.Flaky", &TstRetryReq{Fail: 1}, rsp).Err(); ErrorCode(err) != CodeUnavailable || svc.take() != 1 {
+		t.Fatal("default must not retry: ", err)
+	}
+
+	client.SetRetryPolicy("", &network.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	if err := client.CallAuto("TstRetry.Flaky", &TstRetryReq{Fail: 2}, rsp).Err(); err != nil || rsp.Runs != 3 || svc.take() != 3 {
+		t.Fatal("retry unavailable wrong: ", rsp.Runs, err)
+	}
+	if err := client.CallAuto("TstRetry.Flaky", &TstRetryReq{Fail: 5}, rsp).Err(); ErrorCode(err) != CodeUnavailable || svc.take() != 3 {
+		t.Fatal("retry over max attempts: ", err)
+	}
+
+	// timeout only retry by idempotent api
+	var slow = &network.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond,
+		PerTryTimeout: time.Millisecond * 50, RetryOn: []string{"Unavailable", "DeadlineExceeded"}}
+	client.SetRetryPolicy("TstRetry.Slow", slow)
+	if err := client.CallAuto("TstRetry.Slow", &TstRetryReq{Fail: 1}, rsp).Err(); ErrorCode(err) != CodeDeadlineExceeded || svc.take() != 1 {
+		t.Fatal("not idempotent api must not retry timeout: ", err)
+	}
+	slow.Idempotent = true
+	client.SetRetryPolicy("TstRetry.Slow", slow)
+	if err := client.CallAuto("TstRetry.Slow", &TstRetryReq{Fail: 1}, rsp).Err(); err != nil || rsp.Runs != 2 || svc.take() != 2 {
+		t.Fatal("idempotent api retry timeout wrong: ", rsp.Runs, err)
+	}
+
+	// send api
+	client.SendAuto("TstRetry.Notify", &TstRetryReq{})
+	if runs := svc.take(); runs != 1 {
+		t.Fatal("send api must not retry: ", runs)
+	}
+	client.SetRetryPolicy("TstRetry.Notify", &network.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, RetrySend: true})
+	client.SendAuto("TstRetry.Notify", &TstRetryReq{})
+	if runs := svc.take(); runs != 3 {
+		t.Fatal("send api retry when allowed: ", runs)
+	}
+}
+
+func TestRetryOtherNode(t *testing.T) {
+	var down, up = &TstRetry{down: true}, &TstRetry{}
+	client, _ := testNodePair(t, down)
+	other, server := testNodePair(t, up)
+	for _, name := range []string{"TstRetry.Flaky", "TstRetry.Slow", "TstRetry.Notify"} {
+		server.fmsg.PutMsg(client.QueryFunc(0, name)) // same function id as client
+	}
+	var nc = other.fmsg.GetNodeConn("S")
+	nc.Uuid = "S2"
+	client.fmsg.PutConn(nc)
+	client.fmsg.UpFuncNode(client.GetFuncID("TstRetry.Flaky"), []string{"S", "S2"})
+	client.SetRetryPolicy("", &network.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond})
+
+	for i := 0; i < 4; i++ {
+		var rsp = &TstRetryRsp{}
+		if err := client.CallAuto("TstRetry.Flaky", &TstRetryReq{}, rsp).Err(); err != nil {
+			t.Fatal("retry by other node wrong: ", err)
+		}
+	}
+	// round robin start from both node, retry was the other node
+	if d, u := down.take(), up.take(); d != 2 || u != 4 {
+		t.Fatal("retry node wrong: ", d, u)
+	}
+}
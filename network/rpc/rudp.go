@@ -0,0 +1,472 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RudpByte     = byte(2) // 可靠 udp 包第二个字节，区分普通分块
+	RudpHeadSize = 11      // FirstByte | RudpByte | 类型 | 消息编号(4) | 块序号(2) | 块数(2)
+
+	rudpData = byte(1) // 数据块，内容为普通 udp 分块
+	rudpAck  = byte(2) // 确认收到数据块，块数位置为连续收到的块数
+	rudpNack = byte(3) // 请求重传缺少的数据块，内容为块序号列表
+
+	RudpResend     = time.Millisecond * 100 // 首次重传等待时间，每次重传翻倍
+	RudpMaxResend  = time.Second            // 最大重传等待时间
+	RudpRetries    = 8                      // 数据块最多重传次数
+	RudpWindow     = 16                     // 初始拥塞窗口，未确认的数据块数量
+	RudpMaxWindow  = 256                    // 最大拥塞窗口
+	RudpNackDelay  = time.Millisecond * 50  // 消息缺少数据块多久后请求重传
+	RudpCheckTimer = time.Millisecond * 10  // 检查重传和超时的间隔
+	RudpMaxRecv    = 32                     // 每个地址同时重组的消息数量，超过时丢弃新消息等待重传
+
+	ReassemblyTimeout = time.Second * 5 // 分块没有全部收到时丢弃的时间
+)
+
+var errRudpClosed = errors.New("reliable udp was closed")
+
+// max packet number of a message, udp body split from the max frame
+var rudpMaxTotal = func() int {
+	if n := (MaxFrameSize + udpsplit.BodySplit - 1) / udpsplit.BodySplit; n < 0xffff {
+		return n
+	}
+	return 0xffff
+}()
+
+// reliable udp of the socket, message is split udp body, every data packet was acknowledged,
+// lost packet was retransmitted by timeout or nack, packet was sent in congestion window of the peer
+// and paced across the round trip time
+type rudp struct {
+	conn *net.UDPConn
+	dial bool // connected socket, write without address
+	// complete message received, call in read goroutine
+	recv func(addr *net.UDPAddr, rows [][]byte)
+	// packet loss shim of test, drop the written packet when return true
+	drop func(data []byte) bool
+	// retransmit config
+	resend  time.Duration
+	retries int
+
+	mut   sync.Mutex
+	seq   uint32
+	out   map[uint32]*rudpSend // sending message by id
+	peers map[string]*rudpPeer // sending state by address
+	in    map[string]*rudpRecv // receiving message by address and id
+	recvs map[string]int       // receiving message number by address
+	done  map[string]int64     // received message, acknowledge again without deliver
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// congestion state of the peer address
+type rudpPeer struct {
+	cwnd     float64 // congestion window
+	inflight int     // data packet sent and not acknowledged
+	cut      int64   // last time window was decreased
+	srtt     int64   // smoothed round trip time, 0 is unknown
+	next     int64   // time of next packet can be sent
+	stamp    int64   // last packet sent or acknowledged time
+}
+
+type rudpSend struct {
+	addr  *net.UDPAddr // nil for connected socket
+	peer  *rudpPeer
+	rows  [][]byte
+	acked []bool
+	sent  []int64 // last send time, 0 is not sent
+	tries []int
+	left  int
+	done  chan error
+}
+
+type rudpRecv struct {
+	addr  *net.UDPAddr
+	id    uint32
+	rows  [][]byte
+	left  int
+	next  int   // sort number before it were all received
+	max   int   // max received sort number
+	stamp int64 // last new packet received time
+	nack  int64 // last nack time
+}
+
+func newRudp(conn *net.UDPConn, recv func(addr *net.UDPAddr, rows [][]byte)) *rudp {
+	var result = &rudp{
+		conn:    conn,
+		dial:    conn.RemoteAddr() != nil,
+		recv:    recv,
+		resend:  RudpResend,
+		retries: RudpRetries,
+		out:     make(map[uint32]*rudpSend),
+		peers:   make(map[string]*rudpPeer),
+		in:      make(map[string]*rudpRecv),
+		recvs:   make(map[string]int),
+		done:    make(map[string]int64),
+		stop:    make(chan struct{}),
+	}
+	go result.loop()
+	return result
+}
+
+// stop retransmit and fail all sending message
+func (r *rudp) close() {
+	r.once.Do(func() {
+		close(r.stop)
+		r.mut.Lock()
+		defer r.mut.Unlock()
+		for id, s := range r.out {
+			r.finish(id, s, errRudpClosed)
+		}
+	})
+}
+
+func (r *rudp) loop() {
+	var t = time.NewTicker(RudpCheckTimer)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-t.C:
+			r.mut.Lock()
+			r.flush(now.UnixNano())
+			r.check(now.UnixNano())
+			r.mut.Unlock()
+		}
+	}
+}
+
+// send message and wait all packet acknowledged, addr is nil for connected socket
+func (r *rudp) send(addr *net.UDPAddr, rows [][]byte) error {
+	if len(rows) == 0 {
+		return nil
+	} else if len(rows) > rudpMaxTotal {
+		return errors.New("udp body was too large")
+	}
+	r.mut.Lock()
+	select {
+	case <-r.stop:
+		r.mut.Unlock()
+		return errRudpClosed
+	default:
+	}
+	var s = r.push(addr, rows)
+	r.flush(time.Now().UnixNano())
+	r.mut.Unlock()
+	return <-s.done
+}
+
+// add sending message to the peer, need lock
+func (r *rudp) push(addr *net.UDPAddr, rows [][]byte) *rudpSend {
+	var p, ok = r.peers[addrKey(addr)]
+	if !ok {
+		p = &rudpPeer{cwnd: RudpWindow}
+		r.peers[addrKey(addr)] = p
+	}
+	var s = &rudpSend{
+		addr:  addr,
+		peer:  p,
+		rows:  rows,
+		acked: make([]bool, len(rows)),
+		sent:  make([]int64, len(rows)),
+		tries: make([]int, len(rows)),
+		left:  len(rows),
+		done:  make(chan error, 1),
+	}
+	r.seq++
+	r.out[r.seq] = s
+	return s
+}
+
+// send new packet in window and retransmit timeout packet when pacing allowed, need lock
+func (r *rudp) flush(now int64) {
+	for id, s := range r.out {
+		var p = s.peer
+		for i := range s.rows {
+			if s.acked[i] {
+				continue
+			} else if s.sent[i] == 0 {
+				if p.inflight >= int(p.cwnd) || p.next > now {
+					break
+				}
+				p.inflight++
+			} else if now-s.sent[i] < int64(r.backoff(s.tries[i])) {
+				continue
+			} else if s.tries[i] >= r.retries {
+				r.finish(id, s, errors.New("udp packet was not acknowledged"))
+				break
+			} else if p.next > now {
+				break
+			} else {
+				s.tries[i]++
+				p.decrease(now, r.resend)
+			}
+			p.pace(now, r.resend)
+			s.sent[i] = now
+			r.write(s.addr, rudpData, id, i, len(s.rows), s.rows[i])
+		}
+	}
+}
+
+func (r *rudp) backoff(tries int) time.Duration {
+	if d := r.resend << uint(tries); d > 0 && d < RudpMaxResend {
+		return d
+	}
+	return RudpMaxResend
+}
+
+// packet was lost, halve congestion window at most once a resend time
+func (p *rudpPeer) decrease(now int64, resend time.Duration) {
+	if now-p.cut < int64(resend) {
+		return
+	}
+	p.cut = now
+	if p.cwnd /= 2; p.cwnd < 2 {
+		p.cwnd = 2
+	}
+}
+
+// packet was sent, next packet wait round trip time divided by window,
+// resend time is used before round trip time known, idle time was not saved to burst
+func (p *rudpPeer) pace(now int64, resend time.Duration) {
+	var rtt = p.srtt
+	if rtt == 0 {
+		rtt = int64(resend)
+	}
+	if p.next < now-int64(RudpCheckTimer) {
+		p.next = now - int64(RudpCheckTimer)
+	}
+	p.next += rtt / int64(p.cwnd)
+	p.stamp = now
+}
+
+// round trip time of the packet not retransmitted
+func (p *rudpPeer) sample(rtt int64) {
+	if p.srtt == 0 {
+		p.srtt = rtt
+	} else {
+		p.srtt += (rtt - p.srtt) / 8
+	}
+}
+
+// remove sending message and notify the sender, need lock
+func (r *rudp) finish(id uint32, s *rudpSend, err error) {
+	for i := range s.rows {
+		if !s.acked[i] && s.sent[i] != 0 {
+			s.peer.inflight--
+		}
+	}
+	delete(r.out, id)
+	s.done <- err
+}
+
+// drop timeout message not all received, request lost packet of received message, need lock
+func (r *rudp) check(now int64) {
+	for key, m := range r.in {
+		if now-m.stamp > int64(ReassemblyTimeout) {
+			r.remove(key, m)
+		} else if now-m.nack > int64(RudpNackDelay) {
+			var lost []byte
+			for i := m.next; i < m.max && len(lost) < udpsplit.TotalSize; i++ {
+				if m.rows[i] == nil {
+					lost = append(lost, byte(i>>8), byte(i))
+				}
+			}
+			if len(lost) > 0 {
+				m.nack = now
+				r.write(m.addr, rudpNack, m.id, 0, len(m.rows), lost)
+			}
+		}
+	}
+	// keep longer than all retransmit of the sender
+	for key, stamp := range r.done {
+		if now-stamp > int64(ReassemblyTimeout*2) {
+			delete(r.done, key)
+		}
+	}
+	for key, p := range r.peers {
+		if p.inflight == 0 && now-p.stamp > int64(ReassemblyTimeout*2) {
+			delete(r.peers, key)
+		}
+	}
+}
+
+// parse reliable packet read from the socket
+func (r *rudp) input(addr *net.UDPAddr, data []byte) {
+	if len(data) < RudpHeadSize || data[0] != FirstByte || data[1] != RudpByte {
+		return
+	}
+	var id = binary.BigEndian.Uint32(data[3:])
+	var sort = int(binary.BigEndian.Uint16(data[7:]))
+	var total = int(binary.BigEndian.Uint16(data[9:]))
+	switch data[2] {
+	case rudpData:
+		r.data(addr, id, sort, total, data[RudpHeadSize:])
+	case rudpAck:
+		r.ack(addr, id, sort, total)
+	case rudpNack:
+		r.nack(addr, id, data[RudpHeadSize:])
+	}
+}
+
+func (r *rudp) data(addr *net.UDPAddr, id uint32, sort, total int, body []byte) {
+	if sort >= total || total > rudpMaxTotal || len(body) != udpsplit.TotalSize {
+		return
+	}
+	var key = rudpKey(addr, id)
+	var now = time.Now().UnixNano()
+	r.mut.Lock()
+	// repeated packet of received message, the ack was lost
+	if _, ok := r.done[key]; ok {
+		r.write(addr, rudpAck, id, sort, total, nil)
+		r.mut.Unlock()
+		return
+	}
+	m, ok := r.in[key]
+	if !ok {
+		// too many message of the address, sender retransmit it later
+		if r.recvs[addrKey(addr)] >= RudpMaxRecv {
+			r.mut.Unlock()
+			return
+		}
+		m = &rudpRecv{addr: addr, id: id, rows: make([][]byte, total), left: total, stamp: now}
+		r.in[key] = m
+		r.recvs[addrKey(addr)]++
+	} else if len(m.rows) != total {
+		r.mut.Unlock()
+		return
+	}
+	if m.rows[sort] == nil {
+		m.rows[sort] = append([]byte(nil), body...)
+		m.left--
+		m.stamp = now
+	}
+	for m.next < total && m.rows[m.next] != nil {
+		m.next++
+	}
+	if sort >= m.max {
+		m.max = sort + 1
+	}
+	var rows [][]byte
+	if m.left == 0 {
+		r.remove(key, m)
+		r.done[key] = now
+		rows = m.rows
+	}
+	r.write(addr, rudpAck, id, sort, m.next, nil)
+	r.mut.Unlock()
+	if rows != nil {
+		r.recv(addr, rows)
+	}
+}
+
+// remove receiving message, need lock
+func (r *rudp) remove(key string, m *rudpRecv) {
+	delete(r.in, key)
+	if ak := addrKey(m.addr); r.recvs[ak] > 1 {
+		r.recvs[ak]--
+	} else {
+		delete(r.recvs, ak)
+	}
+}
+
+// acknowledge the packet and all packet before next, lost ack was covered by next ack
+func (r *rudp) ack(addr *net.UDPAddr, id uint32, sort, next int) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	s, ok := r.out[id]
+	if !ok || !sameAddr(s.addr, addr) {
+		return
+	}
+	var p, now = s.peer, time.Now().UnixNano()
+	for i := range s.rows {
+		if (i != sort && i >= next) || s.acked[i] {
+			continue
+		}
+		s.acked[i] = true
+		s.left--
+		if s.sent[i] != 0 {
+			p.inflight--
+		}
+		// retransmitted packet cannot know which one was acknowledged
+		if i == sort && s.tries[i] == 0 && s.sent[i] > 1 {
+			p.sample(now - s.sent[i])
+		}
+		if p.cwnd += 1 / p.cwnd; p.cwnd > RudpMaxWindow {
+			p.cwnd = RudpMaxWindow
+		}
+	}
+	p.stamp = now
+	if s.left == 0 {
+		r.finish(id, s, nil)
+	}
+	// ack clocked, send new packet when window was released
+	r.flush(now)
+}
+
+func (r *rudp) nack(addr *net.UDPAddr, id uint32, lost []byte) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	s, ok := r.out[id]
+	if !ok || !sameAddr(s.addr, addr) {
+		return
+	}
+	for i := 0; i+1 < len(lost); i += 2 {
+		sort := int(binary.BigEndian.Uint16(lost[i:]))
+		if sort < len(s.rows) && !s.acked[sort] && s.sent[sort] != 0 {
+			s.sent[sort] = 1 // retransmit as timeout
+		}
+	}
+	r.flush(time.Now().UnixNano())
+}
+
+// write reliable packet, need lock
+func (r *rudp) write(addr *net.UDPAddr, kind byte, id uint32, sort, total int, body []byte) {
+	var b = make([]byte, RudpHeadSize, RudpHeadSize+len(body))
+	b[0], b[1], b[2] = FirstByte, RudpByte, kind
+	binary.BigEndian.PutUint32(b[3:], id)
+	binary.BigEndian.PutUint16(b[7:], uint16(sort))
+	binary.BigEndian.PutUint16(b[9:], uint16(total))
+	b = append(b, body...)
+	if r.drop != nil && r.drop(b) {
+		return
+	}
+	if r.dial {
+		r.conn.Write(b)
+	} else {
+		r.conn.WriteToUDP(b, addr)
+	}
+}
+
+func rudpKey(addr *net.UDPAddr, id uint32) string {
+	var key = strconv.FormatUint(uint64(id), 10)
+	if addr == nil {
+		return key
+	}
+	return addr.String() + "/" + key
+}
+
+func addrKey(addr *net.UDPAddr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func sameAddr(a, b *net.UDPAddr) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
+
+// reliable packet of udp socket
+func isRudpPacket(data []byte) bool {
+	return len(data) >= RudpHeadSize && data[0] == FirstByte && data[1] == RudpByte
+}
@@ -0,0 +1,279 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// drop every n packet written by the reliable udp
+func testRudpLoss(r *rudp, n int) {
+	var count int
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.drop = func(data []byte) bool {
+		count++
+		return n > 0 && count%n == 0
+	}
+}
+
+func testRudpRead(r *rudp) {
+	var buf = make([]byte, RudpHeadSize+udpsplit.TotalSize)
+	for {
+		num, addr, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			r.close()
+			return
+		}
+		r.input(addr, buf[:num])
+	}
+}
+
+func testRudpPair(t *testing.T) (*rudp, *rudp, chan [][]byte) {
+	lconn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lconn.Close() })
+	var recv = make(chan [][]byte, 10)
+	var server = newRudp(lconn, func(addr *net.UDPAddr, rows [][]byte) { recv <- rows })
+	go testRudpRead(server)
+
+	cconn, err := net.DialUDP("udp", nil, lconn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cconn.Close() })
+	var client = newRudp(cconn, func(addr *net.UDPAddr, rows [][]byte) {})
+	go testRudpRead(client)
+	return client, server, recv
+}
+
+func TestRudp(t *testing.T) {
+	client, server, recv := testRudpPair(t)
+	// lost data packet, ack and nack
+	testRudpLoss(client, 4)
+	testRudpLoss(server, 3)
+
+	var rows = make([][]byte, 100)
+	for i := range rows {
+		rows[i] = bytes.Repeat([]byte{byte(i)}, udpsplit.TotalSize)
+	}
+	if err := client.send(nil, rows); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case result := <-recv:
+		for i := range rows {
+			if !bytes.Equal(result[i], rows[i]) {
+				t.Fatal("received packet wrong: ", i)
+			}
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("message was not received")
+	}
+	select {
+	case <-recv:
+		t.Fatal("repeated packet must not be received again")
+	case <-time.After(time.Millisecond * 300):
+	}
+	client.mut.Lock()
+	if p := client.peers[""]; p.inflight != 0 || len(client.out) != 0 || p.cwnd < 2 || p.srtt == 0 {
+		t.Fatal("sending state wrong: ", p.inflight, len(client.out), p.cwnd, p.srtt)
+	}
+	// all packet lost
+	client.resend, client.retries = time.Millisecond*5, 2
+	client.mut.Unlock()
+	testRudpLoss(client, 1)
+	if err := client.send(nil, rows[:3]); err == nil {
+		t.Fatal("lost message must be failed")
+	}
+	client.close()
+	if err := client.send(nil, rows[:1]); err != errRudpClosed {
+		t.Fatal("closed reliable udp must be failed: ", err)
+	}
+
+	// message not all received was dropped
+	server.data(nil, 99, 0, 3, rows[0])
+	server.mut.Lock()
+	defer server.mut.Unlock()
+	if len(server.in) != 1 {
+		t.Fatal("message must be receiving")
+	}
+	server.check(time.Now().Add(ReassemblyTimeout + time.Second).UnixNano())
+	if len(server.in) != 0 {
+		t.Fatal("timeout message must be dropped")
+	}
+}
+
+func TestRudpPeer(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var r = newRudp(conn, func(addr *net.UDPAddr, rows [][]byte) {})
+	// stop the timer, flush by test
+	r.once.Do(func() { close(r.stop) })
+	var sent = make(map[uint32]int)
+	r.drop = func(data []byte) bool {
+		if data[2] == rudpData {
+			sent[binary.BigEndian.Uint32(data[3:])]++
+		}
+		return true
+	}
+	var rows = make([][]byte, 20)
+	var a = &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1}
+	var b = &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	var sa, sb = r.push(a, rows), r.push(b, rows)
+	var now = time.Now().UnixNano()
+	// packet was paced by resend time before round trip time known
+	r.flush(now)
+	if sent[1] != 2 || sent[2] != 2 {
+		t.Fatal("packet must be paced: ", sent)
+	}
+	for i := 1; i < 10; i++ {
+		r.flush(now + int64(RudpCheckTimer)*int64(i))
+	}
+	if sent[1] != RudpWindow || sent[2] != RudpWindow {
+		t.Fatal("packet must be limited by window: ", sent)
+	}
+
+	// acknowledged by round trip time, packet lost of one peer
+	r.mut.Unlock()
+	r.ack(a, 1, 0, 1)
+	r.mut.Lock()
+	if sa.peer.srtt == 0 || sa.peer.inflight != RudpWindow-1 {
+		t.Fatal("round trip time must be sampled: ", sa.peer.srtt, sa.peer.inflight)
+	}
+	sa.sent[1] = 1
+	for i := range sb.sent[:RudpWindow] {
+		sb.sent[i] = now + int64(time.Second)
+	}
+	r.flush(now + int64(time.Second))
+	if sa.peer.cwnd >= RudpWindow || sb.peer.cwnd < RudpWindow || sa.peer == sb.peer {
+		t.Fatal("window of peer must be separated: ", sa.peer.cwnd, sb.peer.cwnd)
+	}
+	r.finish(1, sa, nil)
+	r.finish(2, sb, nil)
+	if sa.peer.inflight != 0 || sb.peer.inflight != 0 {
+		t.Fatal("finished message must release window")
+	}
+	r.check(now + int64(ReassemblyTimeout*3))
+	if len(r.peers) != 0 {
+		t.Fatal("idle peer must be removed")
+	}
+}
+
+func TestRudpLimit(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var r = newRudp(conn, func(addr *net.UDPAddr, rows [][]byte) {})
+	t.Cleanup(r.close)
+	var row = make([]byte, udpsplit.TotalSize)
+	var a = &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1}
+	var b = &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2}
+
+	// packet number more than split from the max frame
+	r.data(a, 1, 0, rudpMaxTotal+1, row)
+	if err := r.send(a, make([][]byte, rudpMaxTotal+1)); err == nil {
+		t.Fatal("too large message must be failed")
+	}
+	r.mut.Lock()
+	if len(r.in) != 0 {
+		t.Fatal("too large message must be dropped")
+	}
+	r.mut.Unlock()
+
+	for i := 0; i < RudpMaxRecv+1; i++ {
+		r.data(a, uint32(i), 0, 2, row)
+	}
+	r.data(b, 0, 0, 2, row)
+	r.mut.Lock()
+	if len(r.in) != RudpMaxRecv+1 || r.recvs[addrKey(a)] != RudpMaxRecv {
+		t.Fatal("receiving message of address must be limited: ", len(r.in))
+	}
+	r.mut.Unlock()
+	// message completed or timeout release the limit
+	r.data(a, 0, 1, 2, row)
+	r.data(a, uint32(RudpMaxRecv), 0, 2, row)
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if _, ok := r.in[rudpKey(a, uint32(RudpMaxRecv))]; !ok {
+		t.Fatal("completed message must release the limit")
+	}
+	r.check(time.Now().Add(ReassemblyTimeout + time.Second).UnixNano())
+	if len(r.in) != 0 || len(r.recvs) != 0 {
+		t.Fatal("timeout message must release the limit: ", r.recvs)
+	}
+}
+
+func TestRudpCall(t *testing.T) {
+	var server = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}, funcs: make(map[string]*Server),
+		hlist: make(map[string]func(http.ResponseWriter, *http.Request))}
+	if err := server.Register(&TstZip{}); err != nil {
+		t.Fatal(err)
+	}
+	server.Uuid = "S"
+	if err := server.UdpListen(0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.lis.udp.Close() })
+	testRudpLoss(server.lis.rudp, 5)
+
+	uconn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"),
+		Port: server.lis.udp.LocalAddr().(*net.UDPAddr).Port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nc = &NodeConn{uconn: uconn, types: ConnWithUDP, meta: true,
+		rc: make(map[int]*RecvChan), list: make(map[int]*ReadLink)}
+	nc.Uuid = "S"
+	nc.rudp = newRudp(uconn, nc.recvRudp)
+	testRudpLoss(nc.rudp, 4)
+	go nc.ReadRespUDP()
+	t.Cleanup(nc.Close)
+	var client = testNodeMap(t, server, nc)
+
+	var text strings.Builder
+	for i := 0; i < 10000; i++ {
+		text.WriteString(strconv.Itoa(i) + ",")
+	}
+	for _, req := range []string{text.String(), "small"} {
+		var rsp = &TstZipMsg{}
+		if err := client.CallAuto("TstZip.Echo", &TstZipMsg{Text: req}, rsp).Err(); err != nil {
+			t.Fatal(err)
+		} else if rsp.Text != req {
+			t.Fatal("response body wrong")
+		}
+	}
+}
+
+func TestReassemblyTimeout(t *testing.T) {
+	var nc = &NodeConn{list: make(map[int]*ReadLink)}
+	var rows = udpsplit.MakeReqBody(bytes.Repeat([]byte("lost"), udpsplit.BodySplit), 7, 200)
+	if len(rows) < 2 {
+		t.Fatal("request body must be split")
+	}
+	if body, _, _ := nc.parseBody(udpsplit, &ConnBody{Data: rows[0]}); body != nil || len(nc.list) != 1 {
+		t.Fatal("body must be receiving")
+	}
+	nc.list[7].stamp -= ReassemblyTimeout.Milliseconds() + 1
+	nc.sweep = 0
+	rows = udpsplit.MakeReqBody(bytes.Repeat([]byte("next"), udpsplit.BodySplit), 8, 200)
+	nc.parseBody(udpsplit, &ConnBody{Data: rows[0]})
+	if _, ok := nc.list[7]; ok || len(nc.list) != 1 {
+		t.Fatal("timeout body must be dropped")
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -10,7 +11,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+var (
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
 
 type Server struct {
 	rt    reflect.Type
@@ -27,6 +31,7 @@ type ServerFunc struct {
 	req    reflect.Type   // request data type
 	rsp    reflect.Type   // response data type
 	args   []reflect.Type // multi request data types
+	ctx    bool           // first arg is context.Context
 }
 
 // Register server struct with function call
@@ -58,56 +63,59 @@ func (n *NodeDetail) Register(argv interface{}) error {
 	return nil
 }
 
-func (n *NodeDetail) findCall(fmsg *pb.FuncMsg, bts []byte) ([]byte, error) {
+// call server api by request body and metadata, return response metadata
+func (n *NodeDetail) findCall(ctx context.Context, nc *NodeConn, fmsg *pb.FuncMsg, bts []byte, md Metadata) ([]byte, Metadata, error) {
 	s, ok := n.funcs[fmsg.ServName]
 	if !ok {
-		return nil, fmt.Errorf("not found server: %s by local", fmsg.ServName)
-	}
-	if f, ok := s.funcs[fmsg.FuncName]; ok {
-		switch f.api {
-		case pb.ApiType_Send:
-			if req, err := UnmarshalValue(s.proto, f.req, bts); err != nil {
-				return nil, err
-			} else {
-				return nil, f.ValueSend(s.rv, req)
-			}
+		return nil, nil, Errorf(CodeNotFound, "not found server: %s by local", fmsg.ServName)
+	}
+	f, ok := s.funcs[fmsg.FuncName]
+	if !ok {
+		return nil, nil, NewStatus(CodeNotFound, "not found server function: "+fmsg.FuncName)
+	}
 
-		case pb.ApiType_Call:
-			rsp := reflect.New(f.rsp.Elem())
-			if req, err := UnmarshalValue(s.proto, f.req, bts); err != nil {
-				return nil, err
-			} else if err = f.ValueCall(s.rv, req, rsp); err != nil {
-				return nil, err
-			} else {
-				return MarshalValue(s.proto, rsp)
-			}
+	var req interface{}
+	switch f.api {
+	case pb.ApiType_Send, pb.ApiType_Call:
+		v, err := UnmarshalValue(s.proto, f.req, bts)
+		if err != nil {
+			return nil, nil, NewStatus(CodeInvalidArgument, err.Error())
+		}
+		req = v.Interface()
 
-		case pb.ApiType_Multi:
-			var req = &pb.MultiBody{}
-			if err := proto.Unmarshal(bts, req); err != nil {
-				return nil, err
-			} else if req.Count != uint32(len(f.args)) {
-				return nil, errors.New("request args number were wrong")
-			} else {
-				var reqdata = []reflect.Value{s.rv}
-				for i, arg := range f.args {
-					if v, err := UnmarshalValue(s.proto, arg, req.Data[i]); err != nil {
-						return nil, err
-					} else {
-						reqdata = append(reqdata, v)
-					}
-				}
-				rsp := reflect.New(f.rsp.Elem())
-				reqdata = append(reqdata, rsp)
-				if err = f.ValueMulti(reqdata); err == nil {
-					return MarshalValue(s.proto, rsp)
-				} else {
-					return nil, err
-				}
+	case pb.ApiType_Multi:
+		var body = &pb.MultiBody{}
+		if err := proto.Unmarshal(bts, body); err != nil {
+			return nil, nil, NewStatus(CodeInvalidArgument, err.Error())
+		} else if body.Count != uint32(len(f.args)) || len(body.Data) != len(f.args) {
+			return nil, nil, NewStatus(CodeInvalidArgument, "request args number were wrong")
+		}
+		var args = make([]interface{}, 0, len(f.args))
+		for i, arg := range f.args {
+			v, err := UnmarshalValue(s.proto, arg, body.Data[i])
+			if err != nil {
+				return nil, nil, NewStatus(CodeInvalidArgument, err.Error())
 			}
+			args = append(args, v.Interface())
 		}
+		req = args
+
+	case pb.ApiType_Stream:
+		return nil, nil, NewStatus(CodeInvalidArgument, "stream api need to request by CallStream")
+	}
+
+	var node, caller = callerNode(nc.peerInfo(), md), nc.identity()
+	if err := n.authorize(fmsg.ApiName, caller); err != nil {
+		return nil, nil, err
+	}
+	var info = &ServerInfo{Func: fmsg, Node: node, Network: nc.network(), Cert: nc.cert}
+	ctx, rm := newIncomingContext(withPeer(withIdentity(ctx, caller), nc.registered()), node, md)
+	rsp, err := n.runUnary(ctx, info, req, f.handler(s.rv, nil))
+	if err != nil || rsp == nil {
+		return nil, rm.get(), err
 	}
-	return nil, errors.New("not found server function: " + fmsg.FuncName)
+	bts, err = MarshalInterface(s.proto, rsp)
+	return bts, rm.get(), err
 }
 
 // parse function method type
@@ -204,32 +212,41 @@ func (server *ServerFunc) ValueMulti(args []reflect.Value) error {
 	return nil
 }
 
-// parse function in value with request and response interfase
+// parse function in value with request and response interfase,
+// context.Context can be the first arg
 func (s *ServerFunc) parseMethodsNumIn() error {
 	method := s.method.Type
 
+	var in = 1
+	if method.NumIn() > 1 && method.In(1) == typeOfContext {
+		s.ctx, in = true, 2
+	}
 	// Check function run in values
-	switch method.NumIn() {
-	case 0, 1:
+	switch method.NumIn() - in {
+	case -1, 0:
 		return fmt.Errorf("method NumIn: %d parse was wrong", method.NumIn())
-	case 2:
-		s.req, s.api = method.In(1), pb.ApiType_Send
+	case 1:
+		s.req, s.api = method.In(in), pb.ApiType_Send
 		if s.req.Kind() != reflect.Ptr && s.req.Kind() != reflect.Interface {
 			return fmt.Errorf("method request in Kind: %v not ptr or interface", s.req.Kind())
 		}
-	case 3:
-		s.req, s.rsp, s.api = method.In(1), method.In(2), pb.ApiType_Call
+	case 2:
+		s.req, s.rsp, s.api = method.In(in), method.In(in+1), pb.ApiType_Call
 		if s.req.Kind() != reflect.Ptr && s.req.Kind() != reflect.Interface {
 			return fmt.Errorf("method request in Kind: %v not ptr or interface", s.req.Kind())
 		}
+		if s.rsp == typeOfStream {
+			s.rsp, s.api = nil, pb.ApiType_Stream
+			break
+		}
 		if s.rsp.Kind() != reflect.Ptr && s.rsp.Kind() != reflect.Interface {
 			return fmt.Errorf("method response in Kind: %v not ptr or interface", s.rsp.Kind())
 		}
 	default:
-		for i := 1; i < method.NumIn()-1; i++ {
+		for i := in; i < method.NumIn()-1; i++ {
 			arg := method.In(i)
 			if arg.Kind() != reflect.Ptr && arg.Kind() != reflect.Interface {
-				return fmt.Errorf("method request in Kind: %v not ptr or interface", s.req.Kind())
+				return fmt.Errorf("method request in Kind: %v not ptr or interface", arg.Kind())
 			}
 			s.args = append(s.args, arg)
 		}
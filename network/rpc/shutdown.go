@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"micro/network/comm"
+)
+
+// running server requests, reject new request after shutdown
+type drain struct {
+	mut    sync.Mutex
+	closed bool
+	active int
+	idle   chan struct{} // closed when no running request after shutdown
+}
+
+// listeners of the node to close when shutdown
+type listeners struct {
+	shut int32 // node was shutting down
+	tcp  *net.TCPListener
+	udp  *net.UDPConn
+	rudp *rudp // reliable udp of the udp listener
+	http *http.Server
+	// accepted tcp connection, map[*NodeConn]bool
+	conns sync.Map
+}
+
+func errShutdown() error {
+	return NewStatus(CodeUnavailable, "server node was shutting down")
+}
+
+// server request start, response must be sent before done
+func (d *drain) add() {
+	d.mut.Lock()
+	d.active++
+	d.mut.Unlock()
+}
+
+func (d *drain) done() {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if d.active--; d.closed && d.active == 0 {
+		d.signal()
+	}
+}
+
+// new request was rejected when closing
+func (d *drain) closing() bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.closed
+}
+
+// stop new request, wait running request by the channel
+func (d *drain) close() <-chan struct{} {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if !d.closed {
+		d.closed, d.idle = true, make(chan struct{})
+		if d.active == 0 {
+			d.signal()
+		}
+	}
+	return d.idle
+}
+
+// need lock
+func (d *drain) signal() {
+	select {
+	case <-d.idle:
+	default:
+		close(d.idle)
+	}
+}
+
+// Shutdown : deregister from watcher, reject new request with Unavailable,
+// wait running request until ctx done, then close listeners, connections and timer
+func (n *NodeDetail) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&n.lis.shut, 0, 1) {
+		return nil
+	}
+	if n.Name != comm.WatchNodeName {
+		if err := n.wser.Deregister(ctx); err != nil {
+			log.Println("Shutdown deregister: ", err)
+		}
+	}
+
+	var err error
+	select {
+	case <-n.drain.close():
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if n.lis.tcp != nil {
+		n.lis.tcp.Close()
+	}
+	if n.lis.udp != nil {
+		n.lis.udp.Close()
+	}
+	if n.lis.http != nil {
+		// running http request was waited by drain
+		n.lis.http.Close()
+	}
+	// cancel running stream and server api of accepted connection
+	n.lis.conns.Range(func(key, value interface{}) bool {
+		key.(*NodeConn).tconn.Close()
+		return true
+	})
+	n.fmsg.RangeConn(func(conn *NodeConn) bool {
+		conn.Close()
+		return true
+	})
+	n.wser.close()
+	if n.ticker != nil {
+		n.ticker.CloseAndExit()
+	}
+	return err
+}
+
+func (n *NodeDetail) isShutdown() bool { return atomic.LoadInt32(&n.lis.shut) == 1 }
+
+// SignalExit : shutdown node by SIGINT or SIGTERM and exit process,
+// running request was waited in timeout
+func (n *NodeDetail) SignalExit(timeout time.Duration) {
+	var c = make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		log.Println("Signal: ", <-c)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := n.Shutdown(ctx); err != nil {
+			log.Println("Shutdown: ", err)
+		}
+		os.Exit(0)
+	}()
+}
@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type TstShutdownReq struct {
+	Sleep int `json:"sleep"` // milliseconds
+}
+type TstShutdownRsp struct {
+	Done bool `json:"done"`
+}
+type TstShutdown struct {
+	start chan struct{}
+}
+
+func (s *TstShutdown) Compiler_JSON() {}
+
+func (s *TstShutdown) Slow(req *TstShutdownReq, rsp *TstShutdownRsp) error {
+	s.start <- struct{}{}
+	time.Sleep(time.Duration(req.Sleep) * time.Millisecond)
+	rsp.Done = true
+	return nil
+}
+
+// call slow api and wait it running
+func testShutdownCall(client *NodeDetail, svc *TstShutdown, sleep int) (*TstShutdownRsp, chan error) {
+	var rsp = &TstShutdownRsp{}
+	var result = make(chan error, 1)
+	go func() {
+		result <- client.CallAuto("TstShutdown.Slow", &TstShutdownReq{Sleep: sleep}, rsp).Err()
+	}()
+	<-svc.start
+	return rsp, result
+}
+
+func TestShutdown(t *testing.T) {
+	var svc = &TstShutdown{start: make(chan struct{}, 10)}
+	client, server := testNodePair(t, svc)
+	port, err := GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Tport = port
+	if err = server.TcpListen(int(port)); err != nil {
+		t.Fatal(err)
+	}
+
+	// running request was waited
+	rsp, result := testShutdownCall(client, svc, 200)
+	var done = make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second*3)
+		defer cancel()
+		done <- server.Shutdown(ctx)
+	}()
+	for i := 0; i < 100 && !server.drain.closing(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if err = client.CallAuto("TstShutdown.Slow", &TstShutdownReq{}, &TstShutdownRsp{}).Err(); ErrorCode(err) != CodeUnavailable {
+		t.Fatal("new request must be rejected: ", err)
+	}
+	if err = <-result; err != nil || !rsp.Done {
+		t.Fatal("running request must be finished: ", err)
+	}
+	if err = <-done; err != nil {
+		t.Fatal("shutdown wrong: ", err)
+	}
+	if TcpDialTest("127.0.0.1", int(port)) == nil {
+		t.Fatal("tcp listener must be closed")
+	}
+	if server.Shutdown(context.TODO()) != nil {
+		t.Fatal("shutdown again must be ignored")
+	}
+
+	// shutdown timeout, close connection of running request
+	client, server = testNodePair(t, svc)
+	_, result = testShutdownCall(client, svc, 1000)
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond*100)
+	defer cancel()
+	if err = server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatal("shutdown must be timeout: ", err)
+	}
+	if err = <-result; err == nil {
+		t.Fatal("request of closed connection must be failed")
+	}
+}
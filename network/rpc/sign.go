@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"micro/common"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// signed message older than the time was rejected as replay, clock of nodes must be synchronized
+const SignMaxAge = time.Minute * 5
+
+// rsa keys to sign and verify node register message
+type signer struct {
+	key  *rsa.PrivateKey // sign register message of this node, nil when not sign
+	keys []*signKey      // allowed to register, nil when not check
+
+	mut   sync.Mutex
+	seen  map[string]int64 // nonce of verified message, value is expired time
+	sweep int64            // last time to clear expired nonce
+}
+
+// public key and node names it can sign
+type signKey struct {
+	key   *rsa.PublicKey
+	names map[string]bool
+}
+
+// keyFiles: public key or certificate file to node names signed by it
+func newSigner(keyFile string, keyFiles map[string][]string) (*signer, error) {
+	var result = &signer{}
+	if keyFile != "" {
+		bts, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		} else if result.key, err = common.ParsePrivateKey(bts); err != nil {
+			return nil, err
+		}
+	}
+	for file, names := range keyFiles {
+		if len(names) == 0 {
+			return nil, errors.New("sign key without node name: " + file)
+		}
+		bts, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := common.ParsePublicKey(bts)
+		if err != nil {
+			return nil, err
+		}
+		var row = &signKey{key: key, names: make(map[string]bool)}
+		for _, name := range names {
+			row.names[name] = true
+		}
+		result.keys = append(result.keys, row)
+	}
+	return result, nil
+}
+
+// node message without signature, deterministic to verify
+func signData(node *pb.NodeInfo) ([]byte, error) {
+	var tmp = proto.Clone(node).(*pb.NodeInfo)
+	tmp.Sign = nil
+	return proto.MarshalOptions{Deterministic: true}.Marshal(tmp)
+}
+
+// marshal node message with signature
+func (s *signer) marshal(node *pb.NodeInfo) ([]byte, error) {
+	if s == nil || s.key == nil {
+		return proto.Marshal(node)
+	}
+	var tmp = proto.Clone(node).(*pb.NodeInfo)
+	tmp.SignTime, tmp.Nonce = time.Now().UnixMilli(), make([]byte, 16)
+	if _, err := rand.Read(tmp.Nonce); err != nil {
+		return nil, err
+	}
+	bts, err := signData(tmp)
+	if err != nil {
+		return nil, err
+	} else if tmp.Sign, err = common.SignRSAData(bts, s.key); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(tmp)
+}
+
+// node message was checked by allowed keys
+func (s *signer) enabled() bool { return s != nil && len(s.keys) > 0 }
+
+// node message must be signed by one of allowed keys, and the key can sign the node name
+func (s *signer) verify(node *pb.NodeInfo) error {
+	if s == nil || len(s.keys) == 0 {
+		return nil
+	} else if len(node.Sign) == 0 {
+		return NewStatus(CodeUnauthenticated, "node register message was not signed: "+node.Uuid)
+	}
+	bts, err := signData(node)
+	if err != nil {
+		return NewStatus(CodeInvalidArgument, err.Error())
+	}
+	for _, row := range s.keys {
+		if common.VerifySignRSA(bts, node.Sign, row.key) != nil {
+			continue
+		} else if !row.names[node.Name] {
+			return NewStatus(CodeUnauthenticated, "node name "+node.Name+" was not allowed by the sign key: "+node.Uuid)
+		}
+		return s.replay(node)
+	}
+	return NewStatus(CodeUnauthenticated, "node register signature was not allowed: "+node.Uuid)
+}
+
+// signed message must be fresh and the nonce was not used
+func (s *signer) replay(node *pb.NodeInfo) error {
+	var now = time.Now().UnixMilli()
+	var age = SignMaxAge.Milliseconds()
+	if node.SignTime < now-age || node.SignTime > now+age {
+		return NewStatus(CodeUnauthenticated, "node register message was expired: "+node.Uuid)
+	} else if len(node.Nonce) == 0 {
+		return NewStatus(CodeUnauthenticated, "node register message without nonce: "+node.Uuid)
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[string]int64)
+	}
+	if now-s.sweep > time.Second.Milliseconds() {
+		s.sweep = now
+		for key, exp := range s.seen {
+			if exp < now {
+				delete(s.seen, key)
+			}
+		}
+	}
+	var key = string(node.Nonce)
+	if _, ok := s.seen[key]; ok {
+		return NewStatus(CodeUnauthenticated, "node register message was replayed: "+node.Uuid)
+	}
+	s.seen[key] = node.SignTime + age
+	return nil
+}
+
+// VerifyNode : check signature of node register message by allowed public keys,
+// always success when no key configured
+func (n *NodeDetail) VerifyNode(node *pb.NodeInfo) error { return n.sign.verify(node) }
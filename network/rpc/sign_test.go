@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"micro/common"
+	"micro/network/comm"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// write rsa private key and public key pem file
+func testSignKey(t *testing.T, dir, name string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prifile, pubfile = filepath.Join(dir, name+".key"), filepath.Join(dir, name+".pub")
+	if err = ioutil.WriteFile(prifile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = ioutil.WriteFile(pubfile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY",
+		Bytes: pub}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return prifile, pubfile
+}
+
+func TestSignNode(t *testing.T) {
+	var dir = t.TempDir()
+	prifile, pubfile := testSignKey(t, dir, "node")
+	otherfile, _ := testSignKey(t, dir, "other")
+
+	node, err := newSigner(prifile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newSigner(otherfile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := newSigner("", map[string][]string{pubfile: {"Billing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info = &pb.NodeInfo{Uuid: "S", Name: "Billing", Host: "127.0.0.1", Tport: 9000,
+		Funcs: []*pb.FuncApi{{Name: "Billing.Charge", Kind: pb.Compiler_JSON}}}
+
+	var parse = func(s *signer, node *pb.NodeInfo) *pb.NodeInfo {
+		bts, err := s.marshal(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result = &pb.NodeInfo{}
+		if err = proto.Unmarshal(bts, result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	var signed = parse(node, info)
+	if len(info.Sign) != 0 {
+		t.Fatal("node message must not be changed by sign")
+	} else if err = watcher.verify(signed); err != nil {
+		t.Fatal("signed node must be allowed: ", err)
+	} else if (*signer)(nil).verify(&pb.NodeInfo{}) != nil {
+		t.Fatal("node must be allowed without key")
+	}
+
+	var tampered = proto.Clone(signed).(*pb.NodeInfo)
+	tampered.Funcs = append(tampered.Funcs, &pb.FuncApi{Name: "Billing.Refund"})
+	// the key cannot sign other node name
+	var spoof = proto.Clone(info).(*pb.NodeInfo)
+	spoof.Name = "Gateway"
+	for _, row := range []*pb.NodeInfo{parse(nil, info), parse(other, info), tampered, parse(node, spoof)} {
+		if err = watcher.verify(row); ErrorCode(err) != CodeUnauthenticated {
+			t.Fatal("node must be refused: ", err)
+		}
+	}
+
+	// replayed or old message was refused
+	if err = watcher.verify(signed); ErrorCode(err) != CodeUnauthenticated {
+		t.Fatal("replayed message must be refused: ", err)
+	}
+	var old = proto.Clone(info).(*pb.NodeInfo)
+	old.SignTime, old.Nonce = time.Now().Add(-SignMaxAge-time.Minute).UnixMilli(), []byte("old")
+	bts, _ := signData(old)
+	if old.Sign, err = common.SignRSAData(bts, node.key); err != nil {
+		t.Fatal(err)
+	} else if err = watcher.verify(old); ErrorCode(err) != CodeUnauthenticated {
+		t.Fatal("old message must be refused: ", err)
+	}
+
+	// dial register of unsigned node was refused
+	var server = &NodeDetail{fmsg: &funcmap{}, sign: watcher}
+	bts, _ = (*signer)(nil).marshal(info)
+	if _, err = server.builtin(comm.DialRegister, &NodeConn{}, bts); ErrorCode(err) != CodeUnauthenticated {
+		t.Fatal("dial register must be refused: ", err)
+	} else if server.fmsg.GetNodeConn("S") != nil {
+		t.Fatal("refused node must not be connected")
+	}
+	// dial register message was signed every time, replayed message was refused
+	var dial = &NodeDetail{sign: node}
+	dial.tmps.node = &pb.NodeInfo{Uuid: "D", Name: "Billing", Host: "127.0.0.1", Tport: 1}
+	first, _ := dial.dialRegister()
+	second, _ := dial.dialRegister()
+	for _, row := range [][]byte{first, second} {
+		if _, err = server.builtin(comm.DialRegister, &NodeConn{}, row); err != nil {
+			t.Fatal("dial register must be allowed: ", err)
+		}
+	}
+	if _, err = server.builtin(comm.DialRegister, &NodeConn{}, first); ErrorCode(err) != CodeUnauthenticated {
+		t.Fatal("replayed dial register must be refused: ", err)
+	}
+}
@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// status code of rpc error, same number as grpc
+type Code uint32
+
+const (
+	CodeOK                Code = 0
+	CodeCanceled          Code = 1  // caller cancelled the request
+	CodeUnknown           Code = 2  // error returned by server api
+	CodeInvalidArgument   Code = 3  // request body was wrong
+	CodeDeadlineExceeded  Code = 4  // caller deadline was passed
+	CodeNotFound          Code = 5  // server api or node not found
+	CodePermissionDenied  Code = 7  // caller was not allowed
+	CodeResourceExhausted Code = 8  // server was overloaded
+	CodeInternal          Code = 13 // server or client internal error
+	CodeUnavailable       Code = 14 // no node or connection can be used, can retry
+	CodeUnauthenticated   Code = 16 // caller identity was wrong
+)
+
+var codeNames = map[Code]string{
+	CodeOK:                "OK",
+	CodeCanceled:          "Canceled",
+	CodeUnknown:           "Unknown",
+	CodeInvalidArgument:   "InvalidArgument",
+	CodeDeadlineExceeded:  "DeadlineExceeded",
+	CodeNotFound:          "NotFound",
+	CodePermissionDenied:  "PermissionDenied",
+	CodeResourceExhausted: "ResourceExhausted",
+	CodeInternal:          "Internal",
+	CodeUnavailable:       "Unavailable",
+	CodeUnauthenticated:   "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return "Code(" + strconv.Itoa(int(c)) + ")"
+}
+
+const (
+	HttpStatusCode   = "Rpc-Status"  // http response header of status code
+	HttpDetailPrefix = "Rpc-Detail-" // http response header of status details
+)
+
+// rpc error with code, send to caller by tcp, udp and http,
+// error message was the same as before for old node
+type Status struct {
+	Code    Code
+	Message string
+	Details Metadata // optional details, key was lower case
+}
+
+func NewStatus(code Code, msg string) *Status {
+	return &Status{Code: code, Message: msg}
+}
+
+// make status error, server api can return it to caller
+func Errorf(code Code, format string, args ...interface{}) error {
+	return NewStatus(code, fmt.Sprintf(format, args...))
+}
+
+func (s *Status) Error() string { return s.Message }
+
+// add details by key value pairs
+func (s *Status) WithDetails(kv ...string) *Status {
+	var result = &Status{Code: s.Code, Message: s.Message, Details: NewMetadata(kv...)}
+	for k, v := range s.Details {
+		if _, ok := result.Details[k]; !ok {
+			result.Details[k] = v
+		}
+	}
+	return result
+}
+
+// status of error, connection and context error was converted to its code,
+// other error was CodeUnknown, return false when error was not *Status
+func FromError(err error) (*Status, bool) {
+	var s *Status
+	if err == nil {
+		return nil, true
+	} else if errors.As(err, &s) {
+		return s, true
+	} else if isConnError(err) {
+		return NewStatus(CodeUnavailable, err.Error()), false
+	} else if errors.Is(err, context.Canceled) {
+		return NewStatus(CodeCanceled, err.Error()), false
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return NewStatus(CodeDeadlineExceeded, err.Error()), false
+	}
+	return NewStatus(CodeUnknown, err.Error()), false
+}
+
+// status code of error, CodeOK when error was nil
+func ErrorCode(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+	s, _ := FromError(err)
+	return s.Code
+}
+
+// uvarint(code) | uvarint(message length) | message | details
+func (s *Status) encode() []byte {
+	var result = appendUvarint(nil, uint64(s.Code))
+	result = appendUvarint(result, uint64(len(s.Message)))
+	result = append(result, s.Message...)
+	if len(s.Details) > 0 {
+		result = append(result, s.Details.encode()...)
+	}
+	return result
+}
+
+func decodeStatus(bts []byte) (*Status, error) {
+	code, n1 := binary.Uvarint(bts)
+	if n1 <= 0 {
+		return nil, errors.New("status code was wrong")
+	}
+	size, n2 := binary.Uvarint(bts[n1:])
+	if n2 <= 0 || uint64(len(bts)-n1-n2) < size {
+		return nil, errors.New("status message was wrong")
+	}
+	var result = &Status{Code: Code(code), Message: string(bts[n1+n2 : n1+n2+int(size)])}
+	if bts = bts[n1+n2+int(size):]; len(bts) > 0 {
+		md, err := decodeMeta(bts)
+		if err != nil {
+			return nil, err
+		}
+		result.Details = md
+	}
+	return result, nil
+}
+
+// remote error of response body, raw message when cannot decode
+func statusError(bts []byte) error {
+	if s, err := decodeStatus(bts); err == nil {
+		return s
+	}
+	return NewStatus(CodeUnknown, string(bts))
+}
+
+// response error to caller, old node without metadata only parse error message
+func respError(err error, status bool) error {
+	if err == nil {
+		return nil
+	} else if !status {
+		return errors.New(err.Error())
+	}
+	s, _ := FromError(err)
+	return s
+}
+
+func statusToHeader(s *Status, h http.Header) {
+	h.Set(HttpStatusCode, strconv.Itoa(int(s.Code)))
+	for k, v := range s.Details {
+		h.Set(HttpDetailPrefix+k, v)
+	}
+}
+
+// status of http failed response, message is response body
+func statusFromHeader(h http.Header, msg string) *Status {
+	var result = NewStatus(CodeUnknown, msg)
+	if code, err := strconv.Atoi(h.Get(HttpStatusCode)); err == nil {
+		result.Code = Code(code)
+	}
+	for k, v := range h {
+		if len(v) > 0 && len(k) > len(HttpDetailPrefix) && strings.EqualFold(k[:len(HttpDetailPrefix)], HttpDetailPrefix) {
+			if result.Details == nil {
+				result.Details = Metadata{}
+			}
+			result.Details.Set(k[len(HttpDetailPrefix):], v[0])
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"micro/network/pb"
+)
+
+type TstStatusReq struct {
+	Num int `json:"num"`
+}
+type TstStatusRsp struct {
+	Num int `json:"num"`
+}
+type TstStatus struct{}
+
+func (s *TstStatus) Compiler_JSON() {}
+
+func (s *TstStatus) Check(req *TstStatusReq, rsp *TstStatusRsp) error {
+	switch {
+	case req.Num < 0:
+		return NewStatus(CodePermissionDenied, "denied").WithDetails("reason", "negative")
+	case req.Num == 0:
+		return errors.New("zero")
+	case req.Num > 1000:
+		return fmt.Errorf("wrap: %w", Errorf(CodeResourceExhausted, strings.Repeat("x", req.Num)))
+	}
+	rsp.Num = req.Num
+	return nil
+}
+
+func TestStatusEncode(t *testing.T) {
+	var st = NewStatus(CodeNotFound, "no api").WithDetails("Api", "Svc.Get")
+	got, err := decodeStatus(st.encode())
+	if err != nil || got.Code != CodeNotFound || got.Message != "no api" || got.Details.Get("api") != "Svc.Get" {
+		t.Fatal("status decode wrong: ", got, err)
+	}
+	if statusError([]byte("raw message")).(*Status).Code != CodeUnknown {
+		t.Fatal("raw message must be unknown")
+	}
+
+	var cases = []struct {
+		err  error
+		code Code
+	}{
+		{nil, CodeOK},
+		{errors.New("failed"), CodeUnknown},
+		{context.Canceled, CodeCanceled},
+		{fmt.Errorf("wait: %w", context.DeadlineExceeded), CodeDeadlineExceeded},
+		{&connError{errors.New("closed")}, CodeUnavailable},
+		{fmt.Errorf("wrap: %w", st), CodeNotFound},
+	}
+	for _, c := range cases {
+		if code := ErrorCode(c.err); code != c.code {
+			t.Fatal("error code wrong: ", c.err, code)
+		}
+	}
+	if CodeDeadlineExceeded.String() != "DeadlineExceeded" || Code(99).String() != "Code(99)" {
+		t.Fatal("code name wrong")
+	}
+}
+
+func testStatusCall(t *testing.T, client *NodeDetail) {
+	var rsp = &TstStatusRsp{}
+	var st *Status
+	err := client.CallAuto("TstStatus.Check", &TstStatusReq{Num: -1}, rsp).Err()
+	if !errors.As(err, &st) || st.Code != CodePermissionDenied || st.Message != "denied" || st.Details.Get("reason") != "negative" {
+		t.Fatal("status of api error wrong: ", err)
+	}
+	err = client.CallAuto("TstStatus.Check", &TstStatusReq{Num: 0}, rsp).Err()
+	if !errors.As(err, &st) || st.Code != CodeUnknown || err.Error() != "zero" {
+		t.Fatal("status of plain error wrong: ", err)
+	}
+	// status message over one fixed frame
+	err = client.CallAuto("TstStatus.Check", &TstStatusReq{Num: 2000}, rsp).Err()
+	if !errors.As(err, &st) || st.Code != CodeResourceExhausted || len(st.Message) != 2000 {
+		t.Fatal("status of large error wrong: ", ErrorCode(err))
+	}
+	if err = client.CallAuto("TstStatus.Missing", &TstStatusReq{}, rsp).Err(); ErrorCode(err) != CodeNotFound {
+		t.Fatal("status of missing api wrong: ", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	// length-prefixed frame
+	client, server := testNodeLink(t, &TstStatus{}, false)
+	testStatusCall(t, client)
+
+	// fixed-size frame
+	client, _ = testNodeLink(t, &TstStatus{}, true)
+	testStatusCall(t, client)
+
+	// no node to request
+	client.fmsg.UpFuncNode(200, nil)
+	if err := client.CallAuto("TstStatus.Check", &TstStatusReq{Num: 1}, &TstStatusRsp{}).Err(); ErrorCode(err) == CodeUnknown {
+		t.Fatal("status of no node must not be unknown: ", err)
+	}
+
+	// http
+	var svc = server.funcs["TstStatus"]
+	server.httpCall("TstStatus.Check", svc, svc.funcs["Check"])
+	var mux = http.NewServeMux()
+	for key, function := range server.hlist {
+		mux.HandleFunc("/"+key, function)
+	}
+	var hs = httptest.NewServer(mux)
+	defer hs.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(hs.URL, "http://"))
+	hport, _ := strconv.ParseUint(port, 10, 64)
+	var fmsg = &pb.FuncMsg{ApiName: "TstStatus.Check", Protocal: pb.Compiler_JSON}
+	var nc = &NodeConn{NodeInfo: pb.NodeInfo{Host: host, Hport: hport}}
+	var st *Status
+	_, err, _ := postHttpByte(context.TODO(), nc, fmsg, []byte(`{"num":-1}`))
+	if !errors.As(err, &st) || st.Code != CodePermissionDenied || st.Message != "denied" || st.Details.Get("reason") != "negative" {
+		t.Fatal("http status wrong: ", err)
+	}
+	if _, err, _ = postHttpByte(context.TODO(), nc, fmsg, []byte(`{"num":"a"}`)); ErrorCode(err) != CodeInvalidArgument {
+		t.Fatal("http decode status wrong: ", err)
+	}
+}
@@ -0,0 +1,392 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"micro/common"
+	"micro/network"
+	"micro/network/pb"
+)
+
+// 流式接口只使用长度前缀帧的 tcp 连接，事件编号为流编号，
+// 双方按消息数量做流量控制，收到的消息处理一半窗口后通知对方增加可发送数量
+const StreamWindow = 64
+
+var typeOfStream = reflect.TypeOf((*ServerStream)(nil)).Elem()
+
+// server side of stream api,
+// eg: func (s *Svc) Watch(req *Req, stream rpc.ServerStream) error
+type ServerStream interface {
+	// cancelled when client closed stream or connection was closed
+	Context() context.Context
+	// send response message to client
+	Send(msg interface{}) error
+	// receive request message of client, io.EOF when client closed send
+	Recv(msg interface{}) error
+}
+
+type stream struct {
+	conn  *NodeConn
+	num   int         // stream event id
+	fid   int         // server api
+	flag  byte        // FrameFlagResp when server side
+	proto pb.Compiler // message protocal
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	recv   chan []byte   // message of the peer
+	end    chan error    // peer closed send, io.EOF or error
+	wake   chan struct{} // credit was added
+	credit int64         // message number can send
+	done   int32         // server finished, client cannot send
+	used   int           // received message number not notify peer
+	gone   chan struct{} // client stream was ended by server or connection
+	once   sync.Once
+
+	mut sync.Mutex
+	err error // recv end
+}
+
+func newStream(ctx context.Context, conn *NodeConn, num, fid int, flag byte) *stream {
+	var result = &stream{
+		conn: conn, num: num, fid: fid, flag: flag,
+		recv:   make(chan []byte, StreamWindow),
+		end:    make(chan error, 1),
+		wake:   make(chan struct{}, 1),
+		gone:   make(chan struct{}),
+		credit: StreamWindow,
+	}
+	result.ctx, result.cancel = context.WithCancel(ctx)
+	return result
+}
+
+func (s *stream) write(flags byte, status byte, bts []byte) error {
+	_, err := s.conn.tconn.Write((&Frame{Num: s.num, Func: s.fid,
+		Flags: s.flag | FrameFlagStream | flags, Status: status, Data: bts}).Encode())
+	return err
+}
+
+// end stream with status of the error
+func (s *stream) fail(err error) error {
+	st, _ := FromError(err)
+	return s.write(FrameFlagEnd, FrameStatusCode, st.encode())
+}
+
+func (s *stream) getErr() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.err
+}
+
+func (s *stream) setErr(err error) {
+	s.mut.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mut.Unlock()
+}
+
+// wait credit to send, peer may not read message
+func (s *stream) takeCredit() error {
+	for {
+		if atomic.LoadInt32(&s.done) == 1 {
+			return io.EOF
+		}
+		if c := atomic.LoadInt64(&s.credit); c > 0 {
+			if atomic.CompareAndSwapInt64(&s.credit, c, c-1) {
+				return nil
+			}
+			continue
+		}
+		select {
+		case <-s.wake:
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+}
+
+func (s *stream) Context() context.Context { return s.ctx }
+
+func (s *stream) Send(msg interface{}) error {
+	bts, err := MarshalInterface(s.proto, msg)
+	if err != nil {
+		return err
+	} else if err = s.takeCredit(); err != nil {
+		return err
+	}
+	return s.write(0, FrameStatusOK, bts)
+}
+
+func (s *stream) Recv(msg interface{}) error {
+	bts, err := s.recvMsg()
+	if err != nil {
+		return err
+	} else if len(bts) == 0 || msg == nil {
+		return nil
+	}
+	return UnmarshalInterface(s.proto, msg, bts)
+}
+
+func (s *stream) Next(rsp interface{}) error { return s.Recv(rsp) }
+
+func (s *stream) CloseSend() error { return s.write(FrameFlagEnd, FrameStatusOK, nil) }
+
+func (s *stream) Close() { s.cancel() }
+
+// message before end must be received first
+func (s *stream) recvMsg() ([]byte, error) {
+	select {
+	case bts := <-s.recv:
+		s.consume()
+		return bts, nil
+	default:
+	}
+	if err := s.getErr(); err != nil {
+		return nil, err
+	}
+	select {
+	case bts := <-s.recv:
+		s.consume()
+		return bts, nil
+	case err := <-s.end:
+		s.setErr(err)
+		if s.flag == 0 {
+			s.cancel()
+		}
+		return s.recvMsg()
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// notify peer to send more message
+func (s *stream) consume() {
+	if s.used++; s.used >= StreamWindow/2 {
+		var bts = make([]byte, binary.MaxVarintLen64)
+		bts = bts[:binary.PutUvarint(bts, uint64(s.used))]
+		if s.write(FrameFlagWindow, FrameStatusOK, bts) == nil {
+			s.used = 0
+		}
+	}
+}
+
+func (s *stream) finish(err error) {
+	select {
+	case s.end <- err:
+	default:
+	}
+}
+
+// stop waiting of client stream, message received can still be read
+func (s *stream) release() {
+	s.once.Do(func() { close(s.gone) })
+}
+
+// handle frame of the peer in connection reader, cannot be blocked
+func (s *stream) deliver(f *Frame) {
+	switch {
+	case f.Flags&FrameFlagWindow != 0:
+		if add, n := binary.Uvarint(f.Data); n > 0 {
+			atomic.AddInt64(&s.credit, int64(add))
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	case f.Flags&FrameFlagCancel != 0:
+		s.cancel()
+	case f.Flags&FrameFlagEnd != 0:
+		if s.flag == 0 {
+			atomic.StoreInt32(&s.done, 1)
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+		if err := f.Err(); err != nil {
+			s.finish(err)
+		} else {
+			s.finish(io.EOF)
+		}
+		if s.flag == 0 {
+			s.release()
+		}
+	default:
+		select {
+		case s.recv <- f.Data:
+		default:
+			s.finish(errors.New("stream message over window"))
+			s.cancel()
+		}
+	}
+}
+
+// open stream by tcp connection of length-prefixed frame
+func (n *NodeDetail) CallStream(ctx context.Context, name string, req interface{}) (network.ClientStream, error) {
+	if name == "" {
+		return nil, NewStatus(CodeInvalidArgument, "stream server api name cannot be null")
+	}
+	_, apiname := SplitApiName(name)
+	fmsg := n.QueryFunc(0, apiname)
+	if fmsg == nil || fmsg.ApiType != pb.ApiType_Stream {
+		return nil, NewStatus(CodeNotFound, "stream not found this server api: "+name)
+	}
+	bts, err := MarshalInterface(fmsg.Protocal, req)
+	if err != nil {
+		return nil, NewStatus(CodeInternal, "stream marshal error: "+err.Error())
+	}
+	for _, conn := range n.selectConn(fmsg, n.GetRemoteConn(ctx, fmsg)) {
+		if conn.Uuid == n.Uuid || conn.wrong || conn.types != ConnWithTCP || conn.Frame() != FrameLength || !conn.allow() {
+			continue
+		}
+		st, err := conn.openStream(n.callerMeta(ctx), fmsg, bts)
+		n.report(conn, err, 0)
+		if err == nil {
+			return st, nil
+		}
+	}
+	return nil, NewStatus(CodeUnavailable, "not found tcp frame connection to stream: "+name)
+}
+
+func (n *NodeConn) openStream(ctx context.Context, fmsg *pb.FuncMsg, bts []byte) (*stream, error) {
+	c, err := n.NewCall()
+	if err != nil {
+		return nil, err
+	}
+	var st = newStream(ctx, n, c.num, int(fmsg.FuncID), 0)
+	st.proto, st.end = fmsg.Protocal, c.err
+	n.mut.Lock()
+	c.strm = st
+	n.mut.Unlock()
+
+	md, _ := FromOutgoingContext(ctx)
+	if md, err = withTimeout(ctx, md); err != nil {
+		n.DelChan(c.num)
+		st.cancel()
+		return nil, err
+	}
+	_, err = n.tconn.Write((&Frame{Num: st.num, Func: st.fid, Flags: FrameFlagStream | FrameFlagOpen,
+		Data: bts, Meta: md}).Encode())
+	if err != nil {
+		n.DelChan(c.num)
+		st.cancel()
+		return nil, err
+	}
+	// release when caller closed, server ended or connection closed
+	go func() {
+		select {
+		case <-st.ctx.Done():
+			if st.getErr() == nil && atomic.LoadInt32(&st.done) == 0 {
+				st.write(FrameFlagCancel, FrameStatusOK, nil)
+			}
+		case <-st.gone:
+		}
+		n.DelChan(c.num)
+	}()
+	return st, nil
+}
+
+// client receive stream frame
+func (n *NodeConn) recvStream(f *Frame) {
+	n.mut.RLock()
+	c, ok := n.rc[f.Num]
+	n.mut.RUnlock()
+	if ok && c.strm != nil {
+		c.strm.deliver(f)
+	}
+}
+
+// server receive stream frame, open stream to call server api
+func (n *NodeDetail) serveStream(nc *NodeConn, f *Frame) {
+	if f.Flags&FrameFlagOpen == 0 {
+		nc.mut.RLock()
+		st, ok := nc.strm[f.Num]
+		nc.mut.RUnlock()
+		if ok {
+			st.deliver(f)
+		}
+		return
+	}
+
+	ctx, cancel := timeoutContext(nc.context(), f.Meta)
+	ctx = withPeer(withIdentity(ctx, nc.identity()), nc.registered())
+	ctx, _ = newIncomingContext(ctx, callerNode(nc.peerInfo(), f.Meta), f.Meta)
+	var st = newStream(ctx, nc, f.Num, f.Func, FrameFlagResp)
+	stop := st.cancel
+	st.cancel = func() { stop(); cancel() }
+	nc.mut.Lock()
+	if nc.strm == nil {
+		nc.strm = make(map[int]*stream)
+	}
+	if _, ok := nc.strm[f.Num]; ok {
+		nc.mut.Unlock()
+		st.cancel()
+		st.fail(NewStatus(CodeResourceExhausted, "stream event id was in use"))
+		return
+	}
+	nc.strm[f.Num] = st
+	nc.mut.Unlock()
+
+	n.drain.add()
+	go func() {
+		defer n.drain.done()
+		defer common.Recover()
+		err := n.callStream(nc, st, f.Data)
+		nc.mut.Lock()
+		delete(nc.strm, st.num)
+		nc.mut.Unlock()
+		st.cancel()
+		if err != nil {
+			st.fail(err)
+		} else {
+			st.write(FrameFlagEnd, FrameStatusOK, nil)
+		}
+	}()
+}
+
+func (n *NodeDetail) callStream(nc *NodeConn, st *stream, bts []byte) error {
+	if n.drain.closing() {
+		return errShutdown()
+	}
+	fmsg := n.QueryFunc(uint32(st.fid), "")
+	if fmsg == nil {
+		return NewStatus(CodeNotFound, "not found server api mapping in server: "+n.Uuid)
+	}
+	s, ok := n.funcs[fmsg.ServName]
+	if !ok {
+		return NewStatus(CodeNotFound, "not found server: "+fmsg.ServName+" by local")
+	}
+	f, ok := s.funcs[fmsg.FuncName]
+	if !ok || f.api != pb.ApiType_Stream {
+		return NewStatus(CodeNotFound, "not found stream function: "+fmsg.FuncName)
+	}
+	st.proto = s.proto
+	req, err := UnmarshalValue(s.proto, f.req, bts)
+	if err != nil {
+		return NewStatus(CodeInvalidArgument, err.Error())
+	}
+	node, _ := st.ctx.Value(CtxKeyNodeBase).(*pb.NodeInfo)
+	if err = n.authorize(fmsg.ApiName, nc.identity()); err != nil {
+		return err
+	}
+	var info = &ServerInfo{Func: fmsg, Node: node, Network: nc.network(), Cert: nc.cert}
+	return n.runStream(info, req.Interface(), st, f.streamHandler(s.rv))
+}
+
+// connection closed, cancel all server stream
+func (n *NodeConn) cancelStream() {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	for num, st := range n.strm {
+		st.cancel()
+		delete(n.strm, num)
+	}
+}
@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"micro/network"
+	"micro/network/comm"
+	"micro/network/pb"
+)
+
+type TstStreamReq struct {
+	Count int `json:"count"`
+}
+type TstStreamMsg struct {
+	Index int `json:"index"`
+}
+type TstStream struct{ cancel chan error }
+
+func (s *TstStream) Compiler_JSON() {}
+
+func (s *TstStream) Watch(req *TstStreamReq, stream ServerStream) error {
+	for i := 0; i < req.Count; i++ {
+		if err := stream.Send(&TstStreamMsg{Index: i}); err != nil {
+			return err
+		}
+	}
+	if req.Count < 0 {
+		return errors.New("count wrong")
+	}
+	return nil
+}
+
+func (s *TstStream) Echo(req *TstStreamReq, stream ServerStream) error {
+	for {
+		var msg = &TstStreamMsg{}
+		if err := stream.Recv(msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if err = stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *TstStream) Block(req *TstStreamReq, stream ServerStream) error {
+	<-stream.Context().Done()
+	s.cancel <- stream.Context().Err()
+	return stream.Context().Err()
+}
+
+func testStreamNode(t *testing.T) (*NodeDetail, *TstStream) {
+	var svc = &TstStream{cancel: make(chan error, 1)}
+	client, _ := testNodePair(t, svc)
+	return client, svc
+}
+
+// server node registered svc and client node connected by tcp frame
+func testNodePair(t *testing.T, svc interface{}) (*NodeDetail, *NodeDetail) {
+	return testNodeLink(t, svc, false)
+}
+
+// connect by fixed-size frame when server is legacy
+func testNodeLink(t *testing.T, svc interface{}, legacy bool) (*NodeDetail, *NodeDetail) {
+	var server = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}, funcs: make(map[string]*Server),
+		hlist: make(map[string]func(http.ResponseWriter, *http.Request)), legacy: legacy}
+	if err := server.Register(svc); err != nil {
+		t.Fatal(err)
+	}
+	server.Uuid = "S"
+	return testNodeDial(t, server), server
+}
+
+// connect server by a client node, server apis were mapped to the client
+func testNodeDial(t *testing.T, server *NodeDetail) *NodeDetail {
+	var legacy = server.legacy
+	conn, frame, meta, err := (&NodeDetail{}).dialTCP(testFrameServer(t, server))
+	if err != nil || (frame == FrameLength) == legacy || !meta {
+		t.Fatal("dial stream server wrong: ", err)
+	}
+	var nc = &NodeConn{tconn: conn, types: ConnWithTCP, meta: meta,
+		rc: make(map[int]*RecvChan), list: make(map[int]*ReadLink)}
+	nc.Uuid = "S"
+	nc.setFrame(frame)
+	go nc.ReadRespTCP()
+	t.Cleanup(nc.Close)
+	return testNodeMap(t, server, nc)
+}
+
+// client node of the server connection, server apis were mapped to the client
+func testNodeMap(t *testing.T, server *NodeDetail, nc *NodeConn) *NodeDetail {
+	var client = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}}
+	client.Uuid = "C"
+	if b, err := NewBalancer(""); err != nil {
+		t.Fatal(err)
+	} else {
+		client.SetBalancer("", b)
+	}
+	client.fmsg.PutConn(nc)
+	for i, row := range server.Funcs {
+		var fmsg = &pb.FuncMsg{FuncID: uint32(200 + i), ApiName: row.Name,
+			ApiType: row.Type, Protocal: row.Kind}
+		fmsg.ServName, fmsg.FuncName = comm.SplitApiName(row.Name)
+		server.fmsg.PutMsg(fmsg)
+		client.fmsg.PutMsg(&pb.FuncMsg{FuncID: fmsg.FuncID, ApiName: fmsg.ApiName,
+			ServName: fmsg.ServName, FuncName: fmsg.FuncName,
+			ApiType: fmsg.ApiType, Protocal: fmsg.Protocal})
+		client.fmsg.UpFuncNode(fmsg.FuncID, []string{"S"})
+	}
+	return client
+}
+
+func TestStream(t *testing.T) {
+	client, svc := testStreamNode(t)
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*5)
+	defer cancel()
+
+	// server stream over flow control window
+	st, err := client.CallStream(ctx, "TstStream.Watch", &TstStreamReq{Count: StreamWindow * 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < StreamWindow*10; i++ {
+		var msg = &TstStreamMsg{}
+		if err = st.Next(msg); err != nil || msg.Index != i {
+			t.Fatal("stream message wrong: ", i, msg.Index, err)
+		}
+	}
+	if err = st.Next(&TstStreamMsg{}); err != io.EOF {
+		t.Fatal("stream end wrong: ", err)
+	}
+
+	// server return error
+	if st, err = client.CallStream(ctx, "TstStream.Watch", &TstStreamReq{Count: -1}); err != nil {
+		t.Fatal(err)
+	} else if err = st.Next(&TstStreamMsg{}); err == nil || err.Error() != "count wrong" {
+		t.Fatal("stream error wrong: ", err)
+	}
+
+	// bidirectional stream
+	if st, err = client.CallStream(ctx, "TstStream.Echo", &TstStreamReq{}); err != nil {
+		t.Fatal(err)
+	}
+	go func(st network.ClientStream) {
+		for i := 0; i < StreamWindow*3; i++ {
+			if err := st.Send(&TstStreamMsg{Index: i}); err != nil {
+				return
+			}
+		}
+		st.CloseSend()
+	}(st)
+	for i := 0; i < StreamWindow*3; i++ {
+		var msg = &TstStreamMsg{}
+		if err = st.Next(msg); err != nil || msg.Index != i {
+			t.Fatal("echo message wrong: ", i, msg.Index, err)
+		}
+	}
+	if err = st.Next(&TstStreamMsg{}); err != io.EOF {
+		t.Fatal("echo end wrong: ", err)
+	}
+
+	// client cancel stream
+	if st, err = client.CallStream(ctx, "TstStream.Block", &TstStreamReq{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	st.Close()
+	select {
+	case err = <-svc.cancel:
+		if err != context.Canceled {
+			t.Fatal("server stream context wrong: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server stream not cancelled")
+	}
+	if err = st.Next(&TstStreamMsg{}); err == nil {
+		t.Fatal("closed stream should be wrong")
+	}
+
+	if _, err = client.CallStream(ctx, "TstStream.Missing", &TstStreamReq{}); err == nil {
+		t.Fatal("not found api should be wrong")
+	}
+}
+
+func testStreamWait(t *testing.T, nc *NodeConn) {
+	for i := 0; i < 100; i++ {
+		nc.mut.RLock()
+		num := len(nc.rc)
+		nc.mut.RUnlock()
+		if num == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatal("stream must be released")
+}
+
+func TestStreamRelease(t *testing.T) {
+	client, _ := testStreamNode(t)
+	var nc = client.fmsg.GetNodeConn("S")
+
+	// server ended, caller not read to the end and not close
+	st, err := client.CallStream(context.TODO(), "TstStream.Watch", &TstStreamReq{Count: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStreamWait(t, nc)
+	for i := 0; i < 3; i++ {
+		var msg = &TstStreamMsg{}
+		if err = st.Next(msg); err != nil || msg.Index != i {
+			t.Fatal("released stream message wrong: ", i, msg.Index, err)
+		}
+	}
+	if err = st.Next(&TstStreamMsg{}); err != io.EOF {
+		t.Fatal("released stream end wrong: ", err)
+	}
+
+	// connection was closed
+	if st, err = client.CallStream(context.TODO(), "TstStream.Block", &TstStreamReq{}); err != nil {
+		t.Fatal(err)
+	}
+	nc.Close()
+	testStreamWait(t, nc)
+	if err = st.Next(&TstStreamMsg{}); err == nil {
+		t.Fatal("stream of closed connection should be wrong")
+	}
+}
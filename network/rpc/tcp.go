@@ -1,6 +1,10 @@
 package rpc
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
 	"log"
 	"net"
 	"sync"
@@ -52,16 +56,40 @@ func TcpDialTest(host string, port int) error {
 	}
 }
 
-func (n *NodeDetail) DialTCP(addr *net.TCPAddr) (*net.TCPConn, error) {
-	conn, err := net.DialTCP("tcp", nil, addr)
-	if err == nil {
-		for _, row := range n.tmps.tcpreg {
+// 连接Tcp地址，协商帧格式后注册本节点
+func (n *NodeDetail) DialTCP(addr *net.TCPAddr) (net.Conn, FrameType, error) {
+	conn, frame, _, err := n.dialTCP(addr)
+	return conn, frame, err
+}
+
+// return true when node can parse metadata of fixed frame
+func (n *NodeDetail) dialTCP(addr *net.TCPAddr) (net.Conn, FrameType, bool, error) {
+	conn, err := dialConn(addr, n.tlsc)
+	if err != nil {
+		return nil, FrameFixed, false, err
+	}
+	var frame, meta = FrameFixed, false
+	if !n.legacy {
+		if frame, meta, err = negotiateFrame(conn); err != nil {
+			conn.Close()
+			return nil, FrameFixed, false, err
+		}
+	}
+	bts, err := n.dialRegister()
+	if err == nil && len(bts) > 0 && frame == FrameLength {
+		_, err = conn.Write(MakeReqFrame(bts, 1, comm.DialRegister))
+	} else if err == nil && len(bts) > 0 {
+		for _, row := range tcpsplit.MakeReqBody(bts, 1, comm.DialRegister) {
 			if _, err = conn.Write(row); err != nil {
-				conn.Close()
+				break
 			}
 		}
 	}
-	return conn, err
+	if err != nil {
+		conn.Close()
+		return nil, FrameFixed, false, err
+	}
+	return conn, frame, meta, nil
 }
 
 // Tcp端口监听连接请求
@@ -71,36 +99,58 @@ func (n *NodeDetail) TcpListen(port int) error {
 	); err != nil {
 		return err
 	} else {
-		go func(s *NodeDetail, t *net.TCPListener) {
+		n.lis.tcp = listen
+		var t net.Listener = listen
+		if n.tlsc != nil {
+			t = tls.NewListener(listen, n.tlsc.server)
+		}
+		go func(s *NodeDetail, t net.Listener) {
 			for {
-				if conn, err := t.Accept(); err != nil {
+				if conn, err := t.Accept(); errors.Is(err, net.ErrClosed) {
+					return
+				} else if err != nil {
 					log.Printf("rpc.Serve: accept: %v\n", err)
 				} else {
-					go s.tcpAccept(conn.(*net.TCPConn))
+					go s.tcpAccept(conn)
 				}
 			}
-		}(n, listen)
+		}(n, t)
 	}
 	return nil
 }
 
 // ServeCodec is like ServeConn but uses the specified codec to
 // decode requests and encode responses.
-func (n *NodeDetail) tcpAccept(conn *net.TCPConn) {
+func (n *NodeDetail) tcpAccept(conn net.Conn) {
 	defer common.Recover()
 	defer conn.Close()
 
+	cert, err := acceptConn(conn)
+	if err != nil {
+		log.Printf("rpc.Serve: tls handshake: %v\n", err)
+		return
+	}
 	r := &NodeConn{
 		tconn: conn,
+		cert:  cert,
+		zipc:  n.zipc,
 		types: ConnWithTCP,
 		fc:    make(map[string]bool),
 		rc:    make(map[int]*RecvChan),
 		list:  make(map[int]*ReadLink),
 	}
+	// cancel running server api when caller disconnected
+	r.ctx, r.cancel = context.WithCancel(withPeerCertificate(context.Background(), cert))
+	defer r.cancel()
+	n.lis.conns.Store(r, true)
+	defer n.lis.conns.Delete(r)
 
 	var num int
-	var err error
 	for {
+		if r.Frame() == FrameLength {
+			n.tcpAcceptFrame(r)
+			return
+		}
 		buff := NewTcpBuffer()
 		num, err = conn.Read(buff.Data)
 		if err != nil {
@@ -118,17 +168,81 @@ func (n *NodeDetail) tcpAccept(conn *net.TCPConn) {
 			}
 		}
 
-		go func(rc *NodeConn, b *ConnBody) {
-			if rows := n.ParseRspByte(tcpsplit, r, b); len(rows) >= 0 {
-				for _, row := range rows {
-					if _, err := rc.tconn.Write(row); err != nil {
-						rc.tconn.SetReadDeadline(time.Now())
-						rc.tconn.Close()
-						break
-					}
+		// switch frame format before read next request
+		if isFrameNegotiate(buff) {
+			for _, row := range n.ParseRspByte(tcpsplit, r, buff) {
+				if _, err = conn.Write(row); err != nil {
+					PutTcpBuffer(buff)
+					return
 				}
 			}
 			PutTcpBuffer(buff)
+			continue
+		}
+		// 注册设置对端身份，处理完再读取后续请求
+		if isDialRegister(buff) {
+			err = n.serveBody(tcpsplit, r, buff, func(row []byte) error {
+				_, err := conn.Write(row)
+				return err
+			})
+			PutTcpBuffer(buff)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		n.drain.add()
+		go func(rc *NodeConn, b *ConnBody) {
+			defer n.drain.done()
+			if err := n.serveBody(tcpsplit, rc, b, func(row []byte) error {
+				_, err := rc.tconn.Write(row)
+				return err
+			}); err != nil {
+				rc.tconn.SetReadDeadline(time.Now())
+				rc.tconn.Close()
+			}
+			PutTcpBuffer(b)
 		}(r, buff)
 	}
 }
+
+func isFrameNegotiate(b *ConnBody) bool {
+	return b.Data[5] == BodyWholeData && int(b.Data[6])<<8+int(b.Data[7]) == comm.TcpFrameMode
+}
+
+func isDialRegister(b *ConnBody) bool {
+	return b.Data[5] == BodyWholeData && int(b.Data[6])<<8+int(b.Data[7]) == comm.DialRegister
+}
+
+// 读取长度前缀帧请求
+func (n *NodeDetail) tcpAcceptFrame(r *NodeConn) {
+	defer r.cancelStream()
+	var rd = bufio.NewReaderSize(r.tconn, FrameReadSize)
+	for {
+		f, err := ReadFrame(rd)
+		if err != nil {
+			return
+		} else if f.Flags&FrameFlagStream != 0 {
+			n.serveStream(r, f)
+			continue
+		} else if f.Func == comm.DialRegister {
+			if row := n.ParseRspFrame(r, f); row != nil {
+				if _, err := r.tconn.Write(row); err != nil {
+					return
+				}
+			}
+			continue
+		}
+		n.drain.add()
+		go func(rc *NodeConn, f *Frame) {
+			defer n.drain.done()
+			if row := n.ParseRspFrame(rc, f); row != nil {
+				if _, err := rc.tconn.Write(row); err != nil {
+					rc.tconn.SetReadDeadline(time.Now())
+					rc.tconn.Close()
+				}
+			}
+		}(r, f)
+	}
+}
@@ -0,0 +1,153 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"micro/network"
+)
+
+const TLSHandshakeTimeout = time.Second * 5
+
+// tls config of the node, nil when not use
+type tlsConfig struct {
+	server *tls.Config
+	client *tls.Config
+	http   *http.Client
+	name   string // server name to verify remote node
+}
+
+type peerCertKey struct{}
+
+func newTLSConfig(config *network.TLSConfig) (*tlsConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	var cert = config.Certificate
+	if cert == nil {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, errors.New("tls certificate cannot be null")
+		}
+		value, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cert = &value
+	}
+	var pool = config.CAPool
+	if pool == nil && config.CAFile != "" {
+		bts, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bts) {
+			return nil, errors.New("tls CA file have no certificate: " + config.CAFile)
+		}
+	}
+
+	var result = &tlsConfig{name: config.ServerName}
+	result.server = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	if config.ClientAuth {
+		result.server.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	result.client = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		RootCAs:      pool,
+		ServerName:   config.ServerName,
+	}
+	result.http = &http.Client{Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     result.client,
+		TLSHandshakeTimeout: TLSHandshakeTimeout,
+	}}
+	return result, nil
+}
+
+// dial tcp address with keepalive, handshake when the node use tls
+func dialConn(addr *net.TCPAddr, c *tlsConfig) (net.Conn, error) {
+	conn, err := net.DialTCP("tcp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	if c == nil {
+		return conn, nil
+	}
+	var config = c.client
+	if c.name == "" {
+		config = config.Clone()
+		config.ServerName = addr.IP.String()
+	}
+	tconn := tls.Client(conn, config)
+	if err = handshake(tconn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tconn, nil
+}
+
+func handshake(conn *tls.Conn) error {
+	conn.SetDeadline(time.Now().Add(TLSHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+	return conn.Handshake()
+}
+
+// handshake the accepted connection, return verified certificate of the caller,
+// nil when caller has no certificate or not use tls
+func acceptConn(conn net.Conn) (*x509.Certificate, error) {
+	tconn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, nil
+	} else if err := handshake(tconn); err != nil {
+		return nil, err
+	}
+	return peerCertificate(tconn.ConnectionState()), nil
+}
+
+func peerCertificate(state tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		return state.VerifiedChains[0][0]
+	}
+	return nil
+}
+
+func (c *tlsConfig) scheme() string {
+	if c == nil {
+		return "http"
+	}
+	return "https"
+}
+
+func (c *tlsConfig) httpClient() *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c.http
+}
+
+// PeerCertificate : certificate of the caller node verified by mutual tls,
+// nil when the request was not by tls or caller had no certificate
+func PeerCertificate(ctx context.Context) *x509.Certificate {
+	cert, _ := ctx.Value(peerCertKey{}).(*x509.Certificate)
+	return cert
+}
+
+func withPeerCertificate(ctx context.Context, cert *x509.Certificate) context.Context {
+	if cert == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, peerCertKey{}, cert)
+}
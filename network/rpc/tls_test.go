@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"micro/network"
+	"micro/network/pb"
+)
+
+type TstTLSReq struct{}
+type TstTLSRsp struct {
+	Name string `json:"name"`
+}
+type TstTLS struct{}
+
+func (s *TstTLS) Compiler_JSON() {}
+
+func (s *TstTLS) Peer(ctx context.Context, req *TstTLSReq, rsp *TstTLSRsp) error {
+	if cert := PeerCertificate(ctx); cert != nil {
+		rsp.Name = cert.Subject.CommonName
+	}
+	return nil
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+// self-signed CA to issue node certificate
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmpl = &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ca = &testCA{key: key, pool: x509.NewCertPool()}
+	if ca.cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	ca.pool.AddCert(ca.cert)
+	return ca
+}
+
+// node certificate for server and client, valid for 127.0.0.1
+func (ca *testCA) issue(t *testing.T, name string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmpl = &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (ca *testCA) config(t *testing.T, name string) *tlsConfig {
+	result, err := newTLSConfig(&network.TLSConfig{Certificate: ca.issue(t, name),
+		CAPool: ca.pool, ClientAuth: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestTLS(t *testing.T) {
+	var ca = newTestCA(t)
+	var server = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}, funcs: make(map[string]*Server),
+		hlist: make(map[string]func(http.ResponseWriter, *http.Request)), tlsc: ca.config(t, "S")}
+	var cert = make(chan *x509.Certificate, 1)
+	server.Use(func(ctx context.Context, info *ServerInfo, req interface{}, next UnaryHandler) (interface{}, error) {
+		cert <- info.Cert
+		return next(ctx, req)
+	})
+	var err error
+	if server.Tport, err = GetFreePort(); err != nil {
+		t.Fatal(err)
+	} else if server.Hport, err = GetFreePort(); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.Register(&TstTLS{}); err != nil {
+		t.Fatal(err)
+	}
+	server.Uuid, server.Host = "S", "127.0.0.1"
+	var fmsg = &pb.FuncMsg{FuncID: 200, ApiName: "TstTLS.Peer", ServName: "TstTLS",
+		FuncName: "Peer", ApiType: pb.ApiType_Call, Protocal: pb.Compiler_JSON}
+	server.fmsg.PutMsg(fmsg)
+	if err = server.TcpListen(int(server.Tport)); err != nil {
+		t.Fatal(err)
+	} else if err = server.HttpListen(int(server.Hport)); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown(context.TODO())
+
+	// mutual tls by certificate of the same CA
+	var client = &NodeDetail{fmsg: &funcmap{}, wser: &WatchNode{}, tlsc: ca.config(t, "C")}
+	if b, err := NewBalancer(""); err != nil {
+		t.Fatal(err)
+	} else {
+		client.SetBalancer("", b)
+	}
+	client.fmsg.PutMsg(&pb.FuncMsg{FuncID: fmsg.FuncID, ApiName: fmsg.ApiName, ServName: fmsg.ServName,
+		FuncName: fmsg.FuncName, ApiType: fmsg.ApiType, Protocal: fmsg.Protocal})
+	client.fmsg.UpFuncNode(fmsg.FuncID, []string{"S"})
+	var nc = &NodeConn{NodeInfo: pb.NodeInfo{Uuid: "S", Host: "127.0.0.1", Tport: server.Tport},
+		rc: make(map[int]*RecvChan), list: make(map[int]*ReadLink), fc: make(map[string]bool)}
+	if err = client.RefreshConn(nc); err != nil {
+		t.Fatal("dial tls wrong: ", err)
+	}
+	defer nc.Close()
+	if _, ok := nc.tconn.(*tls.Conn); !ok || nc.Frame() != FrameLength {
+		t.Fatal("connection must be tls and negotiate frame")
+	}
+	client.fmsg.PutConn(nc)
+
+	var rsp = &TstTLSRsp{}
+	if err = client.CallAuto("TstTLS.Peer", &TstTLSReq{}, rsp).Err(); err != nil || rsp.Name != "C" {
+		t.Fatal("tcp peer certificate wrong: ", err, rsp.Name)
+	} else if c := <-cert; c == nil || c.Subject.CommonName != "C" {
+		t.Fatal("server info certificate wrong: ", c)
+	}
+
+	// https
+	var hc = &NodeConn{NodeInfo: pb.NodeInfo{Host: "127.0.0.1", Hport: server.Hport}, tlsc: client.tlsc}
+	body, apierr, err := postHttpByte(context.TODO(), hc, fmsg, []byte(`{}`))
+	if err != nil || apierr != nil || string(body) != `{"name":"C"}` {
+		t.Fatal("https peer certificate wrong: ", err, apierr, string(body))
+	} else if c := <-cert; c == nil || c.Subject.CommonName != "C" {
+		t.Fatal("https server info certificate wrong: ", c)
+	} else if httpPing("127.0.0.1", server.Hport, client.tlsc) != nil {
+		t.Fatal("https ping wrong")
+	}
+
+	// handshake failed: plaintext, no client certificate, certificate of other CA
+	var addr = &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(server.Tport)}
+	if _, _, err = (&NodeDetail{}).DialTCP(addr); err == nil {
+		t.Fatal("plaintext connection must be refused")
+	}
+	nocert, err := newTLSConfig(&network.TLSConfig{Certificate: ca.issue(t, "N"), CAPool: ca.pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nocert.client.Certificates = nil
+	if _, _, err = (&NodeDetail{tlsc: nocert}).DialTCP(addr); err == nil {
+		t.Fatal("connection without certificate must be refused")
+	}
+	var other = newTestCA(t).config(t, "O")
+	other.client.RootCAs = ca.pool
+	if _, _, err = (&NodeDetail{tlsc: other}).DialTCP(addr); err == nil {
+		t.Fatal("certificate of other CA must be refused")
+	}
+	if _, _, err = (&NodeDetail{tlsc: newTestCA(t).config(t, "C")}).DialTCP(addr); err == nil {
+		t.Fatal("server certificate of other CA must be refused")
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"log"
 	"net"
 	"sync"
 
@@ -10,8 +11,9 @@ import (
 
 var (
 	udpCleanBuf = make([]byte, udpsplit.TotalSize)
+	// capacity can read reliable packet
 	udpBodyPool = &sync.Pool{New: func() interface{} {
-		return &ConnBody{Data: make([]byte, udpsplit.TotalSize)}
+		return &ConnBody{Data: make([]byte, udpsplit.TotalSize, RudpHeadSize+udpsplit.TotalSize)}
 	}}
 )
 
@@ -19,6 +21,7 @@ func NewUdpBuffer() *ConnBody {
 	return udpBodyPool.Get().(*ConnBody)
 }
 func PutUdpBuffer(c *ConnBody) {
+	c.Data = c.Data[:udpsplit.TotalSize]
 	copy(c.Data[0:], udpCleanBuf)
 	udpBodyPool.Put(c)
 }
@@ -49,13 +52,20 @@ func UdpDialTest(addr *net.UDPAddr) error {
 
 func (n *NodeDetail) DialUDP(addr *net.UDPAddr) (*net.UDPConn, error) {
 	conn, err := net.DialUDP("udp", nil, addr)
-	if err == nil {
-		for _, row := range n.tmps.udpreg {
+	if err != nil {
+		return conn, err
+	}
+	bts, err := n.dialRegister()
+	if err == nil && len(bts) > 0 {
+		for _, row := range udpsplit.MakeReqBody(bts, 1, comm.DialRegister) {
 			if _, err = conn.Write(row); err != nil {
-				conn.Close()
+				break
 			}
 		}
 	}
+	if err != nil {
+		conn.Close()
+	}
 	return conn, err
 }
 
@@ -68,9 +78,17 @@ func (n *NodeDetail) UdpListen(port int) error {
 	}); err != nil {
 		return err
 	} else {
+		n.lis.udp = udpconn
+		var rel *rudp
+		rel = newRudp(udpconn, func(addr *net.UDPAddr, rows [][]byte) {
+			n.drain.add()
+			go n.serveRudp(rel, addr, rows)
+		})
+		n.lis.rudp = rel
 		go func(nd *NodeDetail, conn *net.UDPConn) {
 			defer common.Recover()
 			defer conn.Close()
+			defer rel.close()
 
 			r := &NodeConn{
 				uconn: conn,
@@ -82,19 +100,21 @@ func (n *NodeDetail) UdpListen(port int) error {
 
 			for {
 				buff := NewUdpBuffer()
-				if _, addr, err := conn.ReadFromUDP(buff.Data); err != nil {
+				if num, addr, err := conn.ReadFromUDP(buff.Data[:cap(buff.Data)]); err != nil {
 					PutUdpBuffer(buff)
 					break
+				} else if isRudpPacket(buff.Data[:num]) {
+					rel.input(addr, buff.Data[:num])
+					PutUdpBuffer(buff)
 				} else {
+					n.drain.add()
 					go func(rc *NodeConn, addr *net.UDPAddr, b *ConnBody) {
-						if rows := n.ParseRspByte(udpsplit, r, b); len(rows) >= 0 {
-							for _, row := range rows {
-								if _, err := r.uconn.WriteToUDP(row, addr); err != nil {
-									return
-								}
-							}
-						}
-						PutUdpBuffer(buff)
+						defer n.drain.done()
+						n.serveBody(udpsplit, rc, b, func(row []byte) error {
+							_, err := rc.uconn.WriteToUDP(row, addr)
+							return err
+						})
+						PutUdpBuffer(b)
 					}(r, addr, buff)
 				}
 			}
@@ -104,6 +124,30 @@ func (n *NodeDetail) UdpListen(port int) error {
 	return nil
 }
 
+// serve request received by reliable udp, every request use new connection to join body,
+// event id of callers was not unique
+func (n *NodeDetail) serveRudp(r *rudp, addr *net.UDPAddr, rows [][]byte) {
+	defer n.drain.done()
+	defer common.Recover()
+	var rc = &NodeConn{
+		uconn: r.conn,
+		types: ConnWithUDP,
+		fc:    make(map[string]bool),
+		rc:    make(map[int]*RecvChan),
+		list:  make(map[int]*ReadLink),
+	}
+	var rsp [][]byte
+	for _, row := range rows {
+		n.serveBody(udpsplit, rc, &ConnBody{Data: row}, func(row []byte) error {
+			rsp = append(rsp, row)
+			return nil
+		})
+	}
+	if err := r.send(addr, rsp); err != nil && err != errRudpClosed {
+		log.Println("rpc.serveRudp: ", addr, err)
+	}
+}
+
 var udpconns = sync.Pool{
 	New: func() interface{} {
 		uconn, _ := net.DialUDP("udp", nil, &net.UDPAddr{})
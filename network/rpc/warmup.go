@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	WarmUpMinRate  = 0.1 // weight rate when slow start begin
+	warmUpMinCalls = 10  // requests to count success rate of slow start
+)
+
+// slow start of the node connection
+type warmUp struct {
+	start int64  // slow start timestamp (millisecond), 0 is finished
+	calls uint32 // requests in slow start
+	fails uint32 // failed requests in slow start
+}
+
+// restart slow start when node joined or recovered
+func (n *NodeConn) startWarmUp() {
+	atomic.StoreUint32(&n.warm.calls, 0)
+	atomic.StoreUint32(&n.warm.fails, 0)
+	atomic.StoreInt64(&n.warm.start, time.Now().UnixMilli())
+}
+
+// WarmUpRate : weight rate of the node in slow start [WarmUpMinRate, 1],
+// increase linearly by the time, multiply success rate after some requests
+func (n *NodeConn) WarmUpRate() float64 {
+	start := atomic.LoadInt64(&n.warm.start)
+	if n.WarmUp == 0 || start == 0 {
+		return 1
+	}
+	elapsed := time.Now().UnixMilli() - start
+	if elapsed >= int64(n.WarmUp) {
+		atomic.CompareAndSwapInt64(&n.warm.start, start, 0)
+		return 1
+	}
+	rate := float64(elapsed) / float64(n.WarmUp)
+	if calls := atomic.LoadUint32(&n.warm.calls); calls >= warmUpMinCalls {
+		rate *= 1 - float64(atomic.LoadUint32(&n.warm.fails))/float64(calls)
+	}
+	if rate < WarmUpMinRate {
+		return WarmUpMinRate
+	}
+	return rate
+}
+
+// count request result of the node in slow start
+func (n *NodeConn) warmReport(failed bool) {
+	if n.WarmUp == 0 || atomic.LoadInt64(&n.warm.start) == 0 {
+		return
+	}
+	atomic.AddUint32(&n.warm.calls, 1)
+	if failed {
+		atomic.AddUint32(&n.warm.fails, 1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,22 +21,38 @@ type WatchBuiltApi interface {
 	Heartbeats() error
 	GetFuncMsg(ctx context.Context, fid uint32, name string) (*pb.GetFuncMsgRsp, error)
 	GetApiConn(ctx context.Context, fid uint32, name string) (*pb.GetApiConnRsp, error)
+	GetApiConnAll(ctx context.Context, fid uint32, name string) (*pb.GetApiConnRsp, error)
 	GetNodeMsg(ctx context.Context, uuid string, name string) (*pb.GetNodeMsgRsp, error)
+	GetWatcher(ctx context.Context) (*pb.WatcherList, error)
+	DeleteApi(ctx context.Context, fid uint32, name string, force bool) (*pb.DeleteApiRsp, error)
+	Deregister(ctx context.Context) error
 }
 
 func (n *NodeDetail) WatchApi() WatchBuiltApi { return n.wser }
 
 type WatchNode struct {
 	uuid   string // server node uuid
-	count  uint32 // request watcher number
+	name   string // server node name
 	procid uint64 // proc id
+	term   uint64 // watcher elect term
 
+	// health state report by heartbeat
+	serving pb.ServingState
+	check   func() pb.ServingState
+	// sign deregister message
+	sign *signer
+	// version of authorization policy, watcher push policy when it was different
+	authver uint64
+
+	mut    sync.RWMutex   // safe lock master and slaves
 	config []*pb.NodeInfo // watcher node config
 	master *NodeConn      // master watcher node
 	slaves []*NodeConn    // slave watcher nodes
 }
 
 func (n *NodeDetail) InitWatchConfig() error {
+	n.wser.mut.Lock()
+	defer n.wser.mut.Unlock()
 	if n.wser.master != nil {
 		n.wser.master.Close()
 		n.wser.master = nil
@@ -43,14 +60,15 @@ func (n *NodeDetail) InitWatchConfig() error {
 	for _, row := range n.wser.slaves {
 		row.Close()
 	}
+	n.wser.slaves = make([]*NodeConn, 0)
 
+	// watcher uuid is unknown, connection cannot cache by uuid
 	var err error
 	if len(n.wser.config) == 1 {
-		n.wser.master, err = n.NodeBaseToConn(n.wser.config[0])
+		n.wser.master, err = n.DialNode(n.wser.config[0])
 	} else if len(n.wser.config) > 1 {
-		n.wser.slaves = make([]*NodeConn, 0)
 		for _, node := range n.wser.config {
-			conn, err := n.NodeBaseToConn(node)
+			conn, err := n.DialNode(node)
 			if err == nil && conn.TestTcpConn() == nil {
 				if node.Main && n.wser.master == nil {
 					n.wser.master = conn
@@ -72,7 +90,7 @@ func (n *NodeDetail) InitWatchConfig() error {
 
 func (n *NodeDetail) WatchRegister(ctx context.Context) error {
 	var result = &pb.RegisteredRsp{}
-	if bts, err := proto.Marshal(&n.NodeInfo); err != nil {
+	if bts, err := n.sign.marshal(&n.NodeInfo); err != nil {
 		return err
 	} else {
 		err = n.wser.MasterCall(ctx, comm.Registered, bts, result)
@@ -94,25 +112,84 @@ func (n *NodeDetail) WatchRegister(ctx context.Context) error {
 		n.fmsg.str.Store(fmsg.Name, tmp)
 	}
 
+	n.setAuthPolicy(result.Auth, false)
 	// Init watchers server list
-	for _, node := range result.Watch {
-		if conn, err := n.NodeBaseToConn(node); err == nil {
-			if node.Main {
-				n.wser.master = conn
-			} else {
-				n.wser.slaves = append(n.wser.slaves, conn)
+	n.UpWatchList(&pb.WatcherList{List: result.Watch, Term: result.Term})
+	n.wser.uuid = n.Uuid
+	n.wser.name = n.Name
+	n.wser.procid = n.Pid
+	return nil
+}
+
+// update watchers server list, Main node is master watcher,
+// list of older elect term will be ignored
+func (n *NodeDetail) UpWatchList(data *pb.WatcherList) {
+	n.wser.mut.RLock()
+	var old = append([]*NodeConn{n.wser.master}, n.wser.slaves...)
+	n.wser.mut.RUnlock()
+
+	var master *NodeConn
+	var slaves = make([]*NodeConn, 0, len(data.List))
+	for _, node := range data.List {
+		// watcher api only by tcp or udp, same as watcher config,
+		// not use connection cached by uuid that may be http connection
+		var conn = sameWatchConn(old, node)
+		if conn == nil {
+			var err error
+			if conn, err = n.DialNode(&pb.NodeInfo{Uuid: node.Uuid, Name: node.Name, Host: node.Host,
+				Main: node.Main, Tport: node.Tport, Uport: node.Uport, Metadata: node.Metadata}); err != nil {
+				continue
 			}
 		}
+		if node.Main && master == nil {
+			master = conn
+		} else {
+			slaves = append(slaves, conn)
+		}
 	}
-	if n.wser.master == nil && len(n.wser.slaves) > 0 {
-		n.wser.master = n.wser.slaves[0]
-		n.wser.slaves = n.wser.slaves[1:]
+	if master == nil && len(slaves) > 0 {
+		master, slaves = slaves[0], slaves[1:]
+	}
+	if master == nil {
+		return
+	}
+	var list = append([]*NodeConn{master}, slaves...)
+
+	n.wser.mut.Lock()
+	defer n.wser.mut.Unlock()
+	if data.Term >= n.wser.term {
+		n.wser.term = data.Term
+		n.wser.master, n.wser.slaves = master, slaves
+		closeWatchConn(old, list)
+	} else {
+		closeWatchConn(list, old)
+	}
+}
+
+// watcher connection of same address was kept
+func sameWatchConn(list []*NodeConn, node *pb.NodeInfo) *NodeConn {
+	for _, conn := range list {
+		if conn != nil && conn.Uuid == node.Uuid && conn.Host == node.Host &&
+			conn.Tport == node.Tport && conn.Uport == node.Uport && !conn.isClosed() {
+			return conn
+		}
 	}
-	n.wser.uuid = n.Uuid
-	n.wser.procid = n.Pid
 	return nil
 }
 
+// close connection of list not in keep
+func closeWatchConn(list, keep []*NodeConn) {
+	for _, conn := range list {
+		var used = conn == nil
+		for _, row := range keep {
+			used = used || row == conn
+		}
+		if !used {
+			conn.Close()
+		}
+	}
+}
+
 // send hearbeats to watcher node
 func (w *WatchNode) Heartbeats() error {
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
@@ -146,63 +223,119 @@ func (w *WatchNode) GetNodeMsg(ctx context.Context, uuid string, name string) (*
 // request watcher server api
 func (w *WatchNode) GetApiConn(ctx context.Context, fid uint32, name string) (*pb.GetApiConnRsp, error) {
 	var result = &pb.GetApiConnRsp{}
-	bts, err := proto.Marshal(&pb.GetApiConnReq{FuncID: fid, ApiName: name})
+	bts, err := proto.Marshal(&pb.GetApiConnReq{FuncID: fid, ApiName: name, Uuid: w.uuid})
 	if err != nil {
 		return result, err
 	}
 	return result, w.MasterCall(ctx, comm.GetApiConn, bts, result)
 }
 
+// query all provider nodes of api with health state, not subscribe api node change
+func (w *WatchNode) GetApiConnAll(ctx context.Context, fid uint32, name string) (*pb.GetApiConnRsp, error) {
+	var result = &pb.GetApiConnRsp{}
+	bts, err := proto.Marshal(&pb.GetApiConnReq{FuncID: fid, ApiName: name, Uuid: w.uuid, All: true})
+	if err != nil {
+		return result, err
+	}
+	return result, w.MasterCall(ctx, comm.GetApiConn, bts, result)
+}
+
+// query watchers server list, Main node is master watcher
+func (w *WatchNode) GetWatcher(ctx context.Context) (*pb.WatcherList, error) {
+	var result = &pb.WatcherList{}
+	return result, w.MasterCall(ctx, comm.GetWatcher, nil, result)
+}
+
+// delete api of watcher, function id will be reused later,
+// only verified admin of watcher can delete the api without alive provider node
+func (w *WatchNode) DeleteApi(ctx context.Context, fid uint32, name string, force bool) (*pb.DeleteApiRsp, error) {
+	var result = &pb.DeleteApiRsp{}
+	bts, err := proto.Marshal(&pb.DeleteApiReq{FuncID: fid, ApiName: name, Force: force})
+	if err != nil {
+		return result, err
+	}
+	return result, w.MasterCall(ctx, comm.DeleteApi, bts, result)
+}
+
+// delete this node from watcher, api nodes was pushed to subscribed nodes at once
+func (w *WatchNode) Deregister(ctx context.Context) error {
+	bts, err := w.sign.marshal(&pb.NodeInfo{Uuid: w.uuid, Name: w.name, Pid: w.procid})
+	if err != nil {
+		return err
+	}
+	return w.MasterCall(ctx, comm.Deregister, bts, nil)
+}
+
+// close connections of watcher nodes
+func (w *WatchNode) close() {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if w.master != nil {
+		w.master.Close()
+	}
+	for _, row := range w.slaves {
+		row.Close()
+	}
+}
+
 // request watcher by master node
 func (w *WatchNode) MasterCall(ctx context.Context, fid int, bts []byte, rsp interface{}) error {
-	if w.master == nil || (w.master.tconn == nil && w.master.uconn == nil) {
+	w.mut.RLock()
+	var master = w.master
+	w.mut.RUnlock()
+	if master == nil || (master.tconn == nil && master.uconn == nil) {
 		return w.SlavesCall(ctx, fid, bts, rsp)
 	}
-	var num = int(atomic.AddUint32(&w.count, 1) % 65535)
-	switch w.master.types {
+	switch master.types {
 	case ConnWithTCP:
-		var tcpRow = tcpsplit.MakeReqBody(bts, num, fid)
-		for _, row := range tcpRow {
-			if _, err := w.master.tconn.Write(row); err != nil {
-				return w.SlavesCall(ctx, fid, bts, rsp)
-			}
+		c, err := master.NewCall()
+		if err != nil {
+			return w.SlavesCall(ctx, fid, bts, rsp)
+		} else if err = master.WriteTCP(bts, c.num, fid); err != nil {
+			master.DelChan(c.num)
+			return w.SlavesCall(ctx, fid, bts, rsp)
 		}
-		return w.master.ProtoWaitRsp(ctx, num, rsp)
+		return master.ProtoWaitRsp(ctx, c, rsp)
 
 	case ConnWithUDP:
-		var udpRow = udpsplit.MakeReqBody(bts, num, fid)
-		for _, row := range udpRow {
-			if _, err := w.master.uconn.Write(row); err != nil {
-				return w.SlavesCall(ctx, fid, bts, rsp)
-			}
+		c, err := master.NewCall()
+		if err != nil {
+			return w.SlavesCall(ctx, fid, bts, rsp)
+		} else if err = master.WriteUDP(bts, c.num, fid); err != nil {
+			master.DelChan(c.num)
+			return w.SlavesCall(ctx, fid, bts, rsp)
 		}
-		return w.master.ProtoWaitRsp(ctx, num, rsp)
+		return master.ProtoWaitRsp(ctx, c, rsp)
 	}
 	return w.SlavesCall(ctx, fid, bts, rsp)
 }
 
 // request watcher by slave node
 func (w *WatchNode) SlavesCall(ctx context.Context, fid int, bts []byte, rsp interface{}) error {
-	var num = int(atomic.AddUint32(&w.count, 1) % 65535)
-	for _, wser := range w.slaves {
+	w.mut.RLock()
+	var slaves = w.slaves
+	w.mut.RUnlock()
+	for _, wser := range slaves {
 		switch wser.types {
 		case ConnWithTCP:
-			var tcpRow = tcpsplit.MakeReqBody(bts, num, fid)
-			for _, row := range tcpRow {
-				if _, err := wser.tconn.Write(row); err != nil {
-					goto NextNode
-				}
+			c, err := wser.NewCall()
+			if err != nil {
+				goto NextNode
+			} else if err = wser.WriteTCP(bts, c.num, fid); err != nil {
+				wser.DelChan(c.num)
+				goto NextNode
 			}
-			return wser.ProtoWaitRsp(ctx, num, rsp)
+			return wser.ProtoWaitRsp(ctx, c, rsp)
 
 		case ConnWithUDP:
-			var udpRow = udpsplit.MakeReqBody(bts, num, fid)
-			for _, row := range udpRow {
-				if _, err := wser.uconn.Write(row); err != nil {
-					goto NextNode
-				}
+			c, err := wser.NewCall()
+			if err != nil {
+				goto NextNode
+			} else if err = wser.WriteUDP(bts, c.num, fid); err != nil {
+				wser.DelChan(c.num)
+				goto NextNode
 			}
-			return wser.ProtoWaitRsp(ctx, num, rsp)
+			return wser.ProtoWaitRsp(ctx, c, rsp)
 		}
 	NextNode:
 	}
@@ -210,12 +343,12 @@ func (w *WatchNode) SlavesCall(ctx context.Context, fid int, bts []byte, rsp int
 }
 
 // wait proto protocal response
-func (n *NodeConn) ProtoWaitRsp(ctx context.Context, num int, rsp interface{}) error {
-	var c = n.NewChan(num)
-	defer n.DelChan(num)
+func (n *NodeConn) ProtoWaitRsp(ctx context.Context, c *RecvChan, rsp interface{}) error {
+	defer n.DelChan(c.num)
 
 	select {
 	case <-ctx.Done():
+		n.sendCancel(c.num)
 		return ctx.Err()
 	case buff := <-c.body:
 		if len(buff) == 0 || rsp == nil {
@@ -233,10 +366,11 @@ func (n *NodeConn) ProtoWaitRsp(ctx context.Context, num int, rsp interface{}) e
 
 // gen system env detail
 func (w *WatchNode) SystemState() *pb.SystemStatus {
-	var result = &pb.SystemStatus{Uuid: w.uuid}
-	// if cpu, err := monitor.CpuStat(); err == nil && cpu != nil {
-	// 	result.CpuRate = cpu.Rate
-	// }
+	var result = &pb.SystemStatus{Uuid: w.uuid, State: w.servingState(),
+		AuthVersion: atomic.LoadUint64(&w.authver)}
+	if cpu, err := monitor.CpuStat(); err == nil && cpu != nil {
+		result.CpuRate = cpu.Rate
+	}
 	if mem, err := monitor.MemStat(); err == nil && mem != nil {
 		result.MemFree = mem.Free
 		result.MemUsed = mem.Used
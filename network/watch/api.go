@@ -1,11 +1,17 @@
 package watch
 
 import (
+	"context"
 	"errors"
 	"log"
+	"time"
 
 	"micro/network"
+	"micro/network/comm"
 	"micro/network/pb"
+	"micro/network/rpc"
+
+	"google.golang.org/protobuf/proto"
 )
 
 type WatchApi struct {
@@ -16,6 +22,18 @@ type WatchApi struct {
 
 	msg *WatchDetail
 	api network.NodeApi
+	// check signature of register message
+	verify func(*pb.NodeInfo) error
+	// authorization policy of api
+	auth *authFile
+	// verified callers allowed to call admin api
+	admin map[string]bool
+	// verified caller of the request
+	caller func(context.Context) string
+	// node registered by the connection of the request
+	peer func(context.Context) *pb.NodeInfo
+	// register message was signed and verified
+	signed bool
 }
 
 func (w *WatchApi) Registered(req *pb.NodeInfo, rsp *pb.RegisteredRsp) error {
@@ -23,20 +41,32 @@ func (w *WatchApi) Registered(req *pb.NodeInfo, rsp *pb.RegisteredRsp) error {
 
 	if req.Uuid == "" {
 		return errors.New("node server uuid cannot be null")
+	} else if err := w.verify(req); err != nil {
+		return err
+	} else if w.signed {
+		if err = w.checkSigner(req); err != nil {
+			return err
+		}
+	}
+	if err := w.forward(comm.Registered, req, rsp); err != errNotForward {
+		return err
 	}
+	req.Sign = nil
 
-	// TODO:
-	// if w.Center() || len(w.watch) <= 1 {
+	var changed []uint32
 	for _, v := range req.Funcs {
-		if w.fmsg.PutApi(req.Uuid, v) != nil {
-			// TODO: 通知所有节点
-			// w.api.SendAutoAll(pb.WatchFmsg.GetFuncMsg())
+		had := w.fmsg.GetStr(v.Name).HasNode(req.Uuid)
+		if err := w.fmsg.PutApi(req.Uuid, v); err != nil {
+			return err
+		} else if !had {
+			changed = append(changed, v.ID)
 		}
 		rsp.Funcs = append(rsp.Funcs, v)
 	}
-	// rsp.MainUuid = w.base.Uuid
-	// }
 	w.node.PutNodeDetail(req)
+	w.msg.upIndex()
+	// notify nodes which used these api
+	go w.notifyApiConn(changed...)
 
 	// go w.msg.RangeNodes(func(node *NodeMsg) bool {
 	// 	if !node.state || node.base.Uuid == req.Uuid {
@@ -53,16 +83,31 @@ func (w *WatchApi) Registered(req *pb.NodeInfo, rsp *pb.RegisteredRsp) error {
 	// 	return true
 	// })
 
-	for _, row := range w.msg.watch {
-		rsp.Watch = append(rsp.Watch, &pb.NodeInfo{
-			Uuid: row.Uuid, Main: row.Main,
-			Host: row.Host, Tport: row.Tport,
-			Uport: row.Uport, Hport: row.Hport,
-		})
-	}
+	var list = w.msg.watchList()
+	rsp.Watch, rsp.Term = list.List, list.Term
+	rsp.Auth = w.auth.policy()
 	return nil
 }
 
+var errNotForward = errors.New("request not forward")
+
+// follower watcher forward request to master watcher,
+// return errNotForward when this node is master,
+// request is refused when master unknown or lost majority
+func (w *WatchApi) forward(fid uint32, req proto.Message, rsp interface{}) error {
+	leader, err := w.msg.leader()
+	if err != nil {
+		return err
+	} else if leader == nil {
+		return errNotForward
+	}
+	bts, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return w.msg.peerCall(leader, fid, bts, rsp)
+}
+
 // // send data to master watcher node
 // func (w WatchApi) NewStrcut(req *pb.NodeInfo) error {
 // 	var servname = make(map[string]bool)
@@ -77,10 +122,50 @@ func (w *WatchApi) Registered(req *pb.NodeInfo, rsp *pb.RegisteredRsp) error {
 // server node timer send local message to watcher
 func (w *WatchApi) Heartbeats(req *pb.SystemStatus) error {
 	log.Println("Heartbeats: ", req)
-	if w.node.UpHeartbeat(req) {
-		return nil
+	if err := w.forward(comm.Heartbeats, req, nil); err != errNotForward {
+		return err
+	}
+	found, changed := w.node.UpHeartbeat(req)
+	if !found {
+		return errors.New("no found this node")
+	}
+	if req.AuthVersion != w.auth.policy().Version {
+		// policy push was missed
+		go w.pushPolicy(req.Uuid)
+	}
+	if changed {
+		// health state changed, update api node list of subscribed nodes
+		w.msg.upIndex()
+		go w.notifyApiConn(w.fmsg.NodeFuncs(req.Uuid)...)
+	}
+	return nil
+}
+
+// server node shutdown to delete it, notify the nodes which used its api,
+// the node and process id must be same as registered, response the deleted node
+func (w *WatchApi) Deregister(ctx context.Context, req *pb.NodeInfo, rsp *pb.NodeInfo) error {
+	log.Println("Deregister: ", req)
+	if req.Uuid == "" {
+		return errors.New("node server uuid cannot be null")
+	} else if err := w.verify(req); err != nil {
+		return err
+	} else if err = w.checkOwner(ctx, req); err != nil {
+		return err
+	}
+	if err := w.forward(comm.Deregister, req, rsp); err != errNotForward {
+		return err
+	}
+	node := w.node.GetNodeByUuid(req.Uuid)
+	if node == nil || node.Pid != req.Pid {
+		return errors.New("no found this node")
+	} else if !w.node.DelNode(req.Uuid) {
+		return errors.New("no found this node")
 	}
-	return errors.New("no found this node")
+	proto.Merge(rsp, node)
+	fids := w.fmsg.RemoveNodes([]string{req.Uuid})
+	w.msg.upIndex()
+	go w.notifyApiConn(fids...)
+	return nil
 }
 
 // get server node message
@@ -114,9 +199,14 @@ func (w *WatchApi) GetNodeMsg(req *pb.GetNodeMsgReq, rsp *pb.GetNodeMsgRsp) erro
 	return errors.New("not found this server node")
 }
 
-// get server address array by name
+// get server address array by name, only SERVING node unless request all,
+// request all node was not subscribed
 func (w *WatchApi) GetApiConn(req *pb.GetApiConnReq, rsp *pb.GetApiConnRsp) error {
 	defer log.Println("GetApiConn: ", req, rsp)
+	// subscribe api connection on master watcher
+	if err := w.forward(comm.GetApiConn, req, rsp); err != errNotForward {
+		return err
+	}
 
 	if req.GetFuncID() != 0 {
 		rsp.Func = w.fmsg.GetIds(req.FuncID).GetMsg()
@@ -124,14 +214,134 @@ func (w *WatchApi) GetApiConn(req *pb.GetApiConnReq, rsp *pb.GetApiConnRsp) erro
 		rsp.Func = w.fmsg.GetStr(req.ApiName).GetMsg()
 	}
 	if data := w.fmsg.GetIds(rsp.GetFunc().GetFuncID()); data != nil {
-		var ids []string
-		for _, id := range data.node {
-			if tmp := w.node.GetNodeByUuid(id); tmp != nil {
-				rsp.List = append(rsp.List, tmp)
-				ids = append(ids, id)
+		if !req.All && !data.HasSub(req.GetUuid()) {
+			data.Subscribe(req.GetUuid())
+			w.msg.upIndex()
+		}
+		w.apiConn(data, rsp, req.All)
+	}
+	return nil
+}
+
+// make server api node connection list, skip node not SERVING unless all
+func (w *WatchApi) apiConn(data *funcdata, rsp *pb.GetApiConnRsp, all bool) {
+	for _, id := range data.GetUuid() {
+		tmp := w.node.GetNodeByUuid(id)
+		if tmp == nil {
+			continue
+		}
+		stat := w.node.GetStatByUuid(id)
+		if !all && stat.GetState() != pb.ServingState_SERVING {
+			continue
+		}
+		rsp.List = append(rsp.List, tmp)
+		if stat != nil {
+			rsp.Stat = append(rsp.Stat, stat)
+		}
+	}
+}
+
+// push server api node connection to the nodes which requested it
+func (w *WatchApi) notifyApiConn(fids ...uint32) {
+	if w.msg.call == nil {
+		return
+	}
+	for _, fid := range fids {
+		data := w.fmsg.GetIds(fid)
+		if data == nil {
+			continue
+		}
+		var rsp = &pb.GetApiConnRsp{Func: data.msg}
+		w.apiConn(data, rsp, false)
+		w.pushApiConn(data, rsp)
+	}
+}
+
+func (w *WatchApi) pushApiConn(data *funcdata, rsp *pb.GetApiConnRsp) {
+	bts, err := proto.Marshal(rsp)
+	if err != nil {
+		log.Println("notifyApiConn: ", err)
+		return
+	}
+	data.RangeSubs(func(uuid string) bool {
+		go func(id string) {
+			if err := w.msg.call.WatchSend(time.Second*3, id, comm.UpNodeConnMsg, bts); err != nil {
+				log.Println("notifyApiConn: ", id, err)
 			}
+		}(uuid)
+		return true
+	})
+}
+
+// admin request to delete function id, it will be reused after ApiReuseDelay,
+// force to delete the api which provider nodes were not alive
+func (w *WatchApi) DeleteApi(ctx context.Context, req *pb.DeleteApiReq, rsp *pb.DeleteApiRsp) error {
+	log.Println("DeleteApi: ", req, w.caller(ctx))
+	if err := w.checkAdmin(ctx); err != nil {
+		return err
+	} else if err = w.forward(comm.DeleteApi, req, rsp); err != errNotForward {
+		return err
+	}
+
+	var data *funcdata
+	if req.GetFuncID() != 0 {
+		data = w.fmsg.GetIds(req.FuncID)
+	} else if req.GetApiName() != "" {
+		data = w.fmsg.GetStr(req.ApiName)
+	}
+	for _, uuid := range data.GetUuid() {
+		if w.node.Alive(uuid) {
+			return errors.New("api has alive provider node: " + uuid)
 		}
-		data.node = ids
+	}
+	if err := w.deleteApi(data, req.Force); err != nil {
+		return err
+	}
+	rsp.Func = data.api
+	return nil
+}
+
+// signed node message only accepted when signed name is same as registered node,
+// node message without signature only accepted by the connection registered the node,
+// or forwarded by other watcher of cluster
+func (w *WatchApi) checkOwner(ctx context.Context, req *pb.NodeInfo) error {
+	if w.signed {
+		return w.checkSigner(req)
+	}
+	if peer := w.peer(ctx); peer != nil && (peer.Uuid == req.Uuid || w.msg.isPeer(peer.Uuid)) {
+		return nil
+	}
+	return rpc.Errorf(rpc.CodePermissionDenied, "node %s was not registered by the connection", req.Uuid)
+}
+
+// signed name must be same as registered node, node not found was checked by master watcher
+func (w *WatchApi) checkSigner(req *pb.NodeInfo) error {
+	if node := w.node.GetNodeByUuid(req.Uuid); node != nil && node.Name != req.Name {
+		return rpc.Errorf(rpc.CodePermissionDenied, "node %s was not registered by %s", req.Uuid, req.Name)
+	}
+	return nil
+}
+
+// admin api only called by verified admin, or forwarded by verified watcher
+func (w *WatchApi) checkAdmin(ctx context.Context) error {
+	caller := w.caller(ctx)
+	if caller != "" && (w.admin[caller] || caller == comm.WatchNodeName) {
+		return nil
+	} else if caller == "" {
+		caller = "unverified"
+	}
+	return rpc.Errorf(rpc.CodePermissionDenied, "caller %s was not admin", caller)
+}
+
+// delete api and push empty node list to the nodes which requested it
+func (w *WatchApi) deleteApi(data *funcdata, force bool) error {
+	if err := w.fmsg.Delete(data, force); err != nil {
+		return err
+	}
+	log.Println("delete api: ", data.api)
+	w.msg.upIndex()
+	if w.msg.call != nil {
+		go w.pushApiConn(data, &pb.GetApiConnRsp{Func: data.msg, Deleted: true})
 	}
 	return nil
 }
@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"micro/network/comm"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// authorization policy file of api, reload when it was modified
+//
+//	{"Rules": [{"Caller": "Gateway", "Apis": ["Billing.*"]}]}
+type authFile struct {
+	name string
+	mut  sync.RWMutex
+	mod  time.Time
+	size int64
+	data *pb.AuthPolicy
+}
+
+func newAuthFile(name string) (*authFile, error) {
+	var result = &authFile{name: name, data: &pb.AuthPolicy{}}
+	if name == "" {
+		return result, nil
+	}
+	if _, err := result.reload(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *authFile) policy() *pb.AuthPolicy {
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+	return a.data
+}
+
+// read policy file again when it was modified, return true when rules changed,
+// deleted file is empty policy
+func (a *authFile) reload() (bool, error) {
+	if a.name == "" {
+		return false, nil
+	}
+	var data = &pb.AuthPolicy{}
+	stat, err := os.Stat(a.name)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	} else if err == nil {
+		a.mut.RLock()
+		same := stat.ModTime().Equal(a.mod) && stat.Size() == a.size
+		a.mut.RUnlock()
+		if same {
+			return false, nil
+		}
+		bts, err := ioutil.ReadFile(a.name)
+		if err != nil {
+			return false, err
+		} else if err = json.Unmarshal(bts, data); err != nil {
+			return false, err
+		}
+		if len(data.Rules) > 0 {
+			bts, _ = proto.MarshalOptions{Deterministic: true}.Marshal(&pb.AuthPolicy{Rules: data.Rules})
+			h := fnv.New64a()
+			h.Write(bts)
+			data.Version = h.Sum64()
+		}
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	if stat != nil {
+		a.mod, a.size = stat.ModTime(), stat.Size()
+	} else {
+		a.mod, a.size = time.Time{}, 0
+	}
+	if data.Version == a.data.Version {
+		return false, nil
+	}
+	a.data = data
+	return true, nil
+}
+
+// push authorization policy to the nodes, all registered nodes when uuids is null
+func (w *WatchApi) pushPolicy(uuids ...string) {
+	if w.msg.call == nil {
+		return
+	}
+	if len(uuids) == 0 {
+		for _, node := range w.node.GetNodeList() {
+			uuids = append(uuids, node.Uuid)
+		}
+	}
+	bts, err := proto.Marshal(w.auth.policy())
+	if err != nil {
+		log.Println("pushPolicy: ", err)
+		return
+	}
+	for _, uuid := range uuids {
+		go func(id string) {
+			if err := w.msg.call.WatchSend(time.Second*3, id, comm.UpAuthPolicy, bts); err != nil {
+				log.Println("pushPolicy: ", id, err)
+			}
+		}(uuid)
+	}
+}
+
+// reload policy file, push to nodes when changed
+func (w *WatchApi) reloadPolicy() {
+	if changed, err := w.auth.reload(); err != nil {
+		log.Println("reload auth policy: ", err)
+	} else if changed && w.msg.Center() {
+		log.Println("reload auth policy: ", w.auth.policy())
+		w.pushPolicy()
+	}
+}
@@ -1,82 +1,418 @@
 package watch
 
 import (
+	"context"
 	"errors"
+	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"micro/network/comm"
 	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
 )
 
+// 发现节点集群选举，一个任期内最多一个主节点，
+// 主节点定时向从节点发送心跳并同步数据，从节点心跳超时后发起竞选
 type WatchElect struct {
-	center bool   // 是否为主节点，中心节点
-	begin  int64  // notify send timestamp
-	exprie int64  // end main elect timestamp
-	nodeId string // 发起的节点编号
-	list   []*ElectCampRsp
+	mut     sync.Mutex
+	center  bool          // 是否为主节点，中心节点
+	term    uint64        // 选举任期
+	vote    string        // 当前任期投票的节点编号
+	nodeId  string        // 主节点编号
+	index   uint64        // 状态数据版本号，数据变更时递增
+	stamp   int64         // 最后收到主节点心跳时间戳
+	lease   int64         // 主节点最后一次得到多数节点确认的时间戳，超过选举超时时长不接受写请求
+	exprie  int64         // 本轮选举超时时长(毫秒)，随机避免同时竞选
+	timeout time.Duration // 选举超时时长
+	running int32         // 选举定时任务运行中
+	restore sync.Mutex    // 从节点写入同步数据，不持有 mut 避免磁盘操作阻塞选举
 }
 
-type ElectCampRsp struct {
-	NodeBase  *pb.NodeInfo
-	SystemMsg *pb.SystemStatus
+func (e *WatchElect) resetTimeout() {
+	e.stamp = time.Now().UnixMilli()
+	e.exprie = e.timeout.Milliseconds() + rand.Int63n(e.timeout.Milliseconds()+1)
 }
 
+// 当前节点是否为主节点
 func (w *WatchDetail) Center() bool {
+	w.elect.mut.Lock()
+	defer w.elect.mut.Unlock()
 	return w.elect.center
 }
 
-type ElectCampReq struct {
-	Base   *pb.NodeInfo
-	Uuids  []string
-	Begin  int64
-	Exprie int64
+// 状态数据变更，递增版本号
+func (w *WatchDetail) upIndex() {
+	w.elect.mut.Lock()
+	w.elect.index++
+	w.elect.mut.Unlock()
+}
+
+var errNoMaster = errors.New("master watcher unknown or lost majority")
+
+// 获取主节点，当前节点为主节点时返回 nil，
+// 主节点未知或者当前主节点超过选举超时时长没有得到多数节点确认时返回错误
+func (w *WatchDetail) leader() (*WatchNode, error) {
+	w.elect.mut.Lock()
+	center, uuid, lease := w.elect.center, w.elect.nodeId, w.elect.lease
+	w.elect.mut.Unlock()
+	if center {
+		if len(w.watch) > 1 && time.Now().UnixMilli()-lease > w.elect.timeout.Milliseconds() {
+			return nil, errNoMaster
+		}
+		return nil, nil
+	}
+	for _, peer := range w.peers() {
+		if uuid != "" && peer.uuid() == uuid {
+			return peer, nil
+		}
+	}
+	return nil, errNoMaster
+}
+
+// 其他发现节点
+func (w *WatchDetail) peers() []*WatchNode {
+	var result = make([]*WatchNode, 0, len(w.watch))
+	for _, row := range w.watch {
+		if !row.self {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// 是否为集群中其他发现节点
+func (w *WatchDetail) isPeer(uuid string) bool {
+	for _, peer := range w.peers() {
+		if uuid != "" && peer.uuid() == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// 发现节点列表，Main 为主节点
+func (w *WatchDetail) watchList() *pb.WatcherList {
+	w.elect.mut.Lock()
+	var result = &pb.WatcherList{Term: w.elect.term, Base: w.base}
+	var leader = w.elect.nodeId
+	w.elect.mut.Unlock()
+
+	for _, row := range w.watch {
+		uuid := row.uuid()
+		if uuid == "" {
+			continue
+		}
+		result.List = append(result.List, &pb.NodeInfo{
+			Uuid: uuid, Name: row.Name, Main: uuid == leader,
+			Host: row.Host, Tport: row.Tport,
+			Uport: row.Uport, Hport: row.Hport,
+		})
+	}
+	return result
 }
 
-func (w *WatchDetail) Elect() {
-	now := time.Now().UnixMilli()
-	if w.elect.exprie > now {
+// 连接其他发现节点，获取节点编号
+func (w *WatchDetail) connPeers() {
+	if w.call == nil {
 		return
 	}
+	var wg sync.WaitGroup
+	for _, peer := range w.peers() {
+		if peer.getConn() != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(peer *WatchNode) {
+			defer wg.Done()
+			conn, err := w.call.DialNode(&pb.NodeInfo{Host: peer.Host,
+				Tport: peer.Tport, Uport: peer.Uport, Hport: peer.Hport})
+			if err != nil {
+				return
+			}
+			bts, _ := proto.Marshal(w.base)
+			var rsp = &pb.WatcherList{}
+			ctx, cancel := context.WithTimeout(context.TODO(), w.elect.timeout)
+			defer cancel()
+			if err = w.call.ConnCall(ctx, conn, comm.WatchFmsg.GetWatcher(),
+				bts, rsp); err != nil || rsp.GetBase().GetUuid() == "" {
+				conn.Close()
+				return
+			}
+			peer.setConn(conn, rsp.Base)
+		}(peer)
+	}
+	wg.Wait()
+}
+
+// 请求其他发现节点
+func (w *WatchDetail) peerCall(peer *WatchNode, fid uint32, req []byte, rsp interface{}) error {
+	if w.send != nil {
+		return w.send(peer, fid, req, rsp)
+	}
+	conn := peer.getConn()
+	if conn == nil {
+		return errors.New("watcher node not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), w.elect.timeout)
+	defer cancel()
+	err := w.call.ConnCall(ctx, conn, comm.WatchFmsg.Fmsg[fid], req, rsp)
+	if err != nil {
+		peer.setConn(nil, nil)
+	}
+	return err
+}
 
-	w.elect.center, w.elect.nodeId = false, w.base.Uuid
-	w.elect.begin, w.elect.exprie = now, now+10_000
-	var tmp = &ElectCampReq{
-		Base: w.base, Begin: now, Exprie: w.elect.exprie}
+// 发现更高任期，成为从节点
+func (w *WatchDetail) stepDown(term uint64, leader string) {
+	w.elect.mut.Lock()
+	defer w.elect.mut.Unlock()
+	if term > w.elect.term {
+		w.elect.term, w.elect.vote = term, ""
+		w.elect.center, w.elect.nodeId = false, leader
+		w.elect.resetTimeout()
+	}
+}
+
+// 选举定时任务，主节点同步数据，从节点超时发起竞选
+func (w *WatchApi) electTick() {
+	e := w.msg.elect
+	if !atomic.CompareAndSwapInt32(&e.running, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&e.running, 0)
 
+	w.msg.connPeers()
+	e.mut.Lock()
+	center := e.center
+	timeout := time.Now().UnixMilli()-e.stamp > e.exprie
+	e.mut.Unlock()
+	if center {
+		w.syncPeers()
+	} else if timeout {
+		w.campaign()
+	}
+}
+
+// 发起竞选，获得多数节点投票成为主节点
+func (w *WatchApi) campaign() {
+	e := w.msg.elect
+	e.mut.Lock()
+	e.term++
+	e.center, e.vote, e.nodeId = false, w.msg.base.Uuid, ""
+	e.resetTimeout()
+	var req = &pb.ElectVoteReq{Term: e.term, Index: e.index, Base: w.msg.base}
+	e.mut.Unlock()
+
+	bts, err := proto.Marshal(req)
+	if err != nil {
+		return
+	}
+	var votes int32 = 1
+	var wg sync.WaitGroup
+	for _, peer := range w.msg.peers() {
+		wg.Add(1)
+		go func(peer *WatchNode) {
+			defer wg.Done()
+			var rsp = &pb.ElectVoteRsp{}
+			if w.msg.peerCall(peer, comm.ElectVote, bts, rsp) != nil {
+				return
+			}
+			if rsp.Grant {
+				atomic.AddInt32(&votes, 1)
+			} else if rsp.Term > req.Term {
+				w.msg.stepDown(rsp.Term, "")
+			}
+		}(peer)
+	}
+	wg.Wait()
+
+	if int(votes)*2 > len(w.msg.watch) {
+		w.becomeLeader(req.Term)
+	}
+}
+
+// 成为主节点，通知所有服务节点更新发现节点列表
+func (w *WatchApi) becomeLeader(term uint64) {
+	e := w.msg.elect
+	e.mut.Lock()
+	if e.term != term || e.vote != w.msg.base.Uuid {
+		e.mut.Unlock()
+		return
+	}
+	e.center, e.nodeId = true, w.msg.base.Uuid
+	e.lease = time.Now().UnixMilli()
+	e.mut.Unlock()
+	log.Println("elect master watcher, term: ", term)
+
+	// server nodes send heartbeat to the old master, wait them to retarget
+	w.node.ResetStamp()
+	w.syncPeers()
+	w.notifyWatcher()
+}
+
+// 主节点心跳，从节点数据版本不同时同步全部数据
+func (w *WatchApi) syncPeers() {
+	e := w.msg.elect
+	e.mut.Lock()
+	var term, index = e.term, e.index
+	e.mut.Unlock()
+
+	var start = time.Now().UnixMilli()
+	var acks int32 = 1
+	var state *pb.WatchState
+	var once sync.Once
 	var wg sync.WaitGroup
-	for _, n := range w.watch {
-		if n.status {
-			tmp.Uuids = append(tmp.Uuids, n.Uuid)
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				var rsp = &ElectCampRsp{}
-				if w.call.CallByUuid(n.Uuid, "WatchApi.ElectNotify", tmp, rsp) == nil {
-					w.elect.list = append(w.elect.list, rsp)
-				}
-			}()
+	for _, peer := range w.msg.peers() {
+		var req = &pb.ElectSyncReq{Term: term, Index: index, Base: w.msg.base}
+		if peer.getIndex() != index {
+			once.Do(func() { state = w.state() })
+			req.State = state
+		}
+		bts, err := proto.Marshal(req)
+		if err != nil {
+			continue
 		}
+		wg.Add(1)
+		go func(peer *WatchNode) {
+			defer wg.Done()
+			var rsp = &pb.ElectSyncRsp{}
+			if w.msg.peerCall(peer, comm.ElectSync, bts, rsp) != nil {
+				return
+			}
+			if rsp.Term > term {
+				w.msg.stepDown(rsp.Term, "")
+			} else if rsp.Success {
+				peer.setIndex(rsp.Index)
+				atomic.AddInt32(&acks, 1)
+			}
+		}(peer)
 	}
 	wg.Wait()
+	w.msg.renewLease(term, start, int(acks)*2 > len(w.msg.watch))
+}
+
+// 主节点心跳得到多数节点确认时续约，超过选举超时时长没有续约则退位，避免脑裂
+func (w *WatchDetail) renewLease(term uint64, start int64, majority bool) {
+	e := w.elect
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	if !e.center || e.term != term {
+		return
+	} else if majority {
+		e.lease = start
+	} else if time.Now().UnixMilli()-e.lease > e.timeout.Milliseconds() {
+		log.Println("master watcher lost majority, step down, term: ", term)
+		e.center, e.nodeId = false, ""
+		e.resetTimeout()
+	}
+}
+
+// 推送发现节点列表到所有服务节点
+func (w *WatchApi) notifyWatcher() {
+	if w.msg.call == nil {
+		return
+	}
+	bts, err := proto.Marshal(w.msg.watchList())
+	if err != nil {
+		return
+	}
+	for _, node := range w.node.GetNodeList() {
+		go func(node *pb.NodeInfo) {
+			if _, err := w.msg.call.NodeBaseToConn(node); err != nil {
+				log.Println("notifyWatcher: ", node.Uuid, err)
+			} else if err = w.msg.call.WatchSend(time.Second*3,
+				node.Uuid, comm.UpWatcherList, bts); err != nil {
+				log.Println("notifyWatcher: ", node.Uuid, err)
+			}
+		}(node)
+	}
+}
+
+// 需要同步的状态数据
+func (w *WatchApi) state() *pb.WatchState {
+	var result = &pb.WatchState{Funcs: w.fmsg.Snapshot(), Tomb: w.fmsg.Tombs()}
+	result.Nodes, result.Stat = w.node.Snapshot()
+	return result
+}
+
+// 获取发现节点列表
+func (w *WatchApi) GetWatcher(req *pb.NodeInfo, rsp *pb.WatcherList) error {
+	var list = w.msg.watchList()
+	rsp.List, rsp.Term, rsp.Base = list.List, list.Term, list.Base
+	return nil
 }
 
-// 接收主节点推荐竞选通知，并返回服务器状态
-func (w WatchApi) ElectNotify(req *ElectCampReq, rsp *ElectCampRsp) error {
-	now := time.Now().UnixMilli()
-	msg := w.msg.elect
-	if msg.exprie > now && msg.begin < req.Begin {
-		return errors.New("have other campaign")
+// 接收竞选投票请求，每个任期只投票一次，不投票给数据版本更旧的节点，
+// 选举超时时长内收到过主节点心跳时不投票，主节点租约期间不会选出新主节点
+func (w *WatchApi) ElectVote(req *pb.ElectVoteReq, rsp *pb.ElectVoteRsp) error {
+	uuid := req.GetBase().GetUuid()
+	if uuid == "" {
+		return errors.New("candidate uuid cannot be null")
 	}
 
-	msg.center, msg.nodeId = false, req.Base.Uuid
-	msg.begin, msg.exprie = req.Begin, req.Exprie
-	rsp.NodeBase = w.msg.base
-	rsp.SystemMsg = w.msg.SystemState()
+	e := w.msg.elect
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	if e.nodeId != "" && e.nodeId != uuid &&
+		time.Now().UnixMilli()-e.stamp <= e.timeout.Milliseconds() {
+		rsp.Term = e.term
+		return nil
+	} else if req.Term > e.term {
+		e.term, e.vote = req.Term, ""
+		e.center, e.nodeId = false, ""
+	}
+	rsp.Term = e.term
+	if req.Term == e.term && (e.vote == "" || e.vote == uuid) && req.Index >= e.index {
+		e.vote, rsp.Grant = uuid, true
+		e.resetTimeout()
+	}
 	return nil
 }
 
-// 更新本地发现节点数据
-func (w WatchApi) ElectResult(req *pb.NodeInfo) error {
+// 接收主节点心跳与同步数据
+func (w *WatchApi) ElectSync(req *pb.ElectSyncReq, rsp *pb.ElectSyncRsp) error {
+	uuid := req.GetBase().GetUuid()
+	if uuid == "" {
+		return errors.New("master uuid cannot be null")
+	}
+
+	e := w.msg.elect
+	e.mut.Lock()
+	if req.Term < e.term {
+		rsp.Term, rsp.Index = e.term, e.index
+		e.mut.Unlock()
+		return nil
+	} else if req.Term > e.term {
+		e.term, e.vote = req.Term, ""
+	}
+	e.center, e.nodeId = false, uuid
+	e.resetTimeout()
+	rsp.Term, rsp.Index, rsp.Success = e.term, e.index, true
+	e.mut.Unlock()
+	if req.State == nil {
+		return nil
+	}
 
+	// registry file was rewritten, hold the restore lock only
+	e.restore.Lock()
+	defer e.restore.Unlock()
+	e.mut.Lock()
+	stale := e.term != req.Term
+	e.mut.Unlock()
+	if !stale {
+		w.fmsg.Restore(req.State.Funcs, req.State.Tomb)
+		w.node.Restore(req.State.Nodes, req.State.Stat)
+	}
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	if !stale && e.term == req.Term {
+		e.index = req.Index
+	}
+	rsp.Term, rsp.Index, rsp.Success = e.term, e.index, e.term == req.Term
 	return nil
 }
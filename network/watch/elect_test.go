@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"micro/network/comm"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// watcher cluster in memory, peer request was called directly
+type testCluster struct {
+	mut  sync.Mutex
+	list map[string]*WatchApi
+	down map[string]bool // network partition of the watcher
+}
+
+func (c *testCluster) isDown(uuid string) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.down[uuid]
+}
+
+func (c *testCluster) setDown(uuid string, down bool) {
+	c.mut.Lock()
+	c.down[uuid] = down
+	c.mut.Unlock()
+}
+
+func (c *testCluster) send(from string) func(*WatchNode, uint32, []byte, interface{}) error {
+	return func(peer *WatchNode, fid uint32, req []byte, rsp interface{}) error {
+		to := c.list[peer.uuid()]
+		if to == nil || c.isDown(from) || c.isDown(peer.uuid()) {
+			return errors.New("watcher node not connected")
+		}
+		switch fid {
+		case comm.ElectVote:
+			var arg = &pb.ElectVoteReq{}
+			proto.Unmarshal(req, arg)
+			return to.ElectVote(arg, rsp.(*pb.ElectVoteRsp))
+		case comm.ElectSync:
+			var arg = &pb.ElectSyncReq{}
+			proto.Unmarshal(req, arg)
+			return to.ElectSync(arg, rsp.(*pb.ElectSyncRsp))
+		case comm.Registered:
+			var arg = &pb.NodeInfo{}
+			proto.Unmarshal(req, arg)
+			return to.Registered(arg, rsp.(*pb.RegisteredRsp))
+		}
+		return errors.New("not found this api")
+	}
+}
+
+func testWatchCluster(t *testing.T, uuids ...string) *testCluster {
+	var c = &testCluster{list: make(map[string]*WatchApi), down: make(map[string]bool)}
+	for _, uuid := range uuids {
+		var w = testWatchApi(t)
+		w.msg.base.Uuid = uuid
+		w.msg.elect = &WatchElect{timeout: time.Millisecond * 100}
+		w.msg.elect.resetTimeout()
+		w.msg.watch = nil
+		for _, id := range uuids {
+			w.msg.watch = append(w.msg.watch, &WatchNode{NodeInfo: pb.NodeInfo{Uuid: id}, self: id == uuid})
+		}
+		w.msg.send = c.send(uuid)
+		c.list[uuid] = w
+	}
+	return c
+}
+
+func testMaster(c *testCluster, uuid string) bool {
+	for id, w := range c.list {
+		w.msg.elect.mut.Lock()
+		center, leader := w.msg.elect.center, w.msg.elect.nodeId
+		w.msg.elect.mut.Unlock()
+		if !c.isDown(id) && (center != (id == uuid) || leader != uuid) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWatchElect(t *testing.T) {
+	var c = testWatchCluster(t, "W1", "W2", "W3")
+	var w1, w2, w3 = c.list["W1"], c.list["W2"], c.list["W3"]
+	var rsp = &pb.RegisteredRsp{}
+	if err := w2.Registered(&pb.NodeInfo{Uuid: "A"}, rsp); err == nil {
+		t.Fatal("request must be refused when master unknown")
+	}
+
+	w1.campaign()
+	if !testMaster(c, "W1") {
+		t.Fatal("W1 must be master")
+	}
+	// candidate was refused when master alive
+	var vote = &pb.ElectVoteRsp{}
+	if err := w2.ElectVote(&pb.ElectVoteReq{Term: 9, Index: 9, Base: &pb.NodeInfo{Uuid: "W3"}}, vote); err != nil || vote.Grant {
+		t.Fatal("vote must not be granted when master alive: ", err)
+	}
+	w1.syncPeers()
+	if !testMaster(c, "W1") {
+		t.Fatal("W1 must be master")
+	}
+
+	// follower forward request to master, state was replicated
+	var id = testRegister(t, w2, "A", "Tsv.Echo").Funcs[0].ID
+	w1.syncPeers()
+	for _, w := range []*WatchApi{w1, w2, w3} {
+		if data := w.fmsg.GetIds(id); data == nil || !data.HasNode("A") {
+			t.Fatal("api must be replicated: ", w.msg.base.Uuid)
+		} else if w.node.GetNodeByUuid("A") == nil {
+			t.Fatal("node must be replicated: ", w.msg.base.Uuid)
+		} else if list := w.fmsg.reg.List(); len(list) != 1 || list[0].ID != id {
+			t.Fatal("registry must be replicated: ", w.msg.base.Uuid, list)
+		}
+	}
+}
+
+func TestWatchFailover(t *testing.T) {
+	var c = testWatchCluster(t, "W1", "W2", "W3")
+	var w1, w2, w3 = c.list["W1"], c.list["W2"], c.list["W3"]
+	w1.campaign()
+	var id = testRegister(t, w1, "A", "Tsv.Echo").Funcs[0].ID
+	w1.syncPeers()
+
+	// master lost majority, stop accept request and step down
+	c.setDown("W1", true)
+	w1.syncPeers()
+	if _, err := w1.msg.leader(); err != nil || !w1.msg.Center() {
+		t.Fatal("master must accept request before election timeout: ", err)
+	}
+	time.Sleep(w1.msg.elect.timeout + time.Millisecond*20)
+	var rsp = &pb.RegisteredRsp{}
+	if err := w1.Registered(&pb.NodeInfo{Uuid: "B", Funcs: []*pb.FuncApi{{Name: "Tsv.Name"}}}, rsp); err == nil {
+		t.Fatal("master lost majority must refuse request")
+	}
+	w1.syncPeers()
+	if w1.msg.Center() {
+		t.Fatal("master lost majority must step down")
+	}
+
+	// new master keep the function id
+	w2.campaign()
+	if !testMaster(c, "W2") {
+		t.Fatal("W2 must be master")
+	}
+	if testRegister(t, w3, "B", "Tsv.Echo").Funcs[0].ID != id {
+		t.Fatal("function id must be kept by new master")
+	}
+	var name = testRegister(t, w3, "B", "Tsv.Name").Funcs[0].ID
+	if name == id || w2.fmsg.GetIds(name) == nil {
+		t.Fatal("new api must be made by new master")
+	}
+
+	// old master join the cluster again, replace state by new master
+	c.setDown("W1", false)
+	w2.syncPeers()
+	if !testMaster(c, "W2") || w1.fmsg.GetIds(name) == nil {
+		t.Fatal("old master must follow new master")
+	}
+}
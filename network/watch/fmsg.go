@@ -1,15 +1,12 @@
 package watch
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"micro/network/comm"
 	"micro/network/pb"
@@ -17,80 +14,107 @@ import (
 
 // server api mapping struct
 type funcmap struct {
-	ids  sync.Map // key: function-id
-	str  sync.Map // key: function-api-name
-	num  uint32   // max function id
-	name string   // env file path name
-	file *os.File
+	ids sync.Map   // key: function-id
+	str sync.Map   // key: function-api-name
+	num uint32     // max function id
+	reg *registry  // function id registry file
+	mut sync.Mutex // safe lock to make new function id
+
+	grace time.Duration // api without provider node delete after grace time
+	reuse time.Duration // deleted function id reuse after the time
 }
 
 //
 type funcdata struct {
-	msg  *pb.FuncMsg
-	api  *pb.FuncApi
-	node []string
+	msg   *pb.FuncMsg
+	api   *pb.FuncApi
+	mut   sync.RWMutex // safe lock of node and empty
+	node  []string
+	subs  sync.Map // map[node-uuid]bool, node request api connection
+	empty int64    // timestamp of no provider node, 0 has provider
 }
 
 func (f *funcmap) PutMsg(uuid string, arg *pb.FuncMsg) error {
 	if arg == nil || uuid == "" || arg.ApiName == "" {
 		return errors.New("request data was wrong")
 	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
 	if data := f.GetStr(arg.ApiName); data != nil {
 		arg.FuncID = data.msg.FuncID
-		if uuid != "" {
-			for _, str := range data.node {
-				if str == uuid {
-					return nil
-				}
-			}
-			data.node = append(data.node, uuid)
-		}
+		data.addNode(uuid)
 		return nil
 	}
 
-	// TODO:  Check master watcher node
-	arg.FuncID = atomic.AddUint32(&f.num, 1)
+	var err error
+	if arg.FuncID, err = f.newId(); err != nil {
+		return err
+	}
 	var tmp = &funcdata{
 		msg: arg,
 		api: &pb.FuncApi{
 			ID: arg.FuncID, Name: arg.ApiName,
 			Type: arg.ApiType, Kind: arg.Protocal,
 		}, node: []string{uuid}}
-	f.ids.Store(arg.FuncID, tmp)
-	f.str.Store(arg.ApiName, tmp)
-	f.addEnvFile(tmp.msg)
-	return nil
+	return f.store(tmp)
 }
 
 func (f *funcmap) PutApi(uuid string, arg *pb.FuncApi) error {
 	if arg == nil || uuid == "" || arg.Name == "" {
 		return errors.New("request data was wrong")
 	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
 	if data := f.GetStr(arg.Name); data != nil {
 		arg.ID = data.msg.FuncID
-		if uuid != "" {
-			for _, str := range data.node {
-				if str == uuid {
-					return nil
-				}
-			}
-			data.node = append(data.node, uuid)
-		}
+		data.addNode(uuid)
 		return nil
 	}
 
-	// TODO:  Check master watcher node
-	arg.ID = atomic.AddUint32(&f.num, 1)
-	sname, fname := comm.SplitApiName(arg.Name)
-	var tmp = &funcdata{
+	var err error
+	if arg.ID, err = f.newId(); err != nil {
+		return err
+	}
+	return f.store(newFuncdata(arg, []string{uuid}))
+}
+
+func newFuncdata(api *pb.FuncApi, node []string) *funcdata {
+	sname, fname := comm.SplitApiName(api.Name)
+	var result = &funcdata{
 		msg: &pb.FuncMsg{
-			FuncID: arg.ID, ApiName: arg.Name,
+			FuncID: api.ID, ApiName: api.Name,
 			ServName: sname, FuncName: fname,
-			ApiType: arg.Type, Protocal: arg.Kind,
-		}, api: arg, node: []string{uuid}}
-	f.ids.Store(arg.ID, tmp)
-	f.str.Store(arg.Name, tmp)
-	f.addEnvFile(tmp.msg)
+			ApiType: api.Type, Protocal: api.Kind,
+		}, api: api, node: node}
+	if len(node) == 0 {
+		result.empty = time.Now().UnixMilli()
+	}
+	return result
+}
+
+// make new function id, reuse the deleted id first
+func (f *funcmap) newId() (uint32, error) {
+	if f.reg != nil {
+		if id := f.reg.FreeID(time.Now().Add(-f.reuse).UnixMilli()); id != 0 {
+			return id, nil
+		}
+	}
+	if id := atomic.AddUint32(&f.num, 1); id <= comm.FUNC_ID_MAX {
+		return id, nil
+	}
+	atomic.StoreUint32(&f.num, comm.FUNC_ID_MAX)
+	return 0, errors.New("function id was used up")
+}
+
+// save new function id to registry file, then mapping it
+func (f *funcmap) store(data *funcdata) error {
+	if f.reg != nil {
+		if err := f.reg.Append(funcRecord(data.api, 0)); err != nil {
+			return fmt.Errorf("save function id %d wrong: %v", data.api.ID, err)
+		}
+	}
+	f.ids.Store(data.api.ID, data)
+	f.str.Store(data.api.Name, data)
 	return nil
 }
 
@@ -138,13 +162,274 @@ func (f *funcdata) GetApi() *pb.FuncApi {
 	}
 	return nil
 }
+// copy of provider node uuid list
 func (f *funcdata) GetUuid() []string {
 	if f != nil {
-		return f.node
+		f.mut.RLock()
+		defer f.mut.RUnlock()
+		return append([]string(nil), f.node...)
+	}
+	return nil
+}
+
+// check node uuid provide this api
+func (f *funcdata) HasNode(uuid string) bool {
+	if f != nil {
+		f.mut.RLock()
+		defer f.mut.RUnlock()
+		for _, str := range f.node {
+			if str == uuid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// add provider node uuid
+func (f *funcdata) addNode(uuid string) {
+	if uuid == "" {
+		return
+	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for _, str := range f.node {
+		if str == uuid {
+			return
+		}
+	}
+	f.node = append(f.node, uuid)
+	f.empty = 0
+}
+
+// replace provider node uuid list, record time of no provider
+func (f *funcdata) setNode(node []string) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.node = node
+	if len(node) > 0 {
+		f.empty = 0
+	} else if f.empty == 0 {
+		f.empty = time.Now().UnixMilli()
+	}
+}
+
+// remove provider node uuid, return true when changed
+func (f *funcdata) removeNodes(del map[string]bool) bool {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	var ids = make([]string, 0, len(f.node))
+	for _, uuid := range f.node {
+		if !del[uuid] {
+			ids = append(ids, uuid)
+		}
+	}
+	if len(ids) == len(f.node) {
+		return false
+	}
+	f.node = ids
+	if len(ids) == 0 {
+		f.empty = time.Now().UnixMilli()
+	}
+	return true
+}
+
+// no provider node before the timestamp
+func (f *funcdata) expired(stamp int64) bool {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return len(f.node) == 0 && f.empty != 0 && f.empty < stamp
+}
+
+// record node uuid request this api connection
+func (f *funcdata) Subscribe(uuid string) {
+	if f != nil && uuid != "" {
+		f.subs.Store(uuid, true)
+	}
+}
+
+// check node uuid requested this api connection
+func (f *funcdata) HasSub(uuid string) bool {
+	if f != nil {
+		_, ok := f.subs.Load(uuid)
+		return ok
+	}
+	return false
+}
+
+// range node uuid request this api connection
+func (f *funcdata) RangeSubs(function func(string) bool) {
+	if f != nil {
+		f.subs.Range(func(key, value interface{}) bool {
+			return function(key.(string))
+		})
+	}
+}
+
+// remove expired node uuid of server and subscriber list
+// return function id list which server node changed
+func (f *funcmap) RemoveNodes(uuids []string) []uint32 {
+	var del = make(map[string]bool, len(uuids))
+	for _, uuid := range uuids {
+		del[uuid] = true
+	}
+
+	var result []uint32
+	f.ids.Range(func(key, value interface{}) bool {
+		data, ok := value.(*funcdata)
+		if !ok || data == nil {
+			return true
+		}
+		for uuid := range del {
+			data.subs.Delete(uuid)
+		}
+		if data.removeNodes(del) {
+			result = append(result, data.msg.FuncID)
+		}
+		return true
+	})
+	return result
+}
+
+// function id list which the node provided
+func (f *funcmap) NodeFuncs(uuid string) []uint32 {
+	var result []uint32
+	f.ids.Range(func(key, value interface{}) bool {
+		if data, ok := value.(*funcdata); ok && data != nil && data.HasNode(uuid) {
+			result = append(result, data.msg.FuncID)
+		}
+		return true
+	})
+	return result
+}
+
+// function mapping and server node list to replicate
+func (f *funcmap) Snapshot() []*pb.FuncNode {
+	var result []*pb.FuncNode
+	f.ids.Range(func(key, value interface{}) bool {
+		if v, ok := value.(*funcdata); ok && v.api != nil {
+			var row = &pb.FuncNode{Func: v.api, Node: v.GetUuid()}
+			v.RangeSubs(func(uuid string) bool {
+				row.Subs = append(row.Subs, uuid)
+				return true
+			})
+			result = append(result, row)
+		}
+		return true
+	})
+	return result
+}
+
+// replace function mapping by master watcher state,
+// keep the function id of master and rewrite registry file when changed
+func (f *funcmap) Restore(list []*pb.FuncNode, tombs []*pb.FuncRecord) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	var changed bool
+	var keep = make(map[uint32]bool, len(list))
+	for _, row := range list {
+		api := row.GetFunc()
+		if api == nil || api.Name == "" {
+			continue
+		}
+		keep[api.ID] = true
+		if data := f.GetIds(api.ID); data != nil && data.api.Name == api.Name {
+			data.setNode(row.Node)
+			for _, uuid := range row.Subs {
+				data.Subscribe(uuid)
+			}
+			continue
+		}
+
+		var tmp = newFuncdata(api, row.Node)
+		for _, uuid := range row.Subs {
+			tmp.Subscribe(uuid)
+		}
+		f.ids.Store(api.ID, tmp)
+		f.str.Store(api.Name, tmp)
+		if api.ID > atomic.LoadUint32(&f.num) {
+			atomic.StoreUint32(&f.num, api.ID)
+		}
+		changed = true
+	}
+
+	f.ids.Range(func(key, value interface{}) bool {
+		if !keep[key.(uint32)] {
+			f.ids.Delete(key)
+			changed = true
+		}
+		return true
+	})
+	f.str.Range(func(key, value interface{}) bool {
+		if data := value.(*funcdata); f.GetIds(data.msg.FuncID) != data {
+			f.str.Delete(key)
+		}
+		return true
+	})
+	for _, rec := range tombs {
+		if rec.ID > atomic.LoadUint32(&f.num) {
+			atomic.StoreUint32(&f.num, rec.ID)
+		}
+	}
+	if f.reg != nil && (changed || len(tombs) != len(f.reg.Tombs())) {
+		var rows = make([]*pb.FuncRecord, 0, len(list)+len(tombs))
+		f.RangeApi(func(arg *pb.FuncApi) bool {
+			rows = append(rows, funcRecord(arg, 0))
+			return true
+		})
+		for _, rec := range tombs {
+			if f.GetIds(rec.ID) == nil {
+				rows = append(rows, rec)
+			}
+		}
+		if err := f.reg.Replace(rows); err != nil {
+			log.Println("registry replace: ", err)
+		}
+	}
+}
+
+// deleted function id to replicate
+func (f *funcmap) Tombs() []*pb.FuncRecord {
+	if f.reg != nil {
+		return f.reg.Tombs()
 	}
 	return nil
 }
 
+// delete function id, save tombstone to registry and it can be reused later,
+// force to delete the api which has provider node
+func (f *funcmap) Delete(data *funcdata, force bool) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if data == nil || f.GetIds(data.api.ID) != data {
+		return errors.New("not found this api")
+	} else if len(data.GetUuid()) > 0 && !force {
+		return errors.New("api has provider nodes, delete by force")
+	}
+	if f.reg != nil {
+		rec := funcRecord(data.api, time.Now().UnixMilli())
+		if err := f.reg.Append(rec); err != nil {
+			return fmt.Errorf("save function id %d wrong: %v", rec.ID, err)
+		}
+	}
+	f.ids.Delete(data.api.ID)
+	f.str.Delete(data.api.Name)
+	return nil
+}
+
+// api list which no provider node after grace time
+func (f *funcmap) Expired() []*funcdata {
+	var result []*funcdata
+	var exp = time.Now().Add(-f.grace).UnixMilli()
+	f.ids.Range(func(key, value interface{}) bool {
+		if v, ok := value.(*funcdata); ok && v.expired(exp) {
+			result = append(result, v)
+		}
+		return true
+	})
+	return result
+}
+
 // func (f *funcmap) QuerySerConn(id uint32, name string) []*pb.NodeBaseMsg {
 // 	var ids []string
 // 	if id != 0 {
@@ -171,81 +456,23 @@ func (f *funcdata) GetUuid() []string {
 // 	return result
 // }
 
-func (f *funcmap) InitEnvFile(path string) error {
-	if path == "" {
-		f.name = "./funclist.conf"
-	} else {
-		f.name = filepath.Join(path, "funclist.conf")
-	}
-	bts, err := ioutil.ReadFile(f.name)
-	if err != nil && !os.IsNotExist(err) {
-		panic(err)
-	} else {
-		rows := bytes.Split(bts, []byte("\n"))
-		for _, row := range rows {
-			if len(row) == 0 {
-				continue
-			}
-			var msg = &pb.FuncApi{}
-			if json.Unmarshal(row, msg) == nil && msg.ID > comm.BUILT_IN_MAX {
-				f.PutApi("", msg)
-				if msg.ID > f.num {
-					f.num = uint32(msg.ID)
-				}
-			}
-		}
-		if f.num < comm.BUILT_IN_MAX {
-			f.num = comm.BUILT_IN_MAX
-		}
-	}
-
-	os.Remove(f.name)
-	f.file, err = os.OpenFile(f.name, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
-	if err == nil {
-		f.ids.Range(func(key, value interface{}) bool {
-			if bts, err := json.Marshal(value); err == nil {
-				f.file.Write(bts)
-				f.file.WriteString("\n")
-			}
-			return true
-		})
-	}
-	return err
-}
-
-func (f *funcmap) addEnvFile(fmsg *pb.FuncMsg) {
-	if f.file == nil {
-		f.upEnvFile()
+// open function id registry in config path, load the function ids
+func (f *funcmap) InitRegistry(path string) error {
+	reg, err := openRegistry(registryName(path))
+	if err != nil {
+		return err
 	}
-	if bts, err := json.Marshal(fmsg); err == nil {
-		_, err = f.file.Write(append(bts, []byte("\n")...))
-		if err != nil {
-			f.upEnvFile()
+	for _, api := range reg.List() {
+		var tmp = newFuncdata(api, nil)
+		f.ids.Store(api.ID, tmp)
+		f.str.Store(api.Name, tmp)
+		if api.ID > f.num {
+			f.num = api.ID
 		}
 	}
-}
-
-func (f *funcmap) upEnvFile() {
-	var err error
-	f.file, err = os.OpenFile(f.name, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
-	if err != nil {
-		log.Panic(err)
-	}
-	if f.file != nil {
-		f.RangeApi(func(arg *pb.FuncApi) bool {
-			if bts, err := json.Marshal(arg); err == nil {
-				if _, err = f.file.Write(bts); err != nil {
-					f.name = "./funclist.bak"
-					f.upEnvFile()
-					return false
-				}
-				if _, err = f.file.Write([]byte("\n")); err != nil {
-					f.name = "./funclist.bak"
-					f.upEnvFile()
-					return false
-				}
-			}
-			return true
-		})
+	if f.num < comm.WATCH_IN_MAX {
+		f.num = comm.WATCH_IN_MAX
 	}
+	f.reg = reg
+	return nil
 }
@@ -22,22 +22,67 @@ type nodemap struct {
 }
 
 type NodeMsg struct {
-	base  *pb.NodeInfo
+	base *pb.NodeInfo
+
+	// heartbeat updated, read by api node list and election sync
+	mut   sync.RWMutex
 	syst  *pb.SystemStatus
 	stamp int64 // heartbeat ping last timestamp
 	state bool  // node status, false is cannot connection
 }
 
-// update connect status with heartbeat request
-func (n *nodemap) UpHeartbeat(sys *pb.SystemStatus) bool {
+// update connect status with heartbeat request,
+// return false when node not found, and true when health state changed
+func (n *nodemap) UpHeartbeat(sys *pb.SystemStatus) (bool, bool) {
 	if v, ok := n.uuid.Load(sys.GetUuid()); ok && v != nil {
 		if msg, ok := v.(*NodeMsg); ok && msg != nil {
+			msg.mut.Lock()
+			defer msg.mut.Unlock()
+			changed := msg.syst.GetState() != sys.GetState()
 			msg.stamp = time.Now().UnixMilli()
 			msg.state, msg.syst = true, sys
-			return true
+			return true, changed
 		}
 	}
-	return false
+	return false, false
+}
+
+// health state reported by last heartbeat, default SERVING
+func (m *NodeMsg) Health() pb.ServingState { return m.status().GetState() }
+
+// system status reported by last heartbeat
+func (m *NodeMsg) status() *pb.SystemStatus {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.syst
+}
+
+// node was not deleted and last heartbeat after the time
+func (m *NodeMsg) alive(exp int64) bool {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.state && m.stamp >= exp
+}
+
+// node was not deleted
+func (m *NodeMsg) online() bool {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.state
+}
+
+// node heartbeat or registered again
+func (m *NodeMsg) touch(stamp int64) {
+	m.mut.Lock()
+	m.stamp, m.state = stamp, true
+	m.mut.Unlock()
+}
+
+// node was deleted or heartbeat timeout
+func (m *NodeMsg) down() {
+	m.mut.Lock()
+	m.state = false
+	m.mut.Unlock()
 }
 
 // Get node list by uuid
@@ -52,6 +97,18 @@ func (n *nodemap) GetNodeByUuid(uuid string) *pb.NodeInfo {
 	return nil
 }
 
+// Get node system status by uuid
+func (n *nodemap) GetStatByUuid(uuid string) *pb.SystemStatus {
+	if uuid != "" {
+		if v, ok := n.uuid.Load(uuid); ok && v != nil {
+			if msg, ok := v.(*NodeMsg); ok && msg != nil {
+				return msg.status()
+			}
+		}
+	}
+	return nil
+}
+
 // Get node list by server name
 func (n *nodemap) GetUuidByName(name string) []string {
 	if v, ok := n.name.Load(name); ok && v != nil {
@@ -143,7 +200,7 @@ func (n *nodemap) GetNodeByName(name string) []*pb.NodeInfo {
 func (n *nodemap) PutNodeDetail(msg *pb.NodeInfo) {
 	if v, ok := n.uuid.Load(msg.Uuid); ok && v != nil {
 		if arg, ok := v.(*NodeMsg); ok && arg.base.Host == msg.Host {
-			arg.stamp, arg.state = time.Now().UnixMilli(), true
+			arg.touch(time.Now().UnixMilli())
 		}
 		return
 	}
@@ -154,114 +211,108 @@ func (n *nodemap) PutNodeDetail(msg *pb.NodeInfo) {
 		stamp: time.Now().UnixMilli(),
 	}
 	n.uuid.Store(msg.Uuid, tmp)
+}
 
-	// var keys = make(map[string]bool)
-	// for _, row := range msg.Funcs {
-	// 	sname, _ := comm.SplitApiName(row.Name)
-	// 	keys[sname] = true
-	// }
-
-	// now := time.Now().Add(comm.NodeConnTimeOut).UnixMilli()
-
-	// for k := range keys {
-	// 	go func(key string, tmp *NodeMsg) {
-	// 		if v, ok := n.serv.Load(key); ok {
-	// 			arg := v.(*ServNode)
-
-	// 			arg.lock.Lock()
-	// 			var result = []*NodeMsg{tmp}
-	// 			var notify = &pb.GetApiConnRsp{
-	// 				List: []*pb.NodeInfo{tmp.base},
-	// 				Func: &pb.FuncMsg{ServName: key}}
-	// 			for _, row := range arg.list {
-	// 				if row.state && row.stamp > now {
-	// 					notify.List = append(notify.List, row.base)
-	// 					result = append(result, row)
-	// 				}
-	// 			}
-	// 			arg.list = result
-	// 			arg.lock.Unlock()
+// node registered and heartbeat not timeout
+func (n *nodemap) Alive(uuid string) bool {
+	if v, ok := n.uuid.Load(uuid); ok && v != nil {
+		if msg, ok := v.(*NodeMsg); ok && msg != nil {
+			return msg.alive(time.Now().Add(comm.NodeConnTimeOut).UnixMilli())
+		}
+	}
+	return false
+}
 
-	// 			if bts, err := proto.Marshal(notify); err == nil {
-	// 				var used []*NodeMsg
-	// 				for _, row := range arg.used {
-	// 					if row.state && row.stamp > now {
-	// 						used = append(used, row)
-	// 						go n.call.WatchSend(time.Second*3,
-	// 							row.base.Uuid, comm.UpNodeConnMsg, bts)
-	// 					}
-	// 				}
-	// 				arg.used = used
-	// 			}
-	// 		} else {
-	// 			var data = &ServNode{list: []*NodeMsg{tmp}}
-	// 			n.serv.Store(key, data)
-	// 		}
-	// 	}(k, tmp)
-	// }
+// delete node by uuid, return false when not found
+func (n *nodemap) DelNode(uuid string) bool {
+	if v, ok := n.uuid.Load(uuid); ok {
+		n.uuid.Delete(uuid)
+		v.(*NodeMsg).down()
+		return true
+	}
+	return false
 }
 
-func (w *nodemap) ClearNodeExprie() {
+// clear node which heartbeat timeout, return expired node uuid list
+func (w *nodemap) ClearNodeExprie() []string {
 	var v *NodeMsg
+	var result []string
 	exp := time.Now().Add(comm.NodeConnTimeOut).UnixMilli()
 	w.uuid.Range(func(key, value interface{}) bool {
 		v = value.(*NodeMsg)
-		if !v.state || v.stamp < exp {
-			v.state = false
+		if !v.alive(exp) {
+			v.down()
 			w.uuid.Delete(key)
+			result = append(result, key.(string))
 		}
 		return true
 	})
+	return result
+}
 
-	// w.serv.Range(func(key, value interface{}) bool {
-	// 	tmp, ok := value.(*ServNode)
-	// 	if ok {
-	// 		for _, v = range tmp.list {
-	// 			if !v.state || v.stamp < exp {
-	// 				goto DealWith
-	// 			}
-	// 		}
-	// 	} else {
-	// 		w.serv.Delete(key)
-	// 	}
-	// 	return true
+// Get all server node list
+func (n *nodemap) GetNodeList() []*pb.NodeInfo {
+	var result []*pb.NodeInfo
+	n.uuid.Range(func(key, value interface{}) bool {
+		if v, ok := value.(*NodeMsg); ok && v.online() {
+			result = append(result, v.base)
+		}
+		return true
+	})
+	return result
+}
 
-	// DealWith:
-	// 	go func(n *WatchDetail, data *ServNode) {
-	// 		var dur = time.Now().Add(comm.NodeConnTimeOut).UnixMilli()
-	// 		var nodes []*NodeMsg
-	// 		data.lock.Lock()
-	// 		var notify = &pb.GetApiConnRsp{Func: &pb.FuncMsg{
-	// 			ServName: key.(string), Protocal: pb.Compiler_PROTO}}
-	// 		for _, v = range data.list {
-	// 			if v.state && v.stamp > dur {
-	// 				notify.List = append(notify.List, v.base)
-	// 				nodes = append(nodes, v)
-	// 			}
-	// 		}
-	// 		data.list = nodes
-	// 		data.lock.Unlock()
+// reset heartbeat timestamp of all server node
+func (n *nodemap) ResetStamp() {
+	var now = time.Now().UnixMilli()
+	n.uuid.Range(func(key, value interface{}) bool {
+		if v, ok := value.(*NodeMsg); ok {
+			v.mut.Lock()
+			v.stamp = now
+			v.mut.Unlock()
+		}
+		return true
+	})
+}
 
-	// 		if len(nodes) == 0 {
-	// 			n.serv.Delete(key)
-	// 			return
-	// 		}
+// server node list and system status to replicate
+func (n *nodemap) Snapshot() ([]*pb.NodeInfo, []*pb.SystemStatus) {
+	var nodes []*pb.NodeInfo
+	var stats []*pb.SystemStatus
+	n.uuid.Range(func(key, value interface{}) bool {
+		if v, ok := value.(*NodeMsg); ok && v.online() {
+			nodes = append(nodes, v.base)
+			if syst := v.status(); syst != nil {
+				stats = append(stats, syst)
+			}
+		}
+		return true
+	})
+	return nodes, stats
+}
 
-	// 		nodes = make([]*NodeMsg, 0)
-	// 		if bts, err := proto.Marshal(notify); err == nil {
-	// 			for _, row := range data.used {
-	// 				if row.state && row.stamp > dur {
-	// 					if n.call.WatchSend(time.Second, row.base.Uuid,
-	// 						comm.UpNodeConnMsg, bts) == nil {
-	// 						nodes = append(nodes, row)
-	// 					}
-	// 				}
-	// 			}
-	// 		}
-	// 		data.used = nodes
-	// 	}(w, tmp)
-	// 	return true
-	// })
+// replace server node list by master watcher state
+func (n *nodemap) Restore(nodes []*pb.NodeInfo, stats []*pb.SystemStatus) {
+	var now = time.Now().UnixMilli()
+	var keep = make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		keep[node.Uuid] = true
+		n.uuid.Store(node.Uuid, &NodeMsg{base: node, state: true, stamp: now})
+	}
+	for _, stat := range stats {
+		if v, ok := n.uuid.Load(stat.Uuid); ok {
+			msg := v.(*NodeMsg)
+			msg.mut.Lock()
+			msg.syst = stat
+			msg.mut.Unlock()
+		}
+	}
+	n.uuid.Range(func(key, value interface{}) bool {
+		if !keep[key.(string)] {
+			n.uuid.Delete(key)
+		}
+		return true
+	})
 }
 
 func (n *WatchDetail) SystemState() *pb.SystemStatus {
@@ -0,0 +1,444 @@
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"micro/network/comm"
+	"micro/network/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// registry file name in config path
+	RegistryFile = "funclist.conf"
+
+	registryMagic = "GFID0001" // registry file header
+	recordHead    = 8          // record header: length + crc32
+	recordMaxSize = 1 << 20    // max size of one record
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// function id registry, function id is part of request protocal,
+// it cannot be changed after watcher restart.
+//
+// file: | magic | record | record | ...
+// record: | length uint32 | crc32 uint32 | proto pb.FuncRecord |
+//
+// records only append with fsync, the last record of same function id is valid,
+// deleted function id keep a tombstone record until it be reused.
+// the broken tail (crash when writing) will be truncated when open,
+// and compact file by rename temporary file when too many records.
+type registry struct {
+	mut   sync.Mutex
+	name  string   // registry file path
+	file  *os.File // append only file
+	size  int64    // valid data size of file
+	count int      // record number of file
+	list  map[uint32]*pb.FuncRecord
+}
+
+func registryName(path string) string {
+	if path == "" {
+		path = "./"
+	}
+	return filepath.Join(path, RegistryFile)
+}
+
+// open registry file, recover broken tail and migrate old json lines file
+func openRegistry(name string) (*registry, error) {
+	var r = &registry{name: name, list: make(map[uint32]*pb.FuncRecord)}
+	bts, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix([]byte(registryMagic), bts):
+		// not exist, or crash when create
+		return r, r.compact(nil)
+
+	case !bytes.HasPrefix(bts, []byte(registryMagic)):
+		// skipped line lost its function id, it can be used by other api
+		list, err := decodeFuncTable(bytes.NewReader(bts))
+		if err != nil {
+			return nil, fmt.Errorf("migrate registry %s: %v", name, err)
+		}
+		log.Printf("registry: migrate %d functions from old file %s\n", len(list), name)
+		var rows = make([]*pb.FuncRecord, 0, len(list))
+		for _, api := range list {
+			rows = append(rows, funcRecord(api, 0))
+		}
+		return r, r.compact(rows)
+	}
+
+	var offset = len(registryMagic)
+	for offset < len(bts) {
+		rec, size, err := decodeRecord(bts[offset:])
+		if err != nil {
+			log.Printf("registry: %s broken at %d, truncate: %v\n", name, offset, err)
+			break
+		}
+		r.list[rec.ID] = rec
+		r.count++
+		offset += size
+	}
+	if r.file, err = os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0644); err != nil {
+		return nil, err
+	}
+	if offset < len(bts) {
+		if err = r.file.Truncate(int64(offset)); err == nil {
+			err = r.file.Sync()
+		}
+		if err != nil {
+			r.file.Close()
+			return nil, err
+		}
+	}
+	r.size = int64(offset)
+	return r, nil
+}
+
+func funcRecord(api *pb.FuncApi, deleted int64) *pb.FuncRecord {
+	return &pb.FuncRecord{ID: api.ID, Name: api.Name,
+		Type: api.Type, Kind: api.Kind, Deleted: deleted}
+}
+
+func recordApi(rec *pb.FuncRecord) *pb.FuncApi {
+	return &pb.FuncApi{ID: rec.ID, Name: rec.Name, Type: rec.Type, Kind: rec.Kind}
+}
+
+func checkRecord(rec *pb.FuncRecord) error {
+	if rec == nil || rec.ID <= comm.WATCH_IN_MAX || rec.ID > comm.FUNC_ID_MAX || rec.Name == "" {
+		return fmt.Errorf("registry function id or name wrong: %d %s", rec.GetID(), rec.GetName())
+	}
+	return nil
+}
+
+func encodeRecord(rec *pb.FuncRecord) ([]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	var buf = make([]byte, recordHead+len(data))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(data, crcTable))
+	copy(buf[recordHead:], data)
+	return buf, nil
+}
+
+// decode one record, return record size
+func decodeRecord(bts []byte) (*pb.FuncRecord, int, error) {
+	if len(bts) < recordHead {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	size := binary.LittleEndian.Uint32(bts[0:4])
+	if size > recordMaxSize {
+		return nil, 0, errors.New("record size too large")
+	} else if len(bts) < recordHead+int(size) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	data := bts[recordHead : recordHead+int(size)]
+	if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(bts[4:8]) {
+		return nil, 0, errors.New("record checksum wrong")
+	}
+	var rec = &pb.FuncRecord{}
+	if err := proto.Unmarshal(data, rec); err != nil {
+		return nil, 0, err
+	}
+	if err := checkRecord(rec); err != nil {
+		return nil, 0, err
+	}
+	return rec, recordHead + int(size), nil
+}
+
+// valid function list sort by id
+func (r *registry) List() []*pb.FuncApi {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	var result = make([]*pb.FuncApi, 0, len(r.list))
+	for _, rec := range r.sortList() {
+		if rec.Deleted == 0 {
+			result = append(result, recordApi(rec))
+		}
+	}
+	return result
+}
+
+// deleted function id list
+func (r *registry) Tombs() []*pb.FuncRecord {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	var result []*pb.FuncRecord
+	for _, rec := range r.sortList() {
+		if rec.Deleted != 0 {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+// the earliest deleted function id before the timestamp, 0 is not found
+func (r *registry) FreeID(before int64) uint32 {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	var result *pb.FuncRecord
+	for _, rec := range r.list {
+		if rec.Deleted == 0 || rec.Deleted > before {
+			continue
+		}
+		if result == nil || rec.Deleted < result.Deleted ||
+			(rec.Deleted == result.Deleted && rec.ID < result.ID) {
+			result = rec
+		}
+	}
+	return result.GetID()
+}
+
+func (r *registry) sortList() []*pb.FuncRecord {
+	var result = make([]*pb.FuncRecord, 0, len(r.list))
+	for _, rec := range r.list {
+		result = append(result, rec)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// append function record, return after data sync to disk
+func (r *registry) Append(rec *pb.FuncRecord) error {
+	if err := checkRecord(rec); err != nil {
+		return err
+	}
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.file == nil {
+		return errors.New("registry file was closed")
+	}
+	if _, err = r.file.Write(buf); err == nil {
+		err = r.file.Sync()
+	}
+	if err != nil {
+		// remove the part written
+		r.file.Truncate(r.size)
+		return err
+	}
+	r.size += int64(len(buf))
+	r.count++
+	r.list[rec.ID] = proto.Clone(rec).(*pb.FuncRecord)
+
+	if r.count > len(r.list)*2+64 {
+		if err := r.compact(r.sortList()); err != nil {
+			log.Println("registry compact: ", err)
+		}
+	}
+	return nil
+}
+
+// replace all function records
+func (r *registry) Replace(list []*pb.FuncRecord) error {
+	for _, rec := range list {
+		if err := checkRecord(rec); err != nil {
+			return err
+		}
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.compact(list)
+}
+
+// write records to temporary file, and rename it to registry file
+func (r *registry) compact(list []*pb.FuncRecord) error {
+	tmp := r.name + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var size int64
+	var w = bufio.NewWriter(file)
+	var result = make(map[uint32]*pb.FuncRecord, len(list))
+	n, err := w.WriteString(registryMagic)
+	size += int64(n)
+	for _, rec := range list {
+		if err != nil {
+			break
+		}
+		var buf []byte
+		if buf, err = encodeRecord(rec); err == nil {
+			n, err = w.Write(buf)
+			size += int64(n)
+			result[rec.ID] = proto.Clone(rec).(*pb.FuncRecord)
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, r.name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(r.name))
+
+	if r.file != nil {
+		r.file.Close()
+	}
+	if r.file, err = os.OpenFile(r.name, os.O_RDWR|os.O_APPEND, 0644); err != nil {
+		return err
+	}
+	r.size, r.count, r.list = size, len(list), result
+	return nil
+}
+
+// make rename durable
+func syncDir(path string) {
+	if dir, err := os.Open(path); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+}
+
+func (r *registry) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// read function table of json lines, FuncApi or FuncMsg (old file),
+// return error of the wrong line
+func decodeFuncTable(rd io.Reader) ([]*pb.FuncApi, error) {
+	var result []*pb.FuncApi
+	var ids = make(map[uint32]string)
+	var names = make(map[string]uint32)
+	var scan = bufio.NewScanner(rd)
+	scan.Buffer(make([]byte, 0, 4096), recordMaxSize)
+	for line := 1; scan.Scan(); line++ {
+		row := bytes.TrimSpace(scan.Bytes())
+		if len(row) == 0 {
+			continue
+		}
+		var api = &pb.FuncApi{}
+		if err := json.Unmarshal(row, api); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if api.ID == 0 && api.Name == "" {
+			var msg = &pb.FuncMsg{}
+			if json.Unmarshal(row, msg) == nil {
+				api = &pb.FuncApi{ID: msg.FuncID, Name: msg.ApiName,
+					Type: msg.ApiType, Kind: msg.Protocal}
+			}
+		}
+		var err error
+		if api.ID <= comm.WATCH_IN_MAX || api.ID > comm.FUNC_ID_MAX || api.Name == "" {
+			err = fmt.Errorf("line %d: function id or name wrong", line)
+		} else if name, ok := ids[api.ID]; ok && name != api.Name {
+			err = fmt.Errorf("line %d: function id %d used by %s", line, api.ID, name)
+		} else if id, ok := names[api.Name]; ok && id != api.ID {
+			err = fmt.Errorf("line %d: function %s has id %d", line, api.Name, id)
+		} else if ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, api)
+		ids[api.ID], names[api.Name] = api.Name, api.ID
+	}
+	return result, scan.Err()
+}
+
+// ExportFuncTable : write function id table of registry in config path as json lines
+func ExportFuncTable(path string, w io.Writer) error {
+	r, err := openRegistry(registryName(path))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, api := range r.List() {
+		bts, err := json.Marshal(api)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(bts, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportFuncTable : merge function id table (json lines) into registry in config path,
+// the same function id or api name must not be changed. watcher should be stopped.
+func ImportFuncTable(path string, rd io.Reader) error {
+	list, err := decodeFuncTable(rd)
+	if err != nil {
+		return err
+	}
+	r, err := openRegistry(registryName(path))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// deleted function id can be used by import
+	var rows = r.sortList()
+	var names = make(map[string]uint32, len(rows))
+	for _, rec := range rows {
+		if rec.Deleted == 0 {
+			names[rec.Name] = rec.ID
+		}
+	}
+	for _, api := range list {
+		if id, ok := names[api.Name]; ok && id != api.ID {
+			return fmt.Errorf("function %s has id %d, cannot change to %d", api.Name, id, api.ID)
+		}
+		if old, ok := r.list[api.ID]; ok && old.Deleted == 0 {
+			if old.Name != api.Name {
+				return fmt.Errorf("function id %d used by %s", api.ID, old.Name)
+			}
+			continue
+		}
+		rows = append(rows, funcRecord(api, 0))
+	}
+
+	// the last record of same id is valid
+	var result = make(map[uint32]*pb.FuncRecord, len(rows))
+	for _, rec := range rows {
+		result[rec.ID] = rec
+	}
+	rows = rows[:0]
+	for _, rec := range result {
+		rows = append(rows, rec)
+	}
+	return r.Replace(rows)
+}
@@ -0,0 +1,126 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"micro/network/pb"
+)
+
+func testRegistry(t *testing.T, name string, num int) *registry {
+	r, err := openRegistry(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.List()) != num {
+		t.Fatal("registry function number wrong: ", len(r.List()), num)
+	}
+	return r
+}
+
+func testFileSize(t *testing.T, name string) int64 {
+	stat, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stat.Size()
+}
+
+func TestRegistryRecover(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), RegistryFile)
+	var r = testRegistry(t, name, 0)
+	for i, api := range []string{"Tsv.A", "Tsv.B", "Tsv.C"} {
+		if err := r.Append(&pb.FuncRecord{ID: uint32(200 + i), Name: api}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Append(&pb.FuncRecord{ID: 1, Name: "Tsv.D"}); err == nil {
+		t.Fatal("function id of watcher must be wrong")
+	}
+	r.Close()
+	var size = testFileSize(t, name)
+
+	// crash when writing record, the torn tail was truncated
+	bts, _ := ioutil.ReadFile(name)
+	ioutil.WriteFile(name, append(bts, 9, 0, 0, 0, 1, 2), 0644)
+	r = testRegistry(t, name, 3)
+	r.Close()
+	if testFileSize(t, name) != size {
+		t.Fatal("torn tail must be truncated")
+	}
+
+	// checksum of the last record was wrong
+	bts[len(bts)-1] ^= 0xff
+	ioutil.WriteFile(name, bts, 0644)
+	r = testRegistry(t, name, 2)
+	defer r.Close()
+	if testFileSize(t, name) >= size || r.size != testFileSize(t, name) {
+		t.Fatal("broken record must be truncated")
+	}
+	if err := r.Append(&pb.FuncRecord{ID: 202, Name: "Tsv.C"}); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	testRegistry(t, name, 3).Close()
+}
+
+func TestRegistryCompact(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), RegistryFile)
+	var r = testRegistry(t, name, 0)
+	defer r.Close()
+	for i := 0; i < 100; i++ {
+		if err := r.Append(&pb.FuncRecord{ID: 200, Name: "Tsv.A", Deleted: int64((i + 1) % 2)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.count > 64 {
+		t.Fatal("registry must be compacted: ", r.count)
+	} else if _, err := os.Stat(name + ".tmp"); !os.IsNotExist(err) {
+		t.Fatal("temporary file must be renamed")
+	} else if len(r.Tombs()) != 0 || len(r.List()) != 1 {
+		t.Fatal("last record must be valid")
+	}
+
+	if err := r.Replace([]*pb.FuncRecord{{ID: 300, Name: "Tsv.B"}, {ID: 301, Name: "Tsv.C", Deleted: 1}}); err != nil {
+		t.Fatal(err)
+	} else if err = r.Replace([]*pb.FuncRecord{{ID: 0, Name: "Tsv.D"}}); err == nil {
+		t.Fatal("wrong record must not be replaced")
+	}
+	r.Close()
+	r = testRegistry(t, name, 1)
+	if list := r.List(); list[0].ID != 300 || r.FreeID(1) != 301 || r.FreeID(0) != 0 {
+		t.Fatal("replaced records wrong: ", list, r.Tombs())
+	}
+}
+
+func TestRegistryMigrate(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), RegistryFile)
+	var lines = []string{
+		`{"FuncID":107,"ApiName":"WatchApi.GetWatcher","ServName":"WatchApi","FuncName":"GetWatcher","ApiType":1}`,
+		`{"ID":114,"Name":"Tsv.UpName","Type":1,"Kind":1}`,
+		``,
+		`{"ID":114,"Name":"Tsv.UpName","Type":1,"Kind":1}`,
+	}
+	ioutil.WriteFile(name, []byte(strings.Join(lines, "\n")), 0644)
+	var r = testRegistry(t, name, 2)
+	r.Close()
+	if bts, _ := ioutil.ReadFile(name); !strings.HasPrefix(string(bts), registryMagic) {
+		t.Fatal("old file must be rewritten")
+	}
+	testRegistry(t, name, 2).Close()
+
+	// function id of bad line is unknown, watcher must not start
+	for _, bad := range []string{`{"ID":114,`, `{"ID":1,"Name":"Tsv.Low"}`,
+		`{"ID":115,"Name":"Tsv.UpName"}`, `{"ID":114,"Name":"Tsv.Other"}`} {
+		var old = strings.Join(append(lines, bad), "\n")
+		ioutil.WriteFile(name, []byte(old), 0644)
+		if _, err := openRegistry(name); err == nil {
+			t.Fatal("bad line must be failed: ", bad)
+		} else if bts, _ := ioutil.ReadFile(name); string(bts) != old {
+			t.Fatal("old file must not be changed")
+		}
+	}
+}
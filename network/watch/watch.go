@@ -2,6 +2,7 @@ package watch
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"micro/common"
@@ -18,13 +19,53 @@ type WatchDetail struct {
 	elect *WatchElect
 	call  *rpc.NodeDetail
 	timer timer.TimerStruct
+	// request shim of test, nil is rpc call of watcher node
+	send func(peer *WatchNode, fid uint32, req []byte, rsp interface{}) error
 }
 
 type WatchNode struct {
 	pb.NodeInfo
-	center bool  // 是否为主节点
-	status bool  // 状态
-	stamp  int64 // 时间戳
+	self  bool          // 是否为当前节点
+	index uint64        // 已同步的状态数据版本号
+	conn  *rpc.NodeConn // 发现节点连接
+	mut   sync.Mutex
+}
+
+func (w *WatchNode) uuid() string {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.Uuid
+}
+
+func (w *WatchNode) getConn() *rpc.NodeConn {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.conn
+}
+
+// update watcher node connection, null to close it
+func (w *WatchNode) setConn(conn *rpc.NodeConn, base *pb.NodeInfo) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if w.conn != nil && w.conn != conn {
+		w.conn.Close()
+	}
+	w.conn, w.index = conn, 0
+	if base != nil {
+		w.Uuid, w.Name, w.Pid = base.Uuid, base.Name, base.Pid
+	}
+}
+
+func (w *WatchNode) getIndex() uint64 {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.index
+}
+
+func (w *WatchNode) setIndex(index uint64) {
+	w.mut.Lock()
+	w.index = index
+	w.mut.Unlock()
 }
 
 // func (w *WatchDetail) RangeNodes(function func(*NodeMsg) bool) {
@@ -56,24 +97,70 @@ func NewWatcher(conf *network.WatcherConfig) error {
 		TcpPort:  uint64(conf.TcpPort),
 		UdpPort:  uint64(conf.UdpPort),
 		HttpPort: uint64(conf.HttpPort),
+		TLS:      conf.TLS,
+		SignKey:  conf.SignKey,
+		SignKeys: conf.SignKeys,
 	}); err != nil || node == nil {
 		return err
 	} else {
 		var nodedata = &WatchApi{
-			fmsg: &funcmap{num: comm.WATCH_IN_MAX},
+			fmsg: &funcmap{num: comm.WATCH_IN_MAX,
+				grace: conf.ApiDeleteDelay, reuse: conf.ApiReuseDelay},
 			msg: &WatchDetail{
 				base:  &node.NodeInfo,
-				elect: &WatchElect{center: true},
+				elect: &WatchElect{timeout: conf.ElectTimeout},
 				timer: node.GetTimeTicker(),
 				watch: []*WatchNode{&WatchNode{
 					NodeInfo: node.GetNodeMsg(),
-					center:   true,
-					stamp:    time.Now().UnixMilli(),
+					self:     true,
 				}},
 			},
-			node: &nodemap{},
+			node:   &nodemap{},
+			verify: node.VerifyNode,
+			admin:  make(map[string]bool),
+			caller: rpc.CallerIdentity,
+			peer:   rpc.RegisteredPeer,
+			signed: len(conf.SignKeys) > 0,
+		}
+		for _, name := range conf.Admins {
+			nodedata.admin[name] = true
+		}
+		if err = nodedata.fmsg.InitRegistry(conf.ConfigPath); err != nil {
+			return err
+		} else if nodedata.auth, err = newAuthFile(conf.AuthPolicy); err != nil {
+			return err
+		}
+
+		// other watcher nodes of cluster, skip the config of self
+		for _, peer := range conf.Peers {
+			var host = peer.Host
+			if host == "" || host == "127.0.0.1" {
+				host = conf.Host
+			}
+			if host == conf.Host && peer.TcpPort == conf.TcpPort &&
+				peer.UdpPort == conf.UdpPort && peer.HttpPort == conf.HttpPort {
+				continue
+			}
+			nodedata.msg.watch = append(nodedata.msg.watch, &WatchNode{NodeInfo: pb.NodeInfo{
+				Host: host, Tport: peer.TcpPort,
+				Uport: peer.UdpPort, Hport: peer.HttpPort,
+			}})
+		}
+		if nodedata.fmsg.grace <= 0 {
+			nodedata.fmsg.grace = time.Minute * 10
+		}
+		if nodedata.fmsg.reuse <= 0 {
+			nodedata.fmsg.reuse = time.Hour * 24
+		}
+		if nodedata.msg.elect.timeout <= 0 {
+			nodedata.msg.elect.timeout = time.Millisecond * 1500
+		}
+		nodedata.msg.elect.resetTimeout()
+		if len(nodedata.msg.watch) == 1 {
+			// single watcher node is master
+			nodedata.msg.elect.center = true
+			nodedata.msg.elect.nodeId = node.Uuid
 		}
-		nodedata.fmsg.InitEnvFile(conf.ConfigPath)
 
 		if err = node.Register(nodedata); err != nil {
 			return err
@@ -83,9 +170,24 @@ func NewWatcher(conf *network.WatcherConfig) error {
 			nodedata.msg.call = node
 		}
 
-		nodedata.msg.timer.AddDurationFunction(time.Second*5, -1, func() {
-			nodedata.node.ClearNodeExprie()
+		nodedata.msg.timer.AddDurationFunction(comm.HeartbeatInterval, -1, func() {
+			nodedata.reloadPolicy()
+			if leader, err := nodedata.msg.leader(); err != nil || leader != nil {
+				// only master watcher with majority change the state
+				return
+			}
+			if ids := nodedata.node.ClearNodeExprie(); len(ids) > 0 {
+				fids := nodedata.fmsg.RemoveNodes(ids)
+				nodedata.msg.upIndex()
+				nodedata.notifyApiConn(fids...)
+			}
+			for _, data := range nodedata.fmsg.Expired() {
+				nodedata.deleteApi(data, false)
+			}
 		})
+		if len(nodedata.msg.watch) > 1 {
+			nodedata.msg.timer.AddDurationFunction(nodedata.msg.elect.timeout/3, -1, nodedata.electTick)
+		}
 	}
 	return nil
 }
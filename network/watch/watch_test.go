@@ -1,51 +1,223 @@
 package watch
 
 import (
-	"micro/network"
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
 	"micro/network/comm"
 	"micro/network/pb"
 	"micro/network/rpc"
-	"testing"
-	"time"
 )
 
-func TestWatch(t *testing.T) {
-	wd := &WatchDetail{base: &pb.NodeBaseMsg{
-		Name: comm.WatchNodeName,
-		Host: "192.168.0.175", Tport: 8080, Hport: 8082,
-	}, node: &NodeMsgList{
-		name: make(map[string][]*NodeMsg),
-		uuid: make(map[string]*NodeMsg),
-	}, fmsg: &FuncMsgList{
-		num: comm.WATCH_IN_MAX},
-		elect: &WatchElect{}}
-	for _, row := range comm.WatchFmsg.Fmsg {
-		wd.base.Funcs = append(wd.base.Funcs, row)
-	}
-	wd.watch = append(wd.watch, &WatchNode{
-		NodeBaseMsg: *wd.base,
-		center:      true, stamp: time.Now().UnixMilli(),
-	})
-
-	node, err := rpc.NewClient(&network.NodeConfig{
-		NodeName: comm.WatchNodeName,
-		IPAddr:   wd.base.Host,
-		TcpPort:  uint64(wd.base.Tport),
-		HttpPort: uint64(wd.base.Hport),
-	})
-	if err != nil {
-		t.Error(err)
-	}
-	wd.fmsg.InitEnvFile("./")
-
-	if err = node.Register(&WatchApi{msg: wd}); err != nil {
-		t.Error(err)
-	}
-	api, err := node.RunServer()
-	if err != nil {
-		t.Error(err)
-	}
-	wd.call = api
-
-	time.Sleep(time.Minute * 10)
+type testCaller struct{}
+type testPeer struct{}
+
+// single watcher without network, registry in temporary path
+func testWatchApi(t *testing.T) *WatchApi {
+	var w = &WatchApi{
+		fmsg: &funcmap{num: comm.WATCH_IN_MAX, grace: time.Minute, reuse: time.Hour},
+		msg: &WatchDetail{
+			base:  &pb.NodeInfo{Uuid: "W", Name: comm.WatchNodeName},
+			elect: &WatchElect{center: true, nodeId: "W", timeout: time.Second},
+		},
+		node:   &nodemap{},
+		verify: func(*pb.NodeInfo) error { return nil },
+		admin:  map[string]bool{"Admin": true},
+	}
+	// verified caller was set by test context
+	w.caller = func(ctx context.Context) string {
+		name, _ := ctx.Value(testCaller{}).(string)
+		return name
+	}
+	w.peer = func(ctx context.Context) *pb.NodeInfo {
+		node, _ := ctx.Value(testPeer{}).(*pb.NodeInfo)
+		return node
+	}
+	w.msg.watch = []*WatchNode{{NodeInfo: pb.NodeInfo{Uuid: "W"}, self: true}}
+	if err := w.fmsg.InitRegistry(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.fmsg.reg.Close() })
+	var err error
+	if w.auth, err = newAuthFile(""); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func testRegister(t *testing.T, w *WatchApi, uuid string, apis ...string) *pb.RegisteredRsp {
+	var req = &pb.NodeInfo{Uuid: uuid, Name: "Tsv", Host: "127.0.0.1", Pid: 1}
+	for _, api := range apis {
+		req.Funcs = append(req.Funcs, &pb.FuncApi{Name: api})
+	}
+	var rsp = &pb.RegisteredRsp{}
+	if err := w.Registered(req, rsp); err != nil {
+		t.Fatal(err)
+	}
+	return rsp
+}
+
+func testApiConn(t *testing.T, w *WatchApi, name string, all bool) *pb.GetApiConnRsp {
+	var rsp = &pb.GetApiConnRsp{}
+	if err := w.GetApiConn(&pb.GetApiConnReq{Uuid: "C", ApiName: name, All: all}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	return rsp
+}
+
+func TestWatchApi(t *testing.T) {
+	var w = testWatchApi(t)
+	var rsp = testRegister(t, w, "A", "Tsv.Echo", "Tsv.Name")
+	if rsp.Funcs[0].ID <= comm.WATCH_IN_MAX || rsp.Funcs[1].ID != rsp.Funcs[0].ID+1 {
+		t.Fatal("function id wrong: ", rsp.Funcs)
+	} else if testRegister(t, w, "B", "Tsv.Echo").Funcs[0].ID != rsp.Funcs[0].ID {
+		t.Fatal("same api must have same function id")
+	}
+	if conn := testApiConn(t, w, "Tsv.Echo", false); len(conn.List) != 2 {
+		t.Fatal("api connection wrong: ", conn.List)
+	} else if !w.fmsg.GetStr("Tsv.Echo").HasSub("C") {
+		t.Fatal("caller must subscribe the api")
+	}
+
+	// node not SERVING was skipped unless request all
+	if err := w.Heartbeats(&pb.SystemStatus{Uuid: "B", State: pb.ServingState_DRAINING}); err != nil {
+		t.Fatal(err)
+	} else if conn := testApiConn(t, w, "Tsv.Echo", false); len(conn.List) != 1 || conn.List[0].Uuid != "A" {
+		t.Fatal("draining node must be skipped: ", conn.List)
+	} else if conn = testApiConn(t, w, "Tsv.Echo", true); len(conn.List) != 2 {
+		t.Fatal("all node must be returned: ", conn.List)
+	} else if err = w.Heartbeats(&pb.SystemStatus{Uuid: "X"}); err == nil {
+		t.Fatal("heartbeat of unknown node must be failed")
+	}
+
+	// deregister by the connection registered the node with same pid
+	var other = context.WithValue(context.TODO(), testPeer{}, &pb.NodeInfo{Uuid: "B"})
+	var owner = context.WithValue(context.TODO(), testPeer{}, &pb.NodeInfo{Uuid: "A"})
+	for _, ctx := range []context.Context{context.TODO(), other} {
+		if err := w.Deregister(ctx, &pb.NodeInfo{Uuid: "A", Pid: 1}, &pb.NodeInfo{}); rpc.ErrorCode(err) != rpc.CodePermissionDenied {
+			t.Fatal("other connection must be denied: ", err)
+		}
+	}
+	var deleted = &pb.NodeInfo{}
+	if err := w.Deregister(owner, &pb.NodeInfo{Uuid: "A", Pid: 2}, &pb.NodeInfo{}); err == nil {
+		t.Fatal("other process must be denied")
+	} else if err = w.Deregister(owner, &pb.NodeInfo{Uuid: "A", Pid: 1}, deleted); err != nil {
+		t.Fatal(err)
+	} else if deleted.Uuid != "A" || deleted.Name != "Tsv" {
+		t.Fatal("deleted node must be responded: ", deleted)
+	} else if conn := testApiConn(t, w, "Tsv.Echo", true); len(conn.List) != 1 || conn.List[0].Uuid != "B" {
+		t.Fatal("deregistered node must be removed: ", conn.List)
+	} else if data := w.fmsg.GetStr("Tsv.Name"); len(data.GetUuid()) != 0 || data.empty == 0 {
+		t.Fatal("api without provider must be empty")
+	}
+
+	// message was verified by signature
+	w.signed = true
+	if err := w.Deregister(context.TODO(), &pb.NodeInfo{Uuid: "B", Name: "Gateway", Pid: 1}, &pb.NodeInfo{}); rpc.ErrorCode(err) != rpc.CodePermissionDenied {
+		t.Fatal("node signed by other name must be denied: ", err)
+	} else if err = w.Registered(&pb.NodeInfo{Uuid: "B", Name: "Gateway"}, &pb.RegisteredRsp{}); rpc.ErrorCode(err) != rpc.CodePermissionDenied {
+		t.Fatal("node registered by other name must be denied: ", err)
+	} else if err = w.Deregister(context.TODO(), &pb.NodeInfo{Uuid: "B", Name: "Tsv", Pid: 1}, &pb.NodeInfo{}); err != nil {
+		t.Fatal("signed message must be allowed: ", err)
+	}
+}
+
+func TestWatchDeleteApi(t *testing.T) {
+	var w = testWatchApi(t)
+	var id = testRegister(t, w, "A", "Tsv.Echo").Funcs[0].ID
+	var admin = context.WithValue(context.TODO(), testCaller{}, "Admin")
+	var rsp = &pb.DeleteApiRsp{}
+	for _, ctx := range []context.Context{context.TODO(), context.WithValue(context.TODO(), testCaller{}, "Tsv")} {
+		if err := w.DeleteApi(ctx, &pb.DeleteApiReq{FuncID: id}, rsp); rpc.ErrorCode(err) != rpc.CodePermissionDenied {
+			t.Fatal("caller not admin must be denied: ", err)
+		}
+	}
+	if err := w.DeleteApi(admin, &pb.DeleteApiReq{ApiName: "Tsv.Echo"}, rsp); err == nil {
+		t.Fatal("api has provider must not be deleted")
+	} else if err = w.DeleteApi(admin, &pb.DeleteApiReq{ApiName: "Tsv.Echo", Force: true}, rsp); err == nil {
+		t.Fatal("api has alive provider must not be deleted by force")
+	}
+
+	// provider heartbeat timeout but not cleared, delete by force
+	if v, ok := w.node.uuid.Load("A"); ok {
+		v.(*NodeMsg).touch(time.Now().Add(comm.NodeConnTimeOut * 2).UnixMilli())
+	}
+	if err := w.DeleteApi(admin, &pb.DeleteApiReq{FuncID: id}, rsp); err == nil {
+		t.Fatal("api has provider must not be deleted without force")
+	} else if err = w.DeleteApi(admin, &pb.DeleteApiReq{FuncID: id, Force: true}, rsp); err != nil || rsp.Func.GetID() != id {
+		t.Fatal("api without alive provider must be deleted: ", err)
+	} else if w.fmsg.GetIds(id) != nil || len(w.fmsg.Tombs()) != 1 {
+		t.Fatal("deleted api must be tombstone")
+	}
+
+	// deleted function id was reused after reuse delay
+	if next := testRegister(t, w, "A", "Tsv.Name").Funcs[0].ID; next == id {
+		t.Fatal("function id must not be reused before reuse delay")
+	}
+	w.fmsg.reuse = 0
+	if next := testRegister(t, w, "A", "Tsv.Next").Funcs[0].ID; next != id {
+		t.Fatal("function id must be reused: ", next, id)
+	}
+}
+
+// provider node list of api changed when other goroutine read it
+func TestWatchApiRace(t *testing.T) {
+	var w = testWatchApi(t)
+	testRegister(t, w, "A", "Tsv.Echo")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				uuid := strconv.Itoa(i*100 + j)
+				req := &pb.NodeInfo{Uuid: uuid, Funcs: []*pb.FuncApi{{Name: "Tsv.Echo"}}}
+				if err := w.Registered(req, &pb.RegisteredRsp{}); err != nil {
+					t.Error(err)
+				}
+				w.fmsg.RemoveNodes([]string{uuid})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.GetApiConn(&pb.GetApiConnReq{ApiName: "Tsv.Echo", All: true}, &pb.GetApiConnRsp{})
+				w.fmsg.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+	if uuids := w.fmsg.GetStr("Tsv.Echo").GetUuid(); len(uuids) != 1 || uuids[0] != "A" {
+		t.Fatal("provider node list wrong: ", uuids)
+	}
+}
+
+// heartbeat updated while api node list and election sync read node status
+func TestNodeHeartbeat(t *testing.T) {
+	var n = &nodemap{}
+	n.PutNodeDetail(&pb.NodeInfo{Uuid: "A", Host: "127.0.0.1"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			n.UpHeartbeat(&pb.SystemStatus{Uuid: "A", State: pb.ServingState(i % 2)})
+			n.ResetStamp()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			n.GetStatByUuid("A").GetState()
+			n.Alive("A")
+			n.Snapshot()
+		}
+	}()
+	wg.Wait()
+	if !n.Alive("A") || n.GetStatByUuid("A") == nil {
+		t.Fatal("node must be alive with heartbeat status")
+	}
 }